@@ -0,0 +1,113 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"sigs.k8s.io/yaml"
+)
+
+// configEnvVar overrides the path of the config file, see defaultsFile.
+const configEnvVar = "KUBECTL_UNMOUNT_CONFIG"
+
+// defaultsFile returns the path of the config file that sets default flag values, and whether it was given
+// explicitly (so it must exist).
+func defaultsFile() (string, bool) {
+	if path := os.Getenv(configEnvVar); path != "" {
+		return path, true
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", false
+	}
+	return filepath.Join(home, ".kube", "kubectl-unmount.yaml"), false
+}
+
+// loadDefaults sets the flags that weren't given on the command line to their values in the config file, if
+// there is one. The file maps flag names to values, e.g. "storage-class: [fast-ssd]" or "timeout: 5m". Flags
+// given on the command line always win.
+func loadDefaults(flags *pflag.FlagSet) error {
+	path, explicit := defaultsFile()
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && !explicit {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	var values map[string]any
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return applyDefaults(flags, values, path)
+}
+
+// applyDefaults sets each flag that wasn't given on the command line to its value in values.
+func applyDefaults(flags *pflag.FlagSet, values map[string]any, path string) error {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	// Set flags in a predictable order, since some (like --dry-run) set several fields
+	slices.Sort(names)
+	for _, name := range names {
+		flag := flags.Lookup(name)
+		if flag == nil {
+			return fmt.Errorf("unknown flag %q in config file %s", name, path)
+		}
+		if flag.Changed {
+			continue
+		}
+		if err := setFlag(flag, values[name]); err != nil {
+			return fmt.Errorf("invalid value for %q in config file %s: %w", name, path, err)
+		}
+	}
+	return nil
+}
+
+// setFlag sets the flag to the given value parsed from YAML: a scalar, list (for flags that can be repeated),
+// or map (for key=value flags like --scale-annotations).
+func setFlag(flag *pflag.Flag, value any) error {
+	switch value := value.(type) {
+	case []any:
+		elems := make([]string, len(value))
+		for i, elem := range value {
+			elems[i] = formatValue(elem)
+		}
+		if slice, ok := flag.Value.(pflag.SliceValue); ok {
+			return slice.Replace(elems)
+		}
+		return flag.Value.Set(strings.Join(elems, ","))
+	case map[string]any:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		pairs := make([]string, len(keys))
+		for i, key := range keys {
+			pairs[i] = key + "=" + formatValue(value[key])
+		}
+		return flag.Value.Set(strings.Join(pairs, ","))
+	default:
+		return flag.Value.Set(formatValue(value))
+	}
+}
+
+// formatValue formats a scalar parsed from YAML the way it would be given on the command line. Numbers are
+// parsed as float64, so integers must not be formatted in exponent notation.
+func formatValue(value any) string {
+	if f, ok := value.(float64); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kubectl-unmount.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+storage-class: [fast-ssd, standard]
+wait: true
+timeout: 5m
+concurrency: 1000000
+scale-annotations:
+  team: storage
+`), 0o644))
+	t.Setenv(configEnvVar, path)
+
+	for _, tc := range []struct {
+		name        string
+		args        []string
+		wantClasses []string
+		wantTimeout time.Duration
+	}{
+		{
+			name:        "flags not given",
+			wantClasses: []string{"fast-ssd", "standard"},
+			wantTimeout: 5 * time.Minute,
+		},
+		{
+			name:        "flags given",
+			args:        []string{"--storage-class=slow-hdd", "--timeout=30s"},
+			wantClasses: []string{"slow-hdd"},
+			wantTimeout: 30 * time.Second,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+			storageClasses := flags.StringSlice("storage-class", nil, "")
+			wait := flags.Bool("wait", false, "")
+			timeout := flags.Duration("timeout", 0, "")
+			concurrency := flags.Int("concurrency", 1, "")
+			annotations := flags.StringToString("scale-annotations", nil, "")
+			require.NoError(t, flags.Parse(tc.args))
+
+			require.NoError(t, loadDefaults(flags))
+			require.Equal(t, tc.wantClasses, *storageClasses)
+			require.Equal(t, tc.wantTimeout, *timeout)
+			require.True(t, *wait)
+			require.Equal(t, 1000000, *concurrency)
+			require.Equal(t, map[string]string{"team": "storage"}, *annotations)
+		})
+	}
+}
+
+func TestLoadDefaultsErrors(t *testing.T) {
+	dir := t.TempDir()
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.Bool("wait", false, "")
+
+	t.Setenv(configEnvVar, filepath.Join(dir, "missing.yaml"))
+	require.ErrorContains(t, loadDefaults(flags), "failed to read config file")
+
+	path := filepath.Join(dir, "kubectl-unmount.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("wiat: true\n"), 0o644))
+	t.Setenv(configEnvVar, path)
+	require.ErrorContains(t, loadDefaults(flags), `unknown flag "wiat"`)
+
+	require.NoError(t, os.WriteFile(path, []byte("wait: maybe\n"), 0o644))
+	require.ErrorContains(t, loadDefaults(flags), `invalid value for "wait"`)
+}
+
+func TestLoadDefaultsWithoutFile(t *testing.T) {
+	t.Setenv(configEnvVar, "")
+	t.Setenv("HOME", t.TempDir())
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	wait := flags.Bool("wait", false, "")
+	require.NoError(t, loadDefaults(flags))
+	require.False(t, *wait)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// dryRunValue is the value of the --dry-run flag, which is either a boolean, "client" (like kubectl, the same as
+// true), "diff" to do a dry run that prints how each controller would change, or "server" to do a server-side
+// dry run.
+type dryRunValue struct {
+	dryRun *bool
+	diff   *bool
+	server *bool
+}
+
+func (v *dryRunValue) String() string {
+	switch {
+	case v.diff != nil && *v.diff:
+		return "diff"
+	case v.server != nil && *v.server:
+		return "server"
+	default:
+		return strconv.FormatBool(v.dryRun != nil && *v.dryRun)
+	}
+}
+
+func (v *dryRunValue) Set(s string) error {
+	*v.diff, *v.server = false, false
+	switch s {
+	case "client":
+		*v.dryRun = true
+	case "diff":
+		*v.dryRun, *v.diff = true, true
+	case "server":
+		*v.dryRun, *v.server = true, true
+	default:
+		dryRun, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("must be a boolean, \"client\", \"diff\", or \"server\"")
+		}
+		*v.dryRun = dryRun
+	}
+	return nil
+}
+
+func (v *dryRunValue) Type() string {
+	return "mode"
+}
@@ -1,16 +1,19 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/dancavallaro/kubectl-unmount/pkg/common"
 	"github.com/dancavallaro/kubectl-unmount/pkg/plugin"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
-	"k8s.io/cli-runtime/pkg/genericclioptions"
 
 	// Import cloud auth providers
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
@@ -28,6 +31,10 @@ var (
 func main() {
 	if err := RootCmd().Execute(); err != nil {
 		_, _ = fmt.Fprintln(os.Stderr, err)
+		var exitErr *plugin.ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.Code)
+		}
 		os.Exit(1)
 	}
 }
@@ -35,21 +42,28 @@ func main() {
 func RootCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:           "kubectl unmount",
-		Short:         "Unmount all PersistentVolumes of a particular StorageClass",
+		Short:         "Unmount all PersistentVolumes of particular StorageClasses",
 		SilenceErrors: true,
 		SilenceUsage:  true,
 		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := loadDefaults(cmd.Flags()); err != nil {
+				return err
+			}
 			return viper.BindPFlags(cmd.Flags())
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if *config.Namespace == "" && *config.StorageClass == "" {
-				return errors.New("you must specify at least one of --namespace or --storage-class")
-			}
-			if *config.StorageClass != "" && *config.PVCName != "" {
-				return errors.New("cannot specify both --storage-class and --pvc-name")
-			}
-			if err := plugin.RunPlugin(config); err != nil {
-				return errors.Unwrap(err)
+			// Cancel the run on Ctrl-C, so that it stops cleanly instead of dying mid-scale-down
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			if _, err := plugin.RunPlugin(ctx, config); err != nil {
+				var exitErr *plugin.ExitError
+				if errors.As(err, &exitErr) {
+					return exitErr
+				}
+				if unwrapped := errors.Unwrap(err); unwrapped != nil {
+					return unwrapped
+				}
+				return err
 			}
 			return nil
 		},
@@ -69,25 +83,200 @@ func RootCmd() *cobra.Command {
 	cmd.AddCommand(versionCmd)
 
 	cobra.OnInitialize(initConfig)
-	config = &plugin.ConfigFlags{
-		ConfigFlags:  *genericclioptions.NewConfigFlags(false),
-		Confirmed:    common.BoolP(false),
-		DryRun:       common.BoolP(false),
-		PVCName:      common.StringP(""),
-		StorageClass: common.StringP(""),
-	}
+	config = plugin.NewConfigFlags()
+	config.Version = version
 
-	cmd.Flags().StringVar(config.PVCName, "pvc", "", "Unmount a specific PVC")
-	cmd.Flags().StringVarP(config.StorageClass, "storage-class", "c", "", "Unmount PVs of a specific storage class")
-	cmd.Flags().BoolVarP(config.DryRun, "dry-run", "d", false,
-		"Print summary of controllers that would be scaled down, but *don't* modify anything")
+	cmd.Flags().StringSliceVar(config.PVCName, "pvc", nil, "Unmount specific PVCs (can be repeated)")
+	cmd.Flags().StringVar(config.PVName, "pv", "", "Unmount the PVC bound to a specific PersistentVolume")
+	cmd.Flags().StringVar(config.PVName, "pv-name", "", "Alias for --pv")
+	cmd.Flags().BoolVar(config.FromStdin, "from-stdin", false,
+		"Read the targets from stdin, one per line: a PVC name (in --namespace) or a kind/namespace/name controller")
+	cmd.Flags().StringVar(config.PVCRegex, "pvc-regex", "",
+		"Unmount PVCs whose name matches this regular expression, e.g. '^data-mysql-[0-9]+$'")
+	cmd.Flags().StringVar(config.NamespaceSelector, "namespace-selector", "",
+		"Only unmount PVCs in namespaces matching this label selector, e.g. purpose=batch (instead of --namespace)")
+	cmd.Flags().StringVarP(config.Selector, "selector", "l", "",
+		"Only unmount pods matching this label selector (combined with other filters)")
+	cmd.Flags().StringVar(config.FieldSelector, "field-selector", "",
+		"Only unmount pods matching this field selector, e.g. spec.nodeName=node-1 (combined with other filters)")
+	cmd.Flags().StringVar(config.AnnotationSelector, "annotation-selector", "",
+		"Only unmount pods with these annotations, e.g. maintenance-window=nightly (filtered client-side, combined with other filters)")
+	cmd.Flags().StringVar(config.NodeName, "node", "", "Only unmount pods scheduled on this node")
+	cmd.Flags().StringSliceVar(config.PodStatusFilter, "pod-status-filter", []string{"Running", "Pending"},
+		"Only unmount pods in one of these phases (Pending, Running, Succeeded, Failed, Unknown)")
+	cmd.Flags().StringSliceVar(config.ExcludeNamespaces, "exclude-namespace", nil,
+		"Don't scale down controllers in this namespace (can be repeated)")
+	cmd.Flags().StringSliceVar(config.SkipControllers, "skip-controller", nil,
+		"Don't scale down this controller, given as kind/namespace/name (can be repeated)")
+	cmd.Flags().BoolVar(config.OnlyControllers, "only-controllers", false,
+		"Only scale down controllers, leaving standalone pods running (they're still listed, as excluded)")
+	cmd.Flags().BoolVar(config.OnlyPods, "only-pods", false,
+		"Only delete standalone pods, leaving controllers running (they're still listed, as excluded)")
+	cmd.Flags().StringSliceVar(config.ExcludeControllers, "exclude-controller", nil,
+		"Don't scale down this controller, given as kind/name or name (can be repeated)")
+	cmd.Flags().StringVar(config.ProtectAnnotation, "protect-annotation", common.AnnotationSkip,
+		"Don't scale down controllers that have this annotation set to \"true\" (on themselves or their pods), empty to not check it")
+	cmd.Flags().StringSliceVarP(config.StorageClass, "storage-class", "c", nil,
+		"Unmount PVs of these storage classes (can be repeated or comma-separated)")
+	cmd.Flags().StringVar(config.AccessMode, "access-mode", "",
+		"Only unmount PVCs with this access mode: RWO, ROX, RWX, or RWOP (ignored with --pvc and --pv)")
+	cmd.Flags().StringVar(config.CSIDriver, "csi-driver", "",
+		"Only unmount PVCs whose PV is provisioned by this CSI driver, e.g. ebs.csi.aws.com (ignored with --pvc and --pv)")
+	cmd.Flags().StringVar(config.MinSize, "min-size", "",
+		"Only unmount PVCs requesting at least this much storage, e.g. 10Gi (ignored with --pvc and --pv)")
+	cmd.Flags().BoolVar(config.IncludeUnbound, "include-unbound", false,
+		"Also select PVCs that aren't Bound yet, e.g. Pending ones (ignored with --pvc and --pv)")
+	cmd.Flags().VarP(&dryRunValue{dryRun: config.DryRun, diff: config.DryRunDiff, server: config.DryRunServer}, "dry-run", "d",
+		"Print summary of controllers that would be scaled down, but *don't* modify anything (--dry-run=client is the same). "+
+			"Use --dry-run=diff to print how each controller's replicas would change, or --dry-run=server to "+
+			"send the scale down requests as server-side dry runs (validated by admission webhooks, but not persisted)")
+	cmd.Flags().Lookup("dry-run").NoOptDefVal = "true"
+	cmd.Flags().BoolVar(config.ByNode, "by-node", false,
+		"Group the affected controllers by the nodes their pods run on, listing the PVCs each node would release")
+	cmd.Flags().IntVar(config.Concurrency, "concurrency", 1, "Number of controllers to scale down in parallel")
+	cmd.Flags().IntVar(config.Concurrency, "parallelism", 1, "Alias for --concurrency")
+	cmd.Flags().IntVar(config.MaxDisruptionBudget, "max-disruption-budget", 0,
+		"Maximum number of pods terminating at any given time across all controllers, waiting for terminations "+
+			"to complete before scaling down more (0 means no limit)")
+	cmd.Flags().IntVar(config.MaxResources, "max-resources", 100,
+		"Abort before changing anything if more than this many controllers and pods would be acted on, only warn "+
+			"with --dry-run (0 means no limit)")
+	cmd.Flags().IntVar(config.Replicas, "replicas", 0,
+		"Number of replicas to scale the controllers down to, e.g. to only reduce a workload sharing a ReadWriteMany "+
+			"volume (standalone pods are still deleted, and Jobs and CronJobs suspended)")
+	cmd.Flags().IntVar(config.MaxRetries, "max-retries", 3,
+		"Number of times to retry scaling down a controller after a transient API error (conflicts, throttling, server errors)")
+	cmd.Flags().Float64Var(config.RateLimit, "rate-limit", 10,
+		"Maximum number of API requests per second, to avoid overwhelming the API server (0 means no limit)")
+	cmd.Flags().IntVar(config.Burst, "burst", 20, "Maximum number of API requests to send at once before --rate-limit applies")
+	cmd.Flags().Int64Var(config.GracePeriod, "grace-period", -1,
+		"Seconds to give pods to terminate, overriding their own grace period (may cause data loss). "+
+			"0 force-deletes immediately, negative values use each pod's own grace period")
+	cmd.Flags().StringToStringVar(config.ScaleAnnotations, "scale-annotations", nil,
+		"Annotations to add to scaled down controllers, e.g. ticket-id=CHG-1234,maintenance-window=2024-01-15")
+	cmd.Flags().BoolVar(config.PreValidation, "pre-validation", false,
+		"Skip targeted PVCs that aren't currently mounted by any running pod")
+	cmd.Flags().BoolVar(config.SkipIfScaled, "skip-if-scaled", false,
+		"Report controllers using the targeted PVCs that are already scaled down to 0 replicas as skipped")
+	cmd.Flags().BoolVar(config.SkipUnschedulableCheck, "skip-unschedulable-check", false,
+		"Don't require the targeted node to be cordoned before scaling down its pods")
+	cmd.Flags().BoolVar(config.Cordon, "cordon", false,
+		"Cordon the nodes running the affected pods before scaling down, so that replacement pods don't re-mount the PVCs")
+	cmd.Flags().BoolVar(config.Uncordon, "uncordon", false,
+		"Uncordon the nodes cordoned by a previous run with --cordon (only the one given with --node, if set), then exit")
+	cmd.Flags().BoolVar(config.LeaveHPA, "leave-hpa", false,
+		"Don't disable scale-to-zero HorizontalPodAutoscalers (minReplicas=0) that could scale the controllers back up")
+	cmd.Flags().BoolVar(config.Wait, "wait", false,
+		"Wait for scaled down controllers to report 0 ready replicas, failing if --wait-timeout expires")
+	cmd.Flags().DurationVar(config.WaitTimeout, "wait-timeout", 5*time.Minute, "How long to wait for pods to terminate when using --wait")
+	cmd.Flags().DurationVar(config.MaxWaitForSchedule, "max-wait-for-schedule", 0,
+		"Consider pods that have been unschedulable for this long as terminated while waiting (0 disables this)")
+	cmd.Flags().BoolVar(config.WaitForReplicaSetCleanup, "wait-for-replica-set-cleanup", false,
+		"After pods terminate, also wait for old ReplicaSets of Deployments with revisionHistoryLimit=0 to be deleted")
+	cmd.Flags().BoolVar(config.CleanAttachments, "clean-attachments", false,
+		"After pods terminate, delete lingering VolumeAttachments of the targeted PVs so they can attach elsewhere (requires --wait)")
+	cmd.Flags().BoolVar(config.VerifyDetach, "verify-detach", false,
+		"After pods terminate, also wait for the targeted PVs to be detached from all nodes (i.e. have no VolumeAttachments)")
+	cmd.Flags().BoolVar(config.CheckCustomFinalizers, "check-custom-finalizers", false,
+		"Warn about non-standard finalizers on affected pods that may block termination")
+	cmd.Flags().StringSliceVar(config.RemoveCustomFinalizers, "remove-custom-finalizer", nil,
+		"Remove this finalizer from pods stuck terminating after scale down (can be repeated)")
+	cmd.Flags().BoolVar(config.IgnorePDB, "ignore-pdb", false,
+		"Scale down controllers even if doing so would violate a PodDisruptionBudget")
+	cmd.Flags().BoolVar(config.Force, "force", false, "Override safety checks, including PodDisruptionBudget checks")
+	cmd.Flags().BoolVar(config.DisableEviction, "disable-eviction", false,
+		"Delete standalone pods directly instead of evicting them, bypassing PodDisruptionBudgets")
+	cmd.Flags().BoolVar(config.CheckPDBViolations, "check-pdb-violations", false,
+		"Abort if scaling down all controllers together would violate any PodDisruptionBudget")
+	cmd.Flags().BoolVar(config.CheckAdmissionWebhooks, "check-admission-webhooks", false,
+		"Warn about admission webhooks that may intercept (and reject) the scale down requests")
 	cmd.Flags().BoolVarP(config.Confirmed, "yes", "y", false, "Skip confirmation prompt and proceed with scaling down pods")
+	cmd.Flags().BoolVarP(config.Verbose, "verbose", "v", false,
+		"Log where each affected pod's containers mount the targeted PVCs")
+	cmd.Flags().IntVar(config.Verbosity, "verbosity", 0,
+		"Log each API request (1), or each API request along with the full request and response (2)")
+	cmd.Flags().BoolVarP(config.Interactive, "interactive", "i", false,
+		"Prompt for confirmation of each controller individually")
+	cmd.Flags().BoolVar(config.DatadogMetrics, "datadog-metrics", false,
+		"Push gauge metrics describing the operation to the Datadog Agent via DogStatsD")
+	cmd.Flags().StringVar(config.StatsdAddress, "statsd-address", "127.0.0.1:8125", "Address of the DogStatsD server")
+	cmd.Flags().StringVarP(config.Output, "output", "o", "",
+		"Output format. One of: ndjson or jsonlines (print affected controllers as JSON Lines), "+
+			"ansible-inventory (print affected controllers as an Ansible dynamic inventory), "+
+			"istio-vs-patch (print Istio VirtualService patches instead of scaling down)")
+	cmd.Flags().BoolVar(config.PatchIstioVS, "patch-istio-vs", false,
+		"Redirect traffic away from affected controllers by patching Istio VirtualServices before scaling down")
+	cmd.Flags().StringVar(config.IstioNamespace, "istio-namespace", "istio-system", "Namespace of the Istio control plane")
+	cmd.Flags().StringVar(config.CloudProvider, "cloud-provider", "",
+		"Log the cloud volume identifier of each targeted PVC. One of: aws (EBS volume ID), gcp (disk URL), azure (disk URI)")
+	cmd.Flags().StringVar(config.LogLevel, "log-level", "info", "Only log messages at or above this level. One of: debug, info, warn, error")
+	cmd.Flags().BoolVar(config.LogJSON, "log-json", false, "Log one JSON object per line, with level, msg, and time fields")
+	cmd.Flags().BoolVar(config.Timings, "timings", false,
+		"Log how long each phase (finding PVCs, pods, and controllers, scaling down, and waiting) took at the end")
+	cmd.Flags().BoolVar(config.Watch, "watch", false,
+		"Keep running until interrupted, scaling down the controllers of new pods that mount the targeted PVCs")
+	cmd.Flags().DurationVar(config.WatchCooldown, "watch-cooldown", time.Minute,
+		"How long to leave a controller alone after scaling it down with --watch, instead of fighting whatever scales it back up")
+	cmd.Flags().DurationVar(config.Timeout, "timeout", 0,
+		"Give up if the whole operation takes longer than this, e.g. 30s (0 means no timeout)")
+	cmd.Flags().StringVar(config.OutputFile, "output-file", "",
+		"Write the scaled down controllers and their original replicas to this file (as JSON), to restore them with --restore-from")
+	cmd.Flags().StringVar(config.RestoreFrom, "restore-from", "",
+		"Scale the controllers listed in this file (written with --output-file) back up to their original replicas, then exit")
+	cmd.Flags().StringVar(config.PlanFile, "plan-file", "",
+		"With --dry-run, write the controllers that would be scaled down to this file (as JSON), to apply later with --apply-plan")
+	cmd.Flags().StringVar(config.ApplyPlan, "apply-plan", "",
+		"Scale down exactly the controllers listed in this file (written with --plan-file), without selecting PVCs again, then exit")
+	cmd.Flags().StringVar(config.GenerateRunbook, "generate-runbook", "",
+		"Write a Markdown runbook documenting the affected controllers and how to restore them to this file")
 	config.AddFlags(cmd.Flags())
+	cmd.Flags().StringSliceVar(config.Contexts, "contexts", nil,
+		"Run in each of these kubeconfig contexts, one after the other (can be repeated or comma-separated)")
+	cmd.Flags().BoolVar(config.AllContexts, "all-contexts", false, "Run in every context of the kubeconfig, one after the other")
+	_ = cmd.RegisterFlagCompletionFunc("pvc", completionFunc(plugin.CompletePVCs))
+	_ = cmd.RegisterFlagCompletionFunc("storage-class", completionFunc(plugin.CompleteStorageClasses))
+	cmd.Flags().StringVar(config.Impersonate, "impersonate", "", "Alias for --as")
+	cmd.Flags().StringArrayVar(config.ImpersonateGroup, "impersonate-group", nil, "Alias for --as-group")
+
+	statusCmd := &cobra.Command{
+		Use:   "status",
+		Short: "List the controllers that are currently scaled down by kubectl-unmount",
+		Args:  cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := loadDefaults(cmd.Flags()); err != nil {
+				return err
+			}
+			return viper.BindPFlags(cmd.Flags())
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return plugin.RunStatus(cmd.Context(), config)
+		},
+	}
+	statusCmd.Flags().StringVarP(config.Output, "output", "o", "",
+		"Output format. One of: ndjson or jsonlines (print the controllers as JSON Lines instead of a table)")
+	statusCmd.Flags().StringVar(config.LogLevel, "log-level", "info", "Only log messages at or above this level. One of: debug, info, warn, error")
+	statusCmd.Flags().BoolVar(config.LogJSON, "log-json", false, "Log one JSON object per line, with level, msg, and time fields")
+	statusCmd.Flags().IntVar(config.Verbosity, "verbosity", 0,
+		"Log each API request (1), or each API request along with the full request and response (2)")
+	config.AddFlags(statusCmd.Flags())
+	cmd.AddCommand(statusCmd)
 
 	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
 	return cmd
 }
 
+// completionFunc adapts a function listing the names to complete a flag with to a cobra completion function.
+func completionFunc(complete func(ctx context.Context, cfg *plugin.ConfigFlags, prefix string) ([]string, error)) cobra.CompletionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		names, err := complete(context.Background(), config, toComplete)
+		if err != nil {
+			cobra.CompErrorln(err.Error())
+			return nil, cobra.ShellCompDirectiveError
+		}
+		return names, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
 func initConfig() {
 	viper.AutomaticEnv()
 }
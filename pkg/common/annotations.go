@@ -0,0 +1,25 @@
+package common
+
+// Annotations added to resources that were scaled down, recording the operation for auditability.
+const (
+	AnnotationScaledBy         = "kubectl-unmount/scaled-by"
+	AnnotationScaledAt         = "kubectl-unmount/scaled-at"
+	AnnotationOriginalReplicas = "kubectl-unmount/original-replicas"
+	AnnotationPVCTrigger       = "kubectl-unmount/pvc-trigger"
+	// AnnotationCustomAnnotations lists the keys of the custom annotations that were added (with --scale-annotations).
+	AnnotationCustomAnnotations = "kubectl-unmount/custom-annotations"
+	// AnnotationOriginalMinReplicas records the minReplicas of a HorizontalPodAutoscaler that was disabled.
+	AnnotationOriginalMinReplicas = "kubectl-unmount/original-min-replicas"
+)
+
+// AnnotationSkip is the default annotation (see --protect-annotation) that application owners can set to "true" on
+// their controllers or pods, so that they're never scaled down.
+const AnnotationSkip = "kubectl-unmount/skip"
+
+// AnnotationCordonedBy is added to nodes cordoned with --cordon, so that --uncordon only reverses what
+// kubectl-unmount did.
+const AnnotationCordonedBy = "kubectl-unmount/cordoned-by"
+
+// AnnotationPausedBy is added to KEDA ScaledObjects paused so that they don't scale their targets back up, so that
+// restoring only resumes the ones that kubectl-unmount paused.
+const AnnotationPausedBy = "kubectl-unmount/paused-by"
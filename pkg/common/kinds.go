@@ -1,9 +1,12 @@
 package common
 
 const (
-	KindPod         = "Pod"
-	KindReplicaSet  = "ReplicaSet"
-	KindDeployment  = "Deployment"
-	KindDaemonSet   = "DaemonSet"
-	KindStatefulSet = "StatefulSet"
+	KindPod                   = "Pod"
+	KindReplicaSet            = "ReplicaSet"
+	KindReplicationController = "ReplicationController"
+	KindDeployment            = "Deployment"
+	KindDaemonSet             = "DaemonSet"
+	KindStatefulSet           = "StatefulSet"
+	KindJob                   = "Job"
+	KindCronJob               = "CronJob"
 )
@@ -1,5 +1,7 @@
 package common
 
+import "time"
+
 func StringP(val string) *string {
 	return &val
 }
@@ -7,3 +9,19 @@ func StringP(val string) *string {
 func BoolP(val bool) *bool {
 	return &val
 }
+
+func IntP(val int) *int {
+	return &val
+}
+
+func Int64P(val int64) *int64 {
+	return &val
+}
+
+func Float64P(val float64) *float64 {
+	return &val
+}
+
+func DurationP(val time.Duration) *time.Duration {
+	return &val
+}
@@ -7,6 +7,9 @@ type ControllerRef struct {
 	Kind      string
 	Namespace string
 	Name      string
+	// APIVersion is only set for kinds the plugin doesn't know (e.g. custom resources managed by an operator),
+	// which are scaled down generically through their scale subresource.
+	APIVersion string
 }
 
 func (ref ControllerRef) String() string {
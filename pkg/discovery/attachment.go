@@ -0,0 +1,49 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// FindBoundPVs finds the names of the PVs that the given PVCs are bound to. Unbound PVCs are ignored.
+func (f *Finder) FindBoundPVs(ctx context.Context, pvcsPerNs map[string][]string) ([]string, error) {
+	var pvs []string
+	for ns, pvcs := range pvcsPerNs {
+		for _, name := range pvcs {
+			pvc, err := f.clientset.CoreV1().PersistentVolumeClaims(ns).Get(ctx, name, metav1.GetOptions{})
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to get persistent volume claim %s/%s: %w", ns, name, err)
+			}
+			if pvc.Spec.VolumeName != "" {
+				pvs = append(pvs, pvc.Spec.VolumeName)
+			}
+		}
+	}
+	slices.Sort(pvs)
+	return pvs, nil
+}
+
+// FindVolumeAttachments finds the VolumeAttachments of the given PVs, i.e. the nodes that the CSI driver still
+// has (or is still detaching) them attached to. Once a volume is detached, its VolumeAttachment is deleted.
+func (f *Finder) FindVolumeAttachments(ctx context.Context, pvs []string) ([]storagev1.VolumeAttachment, error) {
+	attachmentList, err := f.clientset.StorageV1().VolumeAttachments().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list volume attachments: %w", err)
+	}
+	var attachments []storagev1.VolumeAttachment
+	for _, attachment := range attachmentList.Items {
+		pv := attachment.Spec.Source.PersistentVolumeName
+		if pv != nil && slices.Contains(pvs, *pv) {
+			attachments = append(attachments, attachment)
+		}
+	}
+	return attachments, nil
+}
@@ -0,0 +1,48 @@
+package discovery
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/logger"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
+)
+
+func TestFindVolumeAttachments(t *testing.T) {
+	newAttachment := func(name, pv string) *storagev1.VolumeAttachment {
+		return &storagev1.VolumeAttachment{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: storagev1.VolumeAttachmentSpec{
+				NodeName: "node-1",
+				Source:   storagev1.VolumeAttachmentSource{PersistentVolumeName: ptr.To(pv)},
+			},
+		}
+	}
+	clientset := fake.NewClientset(
+		&corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: "bound", Namespace: "test-ns"},
+			Spec:       corev1.PersistentVolumeClaimSpec{VolumeName: "pv-1"},
+		},
+		&corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "pending", Namespace: "test-ns"}},
+		newAttachment("csi-1", "pv-1"),
+		newAttachment("csi-2", "pv-2"),
+	)
+	finder := New(clientset, logger.NewLogger(&bytes.Buffer{}, logger.LevelInfo))
+
+	pvs, err := finder.FindBoundPVs(context.Background(), map[string][]string{
+		"test-ns": {"bound", "pending", "missing"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"pv-1"}, pvs)
+
+	attachments, err := finder.FindVolumeAttachments(context.Background(), pvs)
+	require.NoError(t, err)
+	require.Len(t, attachments, 1)
+	require.Equal(t, "csi-1", attachments[0].Name)
+}
@@ -0,0 +1,88 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Cloud providers whose volume identifiers can be extracted from PVs.
+const (
+	CloudProviderAWS   = "aws"
+	CloudProviderGCP   = "gcp"
+	CloudProviderAzure = "azure"
+)
+
+var CloudProviders = []string{CloudProviderAWS, CloudProviderGCP, CloudProviderAzure}
+
+const (
+	awsEBSDriver    = "ebs.csi.aws.com"
+	gcpPDDriver     = "pd.csi.storage.gke.io"
+	azureDiskDriver = "disk.csi.azure.com"
+
+	gcpComputeURL = "https://www.googleapis.com/compute/v1/"
+)
+
+// FindCloudVolumes finds the cloud provider's identifier for the volumes backing the given PVCs: the EBS
+// volume ID on AWS, the disk resource URL on GCP, or the disk URI on Azure. Returns a map from "namespace/name"
+// of each PVC to its volume identifier, omitting PVCs whose volumes aren't managed by the provider.
+func (f *Finder) FindCloudVolumes(ctx context.Context, pvcsPerNs map[string][]string, provider string) (map[string]string, error) {
+	pvList, err := f.clientset.CoreV1().PersistentVolumes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list persistent volumes: %w", err)
+	}
+
+	volumes := make(map[string]string)
+	for _, pv := range pvList.Items {
+		claim := pv.Spec.ClaimRef
+		if claim == nil || !slices.Contains(pvcsPerNs[claim.Namespace], claim.Name) {
+			continue
+		}
+		if id := CloudVolumeID(pv, provider); id != "" {
+			volumes[claim.Namespace+"/"+claim.Name] = id
+		}
+	}
+	return volumes, nil
+}
+
+// CloudVolumeID returns the cloud provider's identifier for the volume backing the given PV, for both
+// in-tree and CSI volumes. Returns an empty string if the volume isn't managed by the provider.
+func CloudVolumeID(pv corev1.PersistentVolume, provider string) string {
+	source := pv.Spec.PersistentVolumeSource
+	csiHandle := func(driver string) string {
+		if source.CSI != nil && source.CSI.Driver == driver {
+			return source.CSI.VolumeHandle
+		}
+		return ""
+	}
+
+	switch provider {
+	case CloudProviderAWS:
+		if source.AWSElasticBlockStore != nil {
+			// In-tree volume IDs may be given as aws://<zone>/<volume-id>
+			id := source.AWSElasticBlockStore.VolumeID
+			return id[strings.LastIndex(id, "/")+1:]
+		}
+		return csiHandle(awsEBSDriver)
+	case CloudProviderGCP:
+		if source.GCEPersistentDisk != nil {
+			return source.GCEPersistentDisk.PDName
+		}
+		// CSI volume handles are partial resource URLs: projects/<project>/zones/<zone>/disks/<disk>
+		if handle := csiHandle(gcpPDDriver); handle != "" {
+			return gcpComputeURL + handle
+		}
+		return ""
+	case CloudProviderAzure:
+		if source.AzureDisk != nil {
+			return source.AzureDisk.DataDiskURI
+		}
+		return csiHandle(azureDiskDriver)
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,94 @@
+package discovery
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/logger"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestCloudVolumeID(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider string
+		source   corev1.PersistentVolumeSource
+		want     string
+	}{
+		{
+			name:     "in-tree EBS",
+			provider: CloudProviderAWS,
+			source: corev1.PersistentVolumeSource{
+				AWSElasticBlockStore: &corev1.AWSElasticBlockStoreVolumeSource{VolumeID: "aws://us-east-1a/vol-0123"},
+			},
+			want: "vol-0123",
+		},
+		{
+			name:     "EBS CSI",
+			provider: CloudProviderAWS,
+			source: corev1.PersistentVolumeSource{
+				CSI: &corev1.CSIPersistentVolumeSource{Driver: awsEBSDriver, VolumeHandle: "vol-0123"},
+			},
+			want: "vol-0123",
+		},
+		{
+			name:     "GCP PD CSI",
+			provider: CloudProviderGCP,
+			source: corev1.PersistentVolumeSource{
+				CSI: &corev1.CSIPersistentVolumeSource{Driver: gcpPDDriver, VolumeHandle: "projects/p/zones/z/disks/d"},
+			},
+			want: "https://www.googleapis.com/compute/v1/projects/p/zones/z/disks/d",
+		},
+		{
+			name:     "Azure Disk CSI",
+			provider: CloudProviderAzure,
+			source: corev1.PersistentVolumeSource{
+				CSI: &corev1.CSIPersistentVolumeSource{Driver: azureDiskDriver, VolumeHandle: "/subscriptions/s/disks/d"},
+			},
+			want: "/subscriptions/s/disks/d",
+		},
+		{
+			name:     "other provider's volume",
+			provider: CloudProviderGCP,
+			source: corev1.PersistentVolumeSource{
+				CSI: &corev1.CSIPersistentVolumeSource{Driver: awsEBSDriver, VolumeHandle: "vol-0123"},
+			},
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pv := corev1.PersistentVolume{Spec: corev1.PersistentVolumeSpec{PersistentVolumeSource: tt.source}}
+			require.Equal(t, tt.want, CloudVolumeID(pv, tt.provider))
+		})
+	}
+}
+
+func TestFindCloudVolumes(t *testing.T) {
+	newPV := func(name, claim, volumeID string) *corev1.PersistentVolume {
+		return &corev1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: corev1.PersistentVolumeSpec{
+				ClaimRef: &corev1.ObjectReference{Namespace: "test-ns", Name: claim},
+				PersistentVolumeSource: corev1.PersistentVolumeSource{
+					CSI: &corev1.CSIPersistentVolumeSource{Driver: awsEBSDriver, VolumeHandle: volumeID},
+				},
+			},
+		}
+	}
+	clientset := fake.NewClientset(
+		newPV("pv-1", "target-pvc", "vol-1"),
+		newPV("pv-2", "other-pvc", "vol-2"),
+	)
+	finder := New(clientset, logger.NewLogger(&bytes.Buffer{}, logger.LevelInfo))
+
+	volumes, err := finder.FindCloudVolumes(context.Background(), map[string][]string{
+		"test-ns": {"target-pvc"},
+	}, CloudProviderAWS)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"test-ns/target-pvc": "vol-1"}, volumes)
+}
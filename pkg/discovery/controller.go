@@ -11,7 +11,7 @@ import (
 )
 
 // FindController traces the owner references to find the top-level controller.
-// It walks up the ownership chain (e.g., Pod -> ReplicaSet -> Deployment).
+// It walks up the ownership chain (e.g., Pod -> ReplicaSet -> Deployment, or Pod -> Job -> CronJob).
 func (f *Finder) FindController(ctx context.Context, pod corev1.Pod) (common.ControllerRef, error) {
 	// Check if pod has any owner references
 	if len(pod.OwnerReferences) == 0 {
@@ -35,12 +35,7 @@ func (f *Finder) FindController(ctx context.Context, pod corev1.Pod) (common.Con
 
 		// Check if ReplicaSet has an owner (likely a Deployment)
 		if len(rs.OwnerReferences) > 0 {
-			rsOwner := rs.OwnerReferences[0]
-			return common.ControllerRef{
-				Kind:      rsOwner.Kind,
-				Namespace: pod.Namespace,
-				Name:      rsOwner.Name,
-			}, nil
+			return ownerRef(rs.OwnerReferences[0], pod.Namespace), nil
 		}
 
 		// ReplicaSet has no owner, it's the top-level controller
@@ -51,26 +46,72 @@ func (f *Finder) FindController(ctx context.Context, pod corev1.Pod) (common.Con
 		}, nil
 	}
 
-	// For other controller types (StatefulSet, DaemonSet, etc.), return as-is
-	return common.ControllerRef{
-		Kind:      owner.Kind,
-		Namespace: pod.Namespace,
-		Name:      owner.Name,
-	}, nil
+	// If the owner is a Job, check if it was created by a CronJob
+	if owner.Kind == common.KindJob {
+		job, err := f.clientset.BatchV1().Jobs(pod.Namespace).Get(ctx, owner.Name, metav1.GetOptions{})
+		if err != nil {
+			return common.ControllerRef{}, err
+		}
+
+		if len(job.OwnerReferences) > 0 && job.OwnerReferences[0].Kind == common.KindCronJob {
+			return common.ControllerRef{
+				Kind:      common.KindCronJob,
+				Namespace: pod.Namespace,
+				Name:      job.OwnerReferences[0].Name,
+			}, nil
+		}
+
+		// Job has no CronJob owner, it's the top-level controller
+		return common.ControllerRef{
+			Kind:      common.KindJob,
+			Namespace: pod.Namespace,
+			Name:      job.Name,
+		}, nil
+	}
+
+	// For other controller types (StatefulSet, ReplicationController, DaemonSet, custom resources, etc.), return as-is
+	return ownerRef(owner, pod.Namespace), nil
+}
+
+// knownKinds are the kinds of controllers that the plugin handles natively.
+var knownKinds = []string{
+	common.KindDeployment, common.KindStatefulSet, common.KindReplicaSet, common.KindReplicationController, common.KindDaemonSet,
+	common.KindJob, common.KindCronJob,
+}
+
+// ownerRef returns a reference to the given owner. Its API version is recorded if it isn't one of the known kinds,
+// so that it can be scaled down generically.
+func ownerRef(owner metav1.OwnerReference, namespace string) common.ControllerRef {
+	ctrl := common.ControllerRef{Kind: owner.Kind, Namespace: namespace, Name: owner.Name}
+	if !slices.Contains(knownKinds, owner.Kind) {
+		ctrl.APIVersion = owner.APIVersion
+	}
+	return ctrl
 }
 
 // FindControllers finds the (deduplicated) top-level controllers for the provided pods.
 func (f *Finder) FindControllers(ctx context.Context, pods []corev1.Pod) ([]common.ControllerRef, error) {
+	podsByController, err := f.GroupPodsByController(ctx, pods)
+	if err != nil {
+		return nil, err
+	}
+	return slices.Collect(maps.Keys(podsByController)), nil
+}
+
+// GroupPodsByController finds the top-level controllers for the provided pods, and groups the pods
+// by the controller that owns them.
+func (f *Finder) GroupPodsByController(ctx context.Context, pods []corev1.Pod) (map[common.ControllerRef][]corev1.Pod, error) {
 	f.log.Info("Finding controllers for pods...")
-	controllers := make(map[string]common.ControllerRef) // key: "kind/namespace/name"
+	podsByController := make(map[common.ControllerRef][]corev1.Pod)
 	for _, pod := range pods {
 		ctrl, err := f.FindController(ctx, pod)
 		if err != nil {
 			f.log.Warn("Failed to find controller for pod %s/%s: %v", pod.Namespace, pod.Name, err)
 			return nil, err
 		}
-		controllers[ctrl.String()] = ctrl
+		f.log.Debug("Pod %s/%s is owned by %v", pod.Namespace, pod.Name, ctrl)
+		podsByController[ctrl] = append(podsByController[ctrl], pod)
 	}
 
-	return slices.Collect(maps.Values(controllers)), nil
+	return podsByController, nil
 }
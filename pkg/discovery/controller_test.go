@@ -0,0 +1,104 @@
+package discovery
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/common"
+	"github.com/dancavallaro/kubectl-unmount/pkg/logger"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestFindControllerForJobs(t *testing.T) {
+	clientset := fake.NewClientset(
+		&batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "standalone-job", Namespace: "test-ns"}},
+		&batchv1.Job{ObjectMeta: metav1.ObjectMeta{
+			Name:            "cron-job-123",
+			Namespace:       "test-ns",
+			OwnerReferences: []metav1.OwnerReference{{Kind: common.KindCronJob, Name: "cron-job"}},
+		}},
+	)
+	finder := New(clientset, logger.NewLogger(&bytes.Buffer{}, logger.LevelInfo))
+
+	ctrl, err := finder.FindController(context.Background(), newJobPod("standalone-job"))
+	require.NoError(t, err)
+	require.Equal(t, common.ControllerRef{Kind: common.KindJob, Namespace: "test-ns", Name: "standalone-job"}, ctrl)
+
+	ctrl, err = finder.FindController(context.Background(), newJobPod("cron-job-123"))
+	require.NoError(t, err)
+	require.Equal(t, common.ControllerRef{Kind: common.KindCronJob, Namespace: "test-ns", Name: "cron-job"}, ctrl)
+}
+
+func TestFindControllerForReplicaSets(t *testing.T) {
+	clientset := fake.NewClientset(
+		&appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: "standalone", Namespace: "test-ns"}},
+		&appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+			Name:            "web-5d8f9",
+			Namespace:       "test-ns",
+			OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: common.KindDeployment, Name: "web"}},
+		}},
+	)
+	finder := New(clientset, logger.NewLogger(&bytes.Buffer{}, logger.LevelInfo))
+
+	for _, tc := range []struct {
+		owner metav1.OwnerReference
+		want  common.ControllerRef
+	}{
+		{
+			owner: metav1.OwnerReference{APIVersion: "apps/v1", Kind: common.KindReplicaSet, Name: "standalone"},
+			want:  common.ControllerRef{Kind: common.KindReplicaSet, Namespace: "test-ns", Name: "standalone"},
+		},
+		{
+			owner: metav1.OwnerReference{APIVersion: "apps/v1", Kind: common.KindReplicaSet, Name: "web-5d8f9"},
+			want:  common.ControllerRef{Kind: common.KindDeployment, Namespace: "test-ns", Name: "web"},
+		},
+		{
+			owner: metav1.OwnerReference{APIVersion: "v1", Kind: common.KindReplicationController, Name: "legacy"},
+			want:  common.ControllerRef{Kind: common.KindReplicationController, Namespace: "test-ns", Name: "legacy"},
+		},
+	} {
+		ctrl, err := finder.FindController(context.Background(), corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name:            tc.owner.Name + "-abcde",
+			Namespace:       "test-ns",
+			OwnerReferences: []metav1.OwnerReference{tc.owner},
+		}})
+		require.NoError(t, err)
+		require.Equal(t, tc.want, ctrl)
+		require.Equal(t, tc.want.Kind+"/test-ns/"+tc.want.Name, ctrl.String())
+	}
+}
+
+func TestFindControllerForCustomResources(t *testing.T) {
+	clientset := fake.NewClientset(&appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+		Name:      "web-5d8f9",
+		Namespace: "test-ns",
+		OwnerReferences: []metav1.OwnerReference{{
+			APIVersion: "argoproj.io/v1alpha1", Kind: "Rollout", Name: "web",
+		}},
+	}})
+	finder := New(clientset, logger.NewLogger(&bytes.Buffer{}, logger.LevelInfo))
+
+	ctrl, err := finder.FindController(context.Background(), corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:            "web-5d8f9-abcde",
+		Namespace:       "test-ns",
+		OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: common.KindReplicaSet, Name: "web-5d8f9"}},
+	}})
+	require.NoError(t, err)
+	require.Equal(t, common.ControllerRef{Kind: "Rollout", Namespace: "test-ns", Name: "web", APIVersion: "argoproj.io/v1alpha1"}, ctrl)
+}
+
+func newJobPod(job string) corev1.Pod {
+	return corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            job + "-pod",
+			Namespace:       "test-ns",
+			OwnerReferences: []metav1.OwnerReference{{Kind: common.KindJob, Name: job}},
+		},
+	}
+}
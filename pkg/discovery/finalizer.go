@@ -0,0 +1,27 @@
+package discovery
+
+import (
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// standardFinalizers are finalizers that Kubernetes itself may add to pods, and which
+// don't block termination indefinitely.
+var standardFinalizers = []string{
+	metav1.FinalizerOrphanDependents,
+	metav1.FinalizerDeleteDependents,
+	"batch.kubernetes.io/job-tracking",
+}
+
+// CustomFinalizers returns the non-standard finalizers set on the given pod.
+func CustomFinalizers(pod corev1.Pod) []string {
+	var custom []string
+	for _, finalizer := range pod.Finalizers {
+		if !slices.Contains(standardFinalizers, finalizer) {
+			custom = append(custom, finalizer)
+		}
+	}
+	return custom
+}
@@ -7,12 +7,12 @@ import (
 
 // Finder handles Kubernetes resource discovery operations.
 type Finder struct {
-	clientset *kubernetes.Clientset
+	clientset kubernetes.Interface
 	log       *logger.Logger
 }
 
 // New creates a new Finder instance.
-func New(clientset *kubernetes.Clientset, log *logger.Logger) Finder {
+func New(clientset kubernetes.Interface, log *logger.Logger) Finder {
 	return Finder{
 		clientset: clientset,
 		log:       log,
@@ -0,0 +1,32 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/common"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// FindHPA finds the HorizontalPodAutoscaler targeting the given controller, or nil if there isn't one (or the
+// controller's kind can't be autoscaled).
+func (f *Finder) FindHPA(ctx context.Context, ctrl common.ControllerRef) (*autoscalingv2.HorizontalPodAutoscaler, error) {
+	switch ctrl.Kind {
+	case common.KindDeployment, common.KindStatefulSet, common.KindReplicaSet, common.KindReplicationController:
+	default:
+		return nil, nil
+	}
+
+	hpaList, err := f.clientset.AutoscalingV2().HorizontalPodAutoscalers(ctrl.Namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list horizontal pod autoscalers: %w", err)
+	}
+	for _, hpa := range hpaList.Items {
+		ref := hpa.Spec.ScaleTargetRef
+		if ref.Kind == ctrl.Kind && ref.Name == ctrl.Name {
+			return &hpa, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,44 @@
+package discovery
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/common"
+	"github.com/dancavallaro/kubectl-unmount/pkg/logger"
+	"github.com/stretchr/testify/require"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestFindHPA(t *testing.T) {
+	newHPA := func(name, kind, target string) *autoscalingv2.HorizontalPodAutoscaler {
+		return &autoscalingv2.HorizontalPodAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test-ns"},
+			Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+				ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{APIVersion: "apps/v1", Kind: kind, Name: target},
+			},
+		}
+	}
+	clientset := fake.NewClientset(
+		newHPA("web-hpa", common.KindDeployment, "web"),
+		newHPA("db-hpa", common.KindStatefulSet, "web"),
+	)
+	finder := New(clientset, logger.NewLogger(&bytes.Buffer{}, logger.LevelInfo))
+	ctx := context.Background()
+
+	hpa, err := finder.FindHPA(ctx, common.ControllerRef{Kind: common.KindDeployment, Namespace: "test-ns", Name: "web"})
+	require.NoError(t, err)
+	require.NotNil(t, hpa)
+	require.Equal(t, "web-hpa", hpa.Name)
+
+	hpa, err = finder.FindHPA(ctx, common.ControllerRef{Kind: common.KindDeployment, Namespace: "test-ns", Name: "api"})
+	require.NoError(t, err)
+	require.Nil(t, hpa)
+
+	hpa, err = finder.FindHPA(ctx, common.ControllerRef{Kind: common.KindPod, Namespace: "test-ns", Name: "web"})
+	require.NoError(t, err)
+	require.Nil(t, hpa)
+}
@@ -0,0 +1,21 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// FindNamespaces finds the names of the namespaces matching the given label selector.
+func (f *Finder) FindNamespaces(ctx context.Context, selector string) ([]string, error) {
+	nsList, err := f.clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list namespaces: %w", err)
+	}
+	var namespaces []string
+	for _, ns := range nsList.Items {
+		namespaces = append(namespaces, ns.Name)
+	}
+	return namespaces, nil
+}
@@ -0,0 +1,29 @@
+package discovery
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/logger"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestFindNamespaces(t *testing.T) {
+	newNamespace := func(name string, labels map[string]string) *corev1.Namespace {
+		return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+	}
+	clientset := fake.NewClientset(
+		newNamespace("batch-1", map[string]string{"purpose": "batch"}),
+		newNamespace("batch-2", map[string]string{"purpose": "batch"}),
+		newNamespace("web", map[string]string{"purpose": "web"}),
+	)
+	finder := New(clientset, logger.NewLogger(&bytes.Buffer{}, logger.LevelInfo))
+
+	namespaces, err := finder.FindNamespaces(context.Background(), "purpose=batch")
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"batch-1", "batch-2"}, namespaces)
+}
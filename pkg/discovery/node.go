@@ -0,0 +1,59 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/common"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// IsNodeUnschedulable checks whether the given node has been marked unschedulable (e.g. cordoned).
+func (f *Finder) IsNodeUnschedulable(ctx context.Context, name string) (bool, error) {
+	node, err := f.clientset.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return false, fmt.Errorf("failed to get node %s: %w", name, err)
+	}
+	return node.Spec.Unschedulable, nil
+}
+
+// FindCordonedNodes finds the nodes that were cordoned by kubectl-unmount (with --cordon) and are still
+// unschedulable. If name is set, only that node is considered.
+func (f *Finder) FindCordonedNodes(ctx context.Context, name string) ([]corev1.Node, error) {
+	var nodes []corev1.Node
+	if name != "" {
+		node, err := f.clientset.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get node %s: %w", name, err)
+		}
+		nodes = []corev1.Node{*node}
+	} else {
+		nodeList, err := f.clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list nodes: %w", err)
+		}
+		nodes = nodeList.Items
+	}
+
+	var cordoned []corev1.Node
+	for _, node := range nodes {
+		if _, ok := node.Annotations[common.AnnotationCordonedBy]; ok && node.Spec.Unschedulable {
+			cordoned = append(cordoned, node)
+		}
+	}
+	return cordoned, nil
+}
+
+// NodesOf returns the (deduplicated) names of the nodes the given pods are scheduled on.
+func NodesOf(pods []corev1.Pod) []string {
+	var nodes []string
+	for _, pod := range pods {
+		if pod.Spec.NodeName != "" && !slices.Contains(nodes, pod.Spec.NodeName) {
+			nodes = append(nodes, pod.Spec.NodeName)
+		}
+	}
+	slices.Sort(nodes)
+	return nodes
+}
@@ -0,0 +1,48 @@
+package discovery
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/common"
+	"github.com/dancavallaro/kubectl-unmount/pkg/logger"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestFindCordonedNodes(t *testing.T) {
+	newNode := func(name string, unschedulable bool, annotations map[string]string) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Annotations: annotations},
+			Spec:       corev1.NodeSpec{Unschedulable: unschedulable},
+		}
+	}
+	cordonedBy := map[string]string{common.AnnotationCordonedBy: "kubectl-unmount"}
+	clientset := fake.NewClientset(
+		newNode("cordoned", true, cordonedBy),
+		newNode("cordoned-by-someone-else", true, nil),
+		newNode("already-uncordoned", false, cordonedBy),
+		newNode("schedulable", false, nil),
+	)
+	finder := New(clientset, logger.NewLogger(&bytes.Buffer{}, logger.LevelInfo))
+
+	nodes, err := finder.FindCordonedNodes(context.Background(), "")
+	require.NoError(t, err)
+	require.Len(t, nodes, 1)
+	require.Equal(t, "cordoned", nodes[0].Name)
+
+	nodes, err = finder.FindCordonedNodes(context.Background(), "cordoned-by-someone-else")
+	require.NoError(t, err)
+	require.Empty(t, nodes)
+}
+
+func TestNodesOf(t *testing.T) {
+	newPod := func(node string) corev1.Pod {
+		return corev1.Pod{Spec: corev1.PodSpec{NodeName: node}}
+	}
+	nodes := NodesOf([]corev1.Pod{newPod("node-2"), newPod("node-1"), newPod(""), newPod("node-2")})
+	require.Equal(t, []string{"node-1", "node-2"}, nodes)
+}
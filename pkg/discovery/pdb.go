@@ -0,0 +1,82 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// PDBViolation describes a PodDisruptionBudget that would be violated by removing a set of pods.
+type PDBViolation struct {
+	PDB     policyv1.PodDisruptionBudget
+	Removed int32
+}
+
+// FindBlockingPDB finds a PodDisruptionBudget that would be violated by removing all the given pods,
+// which must all be in the same namespace. Returns nil if no PDB would be violated.
+func (f *Finder) FindBlockingPDB(ctx context.Context, namespace string, pods []corev1.Pod) (*policyv1.PodDisruptionBudget, error) {
+	violations, err := f.findPDBViolations(ctx, namespace, pods)
+	if err != nil {
+		return nil, err
+	}
+	if len(violations) == 0 {
+		return nil, nil
+	}
+	return &violations[0].PDB, nil
+}
+
+// FindPDBViolations finds all PodDisruptionBudgets that would be violated by removing all the given
+// pods at once. Unlike FindBlockingPDB, this considers the combined effect of scaling down multiple
+// controllers whose pods are selected by the same PDB.
+func (f *Finder) FindPDBViolations(ctx context.Context, pods []corev1.Pod) ([]PDBViolation, error) {
+	podsPerNs := make(map[string][]corev1.Pod)
+	for _, pod := range pods {
+		podsPerNs[pod.Namespace] = append(podsPerNs[pod.Namespace], pod)
+	}
+
+	var violations []PDBViolation
+	for _, ns := range slices.Sorted(maps.Keys(podsPerNs)) {
+		nsViolations, err := f.findPDBViolations(ctx, ns, podsPerNs[ns])
+		if err != nil {
+			return nil, err
+		}
+		violations = append(violations, nsViolations...)
+	}
+	return violations, nil
+}
+
+func (f *Finder) findPDBViolations(ctx context.Context, namespace string, pods []corev1.Pod) ([]PDBViolation, error) {
+	pdbList, err := f.clientset.PolicyV1().PodDisruptionBudgets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pod disruption budgets: %w", err)
+	}
+
+	var violations []PDBViolation
+	for _, pdb := range pdbList.Items {
+		if pdb.Spec.Selector == nil {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid selector on pod disruption budget %s/%s: %w", pdb.Namespace, pdb.Name, err)
+		}
+
+		matched := int32(0)
+		for _, pod := range pods {
+			if selector.Matches(labels.Set(pod.Labels)) {
+				matched++
+			}
+		}
+		if matched > 0 && pdb.Status.CurrentHealthy-matched < pdb.Status.DesiredHealthy {
+			violations = append(violations, PDBViolation{PDB: pdb, Removed: matched})
+		}
+	}
+
+	return violations, nil
+}
@@ -0,0 +1,104 @@
+package discovery
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/logger"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestFindBlockingPDB(t *testing.T) {
+	pdb := &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-pdb", Namespace: "test-ns"},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			MinAvailable: &intstr.IntOrString{Type: intstr.Int, IntVal: 1},
+			Selector:     &metav1.LabelSelector{MatchLabels: map[string]string{"app": "test"}},
+		},
+		Status: policyv1.PodDisruptionBudgetStatus{
+			CurrentHealthy: 2,
+			DesiredHealthy: 1,
+		},
+	}
+	finder := New(fake.NewClientset(pdb), logger.NewLogger(&bytes.Buffer{}, logger.LevelInfo))
+
+	tests := []struct {
+		name     string
+		pods     []corev1.Pod
+		blocking bool
+	}{
+		{
+			name:     "unrelated pods",
+			pods:     []corev1.Pod{newLabeledPod("other-1", "other"), newLabeledPod("other-2", "other")},
+			blocking: false,
+		},
+		{
+			name:     "some selected pods",
+			pods:     []corev1.Pod{newLabeledPod("test-1", "test")},
+			blocking: false,
+		},
+		{
+			name:     "all selected pods",
+			pods:     []corev1.Pod{newLabeledPod("test-1", "test"), newLabeledPod("test-2", "test")},
+			blocking: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocking, err := finder.FindBlockingPDB(context.Background(), "test-ns", tt.pods)
+			require.NoError(t, err)
+			if tt.blocking {
+				require.NotNil(t, blocking)
+				require.Equal(t, "test-pdb", blocking.Name)
+			} else {
+				require.Nil(t, blocking)
+			}
+		})
+	}
+}
+
+func TestFindPDBViolations(t *testing.T) {
+	pdb := &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-pdb", Namespace: "test-ns"},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			MinAvailable: &intstr.IntOrString{Type: intstr.Int, IntVal: 1},
+			Selector:     &metav1.LabelSelector{MatchLabels: map[string]string{"app": "test"}},
+		},
+		Status: policyv1.PodDisruptionBudgetStatus{
+			CurrentHealthy: 2,
+			DesiredHealthy: 1,
+		},
+	}
+	finder := New(fake.NewClientset(pdb), logger.NewLogger(&bytes.Buffer{}, logger.LevelInfo))
+
+	// Pods from two different controllers, neither of which violates the PDB on its own
+	first := []corev1.Pod{newLabeledPod("test-1", "test")}
+	second := []corev1.Pod{newLabeledPod("test-2", "test")}
+	for _, pods := range [][]corev1.Pod{first, second} {
+		blocking, err := finder.FindBlockingPDB(context.Background(), "test-ns", pods)
+		require.NoError(t, err)
+		require.Nil(t, blocking)
+	}
+
+	violations, err := finder.FindPDBViolations(context.Background(), append(first, second...))
+	require.NoError(t, err)
+	require.Len(t, violations, 1)
+	require.Equal(t, "test-pdb", violations[0].PDB.Name)
+	require.Equal(t, int32(2), violations[0].Removed)
+}
+
+func newLabeledPod(name, app string) corev1.Pod {
+	return corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "test-ns",
+			Labels:    map[string]string{"app": app},
+		},
+	}
+}
@@ -5,35 +5,218 @@ import (
 	"fmt"
 	"maps"
 	"slices"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-// FindPodsUsingPVCs finds all pods that are using the given PVCs.
+// PodFilter contains criteria for filtering pods during discovery.
+type PodFilter struct {
+	LabelSelector string
+	FieldSelector string
+	// Phases limits pods to those in one of the given phases. If empty, all pods that haven't
+	// terminated (Succeeded or Failed) are included.
+	Phases []corev1.PodPhase
+	// Annotations limits pods to those with all of the given annotations. The API server can't filter by
+	// annotation, so this is applied after listing the pods.
+	Annotations map[string]string
+}
+
+// FindPodsUsingPVCs finds all pods that are using the given PVCs and match the given filter.
 // Returns a deduplicated list of pods.
-func (f *Finder) FindPodsUsingPVCs(ctx context.Context, pvcsPerNs map[string][]string) ([]corev1.Pod, error) {
+func (f *Finder) FindPodsUsingPVCs(ctx context.Context, pvcsPerNs map[string][]string, filter PodFilter) ([]corev1.Pod, error) {
 	pods := make(map[string]corev1.Pod) // key: namespace/name
 
 	for ns, pvcs := range pvcsPerNs {
-		podList, err := f.clientset.CoreV1().Pods(ns).List(ctx, metav1.ListOptions{})
+		podList, err := f.listPods(ctx, ns, filter)
 		if err != nil {
-			return nil, fmt.Errorf("failed to list pods: %w", err)
+			return nil, err
 		}
 
-		for _, pod := range podList.Items {
-			if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		for _, pod := range podList {
+			if !matchesPhase(pod, filter.Phases) {
 				continue
 			}
-			for _, vol := range pod.Spec.Volumes {
-				if vol.PersistentVolumeClaim != nil && slices.Contains(pvcs, vol.PersistentVolumeClaim.ClaimName) {
-					key := fmt.Sprintf("%s/%s", pod.Namespace, pod.Name)
-					pods[key] = pod
-					break // Found a matching PVC, no need to check other volumes
-				}
+			if slices.ContainsFunc(pvcs, func(pvc string) bool { return usesPVC(pod, pvc) }) {
+				key := fmt.Sprintf("%s/%s", pod.Namespace, pod.Name)
+				pods[key] = pod
 			}
 		}
 	}
 
 	return slices.Collect(maps.Values(pods)), nil
 }
+
+// FindMountedPVCs filters the given PVCs down to those that are mounted by at least one running pod
+// matching the given filter. Returns a map from namespace to list of PVC names.
+func (f *Finder) FindMountedPVCs(ctx context.Context, pvcsPerNs map[string][]string, filter PodFilter) (map[string][]string, error) {
+	mounted := make(map[string][]string)
+
+	for ns, pvcs := range pvcsPerNs {
+		podList, err := f.listPods(ctx, ns, filter)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, pvc := range pvcs {
+			if slices.ContainsFunc(podList, func(pod corev1.Pod) bool {
+				return pod.Status.Phase == corev1.PodRunning && usesPVC(pod, pvc)
+			}) {
+				mounted[ns] = append(mounted[ns], pvc)
+			} else {
+				f.log.Info("PVC %s/%s has no running pods; skipping", ns, pvc)
+			}
+		}
+	}
+
+	return mounted, nil
+}
+
+// PVCsUsedBy returns the (deduplicated) PVCs from the given set that are used by any of the given pods,
+// formatted as "namespace/name".
+func PVCsUsedBy(pods []corev1.Pod, pvcsPerNs map[string][]string) []string {
+	used := make(map[string]struct{})
+	for _, pod := range pods {
+		for _, pvc := range pvcsPerNs[pod.Namespace] {
+			if usesPVC(pod, pvc) {
+				used[fmt.Sprintf("%s/%s", pod.Namespace, pvc)] = struct{}{}
+			}
+		}
+	}
+	return slices.Sorted(maps.Keys(used))
+}
+
+func (f *Finder) listPods(ctx context.Context, namespace string, filter PodFilter) ([]corev1.Pod, error) {
+	f.log.Debug("Listing pods in namespace %q (label selector %q, field selector %q)",
+		namespace, filter.LabelSelector, filter.FieldSelector)
+	podList, err := f.clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: filter.LabelSelector,
+		FieldSelector: filter.FieldSelector,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+	if len(filter.Annotations) == 0 {
+		return podList.Items, nil
+	}
+	return slices.DeleteFunc(podList.Items, func(pod corev1.Pod) bool {
+		key, value, ok := hasAnnotations(pod, filter.Annotations)
+		if !ok {
+			f.log.Debug("Skipping pod %s/%s, it doesn't have the annotation %s=%s", pod.Namespace, pod.Name, key, value)
+		}
+		return !ok
+	}), nil
+}
+
+// Matches checks whether the pod matches the criteria that the API server can't filter pods by (their phase and
+// annotations), for pods that weren't found by listing them, e.g. ones being watched.
+func (filter PodFilter) Matches(pod corev1.Pod) bool {
+	_, _, ok := hasAnnotations(pod, filter.Annotations)
+	return ok && matchesPhase(pod, filter.Phases)
+}
+
+// hasAnnotations checks whether the pod has all of the given annotations. If it doesn't, also returns one of the
+// annotations that it's missing.
+func hasAnnotations(pod corev1.Pod, annotations map[string]string) (string, string, bool) {
+	for key, value := range annotations {
+		if actual, ok := pod.Annotations[key]; !ok || actual != value {
+			return key, value, false
+		}
+	}
+	return "", "", true
+}
+
+func matchesPhase(pod corev1.Pod, phases []corev1.PodPhase) bool {
+	if len(phases) == 0 {
+		return pod.Status.Phase != corev1.PodSucceeded && pod.Status.Phase != corev1.PodFailed
+	}
+	return slices.Contains(phases, pod.Status.Phase)
+}
+
+// UnschedulableSince returns the time since which the pod has been Pending because it can't be scheduled
+// (e.g. because of unsatisfiable node affinity), or false if it isn't stuck waiting to be scheduled.
+func UnschedulableSince(pod corev1.Pod) (time.Time, bool) {
+	if pod.Status.Phase != corev1.PodPending {
+		return time.Time{}, false
+	}
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodScheduled && cond.Status == corev1.ConditionFalse && cond.Reason == corev1.PodReasonUnschedulable {
+			return cond.LastTransitionTime.Time, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// VolumeMount describes where a container mounts one of the targeted PVCs.
+type VolumeMount struct {
+	Container string
+	Path      string
+	PVC       string
+}
+
+// VolumeMounts returns where the pod's containers (including init containers) mount any of the given PVCs.
+func VolumeMounts(pod corev1.Pod, pvcs []string) []VolumeMount {
+	volumePVCs := make(map[string]string) // volume name -> PVC name
+	for _, vol := range pod.Spec.Volumes {
+		if pvc := claimName(pod, vol); pvc != "" && slices.Contains(pvcs, pvc) {
+			volumePVCs[vol.Name] = pvc
+		}
+	}
+
+	var mounts []VolumeMount
+	for _, container := range slices.Concat(pod.Spec.InitContainers, pod.Spec.Containers) {
+		for _, mount := range container.VolumeMounts {
+			if pvc, ok := volumePVCs[mount.Name]; ok {
+				mounts = append(mounts, VolumeMount{Container: container.Name, Path: mount.MountPath, PVC: pvc})
+			}
+		}
+	}
+	return mounts
+}
+
+// EphemeralPVCs returns the names of the PVCs created for the pod's generic ephemeral volumes.
+// These PVCs are owned by the pod, so they're garbage-collected when the pod is deleted.
+func EphemeralPVCs(pod corev1.Pod) []string {
+	var pvcs []string
+	for _, vol := range pod.Spec.Volumes {
+		if vol.Ephemeral != nil {
+			pvcs = append(pvcs, claimName(pod, vol))
+		}
+	}
+	return pvcs
+}
+
+// CountPodsUsingPVC counts how many of the given pods use the given PVC.
+func CountPodsUsingPVC(pods []corev1.Pod, namespace, pvc string) int {
+	count := 0
+	for _, pod := range pods {
+		if pod.Namespace == namespace && usesPVC(pod, pvc) {
+			count++
+		}
+	}
+	return count
+}
+
+// usesPVC checks whether any of the pod's volumes reference the given PVC.
+func usesPVC(pod corev1.Pod, pvc string) bool {
+	for _, vol := range pod.Spec.Volumes {
+		if claimName(pod, vol) == pvc {
+			return true
+		}
+	}
+	return false
+}
+
+// claimName returns the name of the PVC backing the given volume, or "" if it isn't backed by a PVC.
+func claimName(pod corev1.Pod, vol corev1.Volume) string {
+	switch {
+	case vol.PersistentVolumeClaim != nil:
+		return vol.PersistentVolumeClaim.ClaimName
+	case vol.Ephemeral != nil:
+		// Generic ephemeral volumes create a PVC named <pod>-<volume>
+		return fmt.Sprintf("%s-%s", pod.Name, vol.Name)
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,250 @@
+package discovery
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/logger"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestFindPodsUsingPVCsForwardsSelectors(t *testing.T) {
+	clientset := fake.NewClientset()
+
+	var listOpts []metav1.ListOptions
+	clientset.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		restrictions := action.(k8stesting.ListAction).GetListRestrictions()
+		listOpts = append(listOpts, metav1.ListOptions{
+			LabelSelector: restrictions.Labels.String(),
+			FieldSelector: restrictions.Fields.String(),
+		})
+		return true, &corev1.PodList{}, nil
+	})
+
+	finder := New(clientset, logger.NewLogger(&bytes.Buffer{}, logger.LevelInfo))
+	_, err := finder.FindPodsUsingPVCs(context.Background(), map[string][]string{
+		"test-ns": {"test-pvc"},
+	}, PodFilter{
+		LabelSelector: "app=test",
+		FieldSelector: "spec.nodeName=node-1",
+	})
+	require.NoError(t, err)
+
+	require.Len(t, listOpts, 1)
+	require.Equal(t, "app=test", listOpts[0].LabelSelector)
+	require.Equal(t, "spec.nodeName=node-1", listOpts[0].FieldSelector)
+}
+
+func TestFindPodsUsingPVCsMatchesEphemeralVolumes(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-pod", Namespace: "test-ns"},
+		Spec: corev1.PodSpec{
+			Volumes: []corev1.Volume{
+				{
+					Name: "scratch",
+					VolumeSource: corev1.VolumeSource{
+						Ephemeral: &corev1.EphemeralVolumeSource{},
+					},
+				},
+			},
+		},
+	}
+	finder := New(fake.NewClientset(pod), logger.NewLogger(&bytes.Buffer{}, logger.LevelInfo))
+
+	pods, err := finder.FindPodsUsingPVCs(context.Background(), map[string][]string{
+		"test-ns": {"test-pod-scratch"},
+	}, PodFilter{})
+	require.NoError(t, err)
+	require.Len(t, pods, 1)
+	require.Equal(t, "test-pod", pods[0].Name)
+	require.Equal(t, []string{"test-pod-scratch"}, EphemeralPVCs(pods[0]))
+}
+
+func TestFindPodsUsingPVCsFiltersByAnnotations(t *testing.T) {
+	newPod := func(name string, annotations map[string]string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test-ns", Annotations: annotations},
+			Spec: corev1.PodSpec{
+				Volumes: []corev1.Volume{
+					{
+						Name: "data",
+						VolumeSource: corev1.VolumeSource{
+							PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "test-pvc"},
+						},
+					},
+				},
+			},
+		}
+	}
+	finder := New(fake.NewClientset(
+		newPod("nightly", map[string]string{"maintenance-window": "nightly", "team": "data"}),
+		newPod("weekly", map[string]string{"maintenance-window": "weekly"}),
+		newPod("unannotated", nil),
+	), logger.NewLogger(&bytes.Buffer{}, logger.LevelInfo))
+
+	pods, err := finder.FindPodsUsingPVCs(context.Background(), map[string][]string{
+		"test-ns": {"test-pvc"},
+	}, PodFilter{Annotations: map[string]string{"maintenance-window": "nightly"}})
+	require.NoError(t, err)
+	require.Len(t, pods, 1)
+	require.Equal(t, "nightly", pods[0].Name)
+}
+
+func TestFindPodsUsingPVCsMatchesInitContainers(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-pod", Namespace: "test-ns"},
+		Spec: corev1.PodSpec{
+			InitContainers: []corev1.Container{
+				{Name: "restore", VolumeMounts: []corev1.VolumeMount{{Name: "data", MountPath: "/restore"}}},
+			},
+			Containers: []corev1.Container{{Name: "app"}},
+			Volumes: []corev1.Volume{
+				{
+					Name: "data",
+					VolumeSource: corev1.VolumeSource{
+						PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "test-pvc"},
+					},
+				},
+			},
+		},
+		Status: corev1.PodStatus{Phase: corev1.PodPending},
+	}
+	finder := New(fake.NewClientset(pod), logger.NewLogger(&bytes.Buffer{}, logger.LevelInfo))
+
+	pods, err := finder.FindPodsUsingPVCs(context.Background(), map[string][]string{
+		"test-ns": {"test-pvc"},
+	}, PodFilter{})
+	require.NoError(t, err)
+	require.Len(t, pods, 1)
+	require.Equal(t, []VolumeMount{{Container: "restore", Path: "/restore", PVC: "test-pvc"}}, VolumeMounts(pods[0], []string{"test-pvc"}))
+}
+
+func TestFindPodsUsingPVCsFiltersByPhase(t *testing.T) {
+	newPod := func(name string, phase corev1.PodPhase) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test-ns"},
+			Spec: corev1.PodSpec{
+				Volumes: []corev1.Volume{
+					{
+						Name: "data",
+						VolumeSource: corev1.VolumeSource{
+							PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "test-pvc"},
+						},
+					},
+				},
+			},
+			Status: corev1.PodStatus{Phase: phase},
+		}
+	}
+	finder := New(fake.NewClientset(
+		newPod("running", corev1.PodRunning),
+		newPod("failed", corev1.PodFailed),
+		newPod("unknown", corev1.PodUnknown),
+	), logger.NewLogger(&bytes.Buffer{}, logger.LevelInfo))
+	pvcsPerNs := map[string][]string{"test-ns": {"test-pvc"}}
+
+	tests := []struct {
+		phases   []corev1.PodPhase
+		expected []string
+	}{
+		{phases: nil, expected: []string{"running", "unknown"}},
+		{phases: []corev1.PodPhase{corev1.PodRunning, corev1.PodPending}, expected: []string{"running"}},
+		{phases: []corev1.PodPhase{corev1.PodFailed}, expected: []string{"failed"}},
+		{phases: []corev1.PodPhase{corev1.PodUnknown}, expected: []string{"unknown"}},
+	}
+	for _, tt := range tests {
+		pods, err := finder.FindPodsUsingPVCs(context.Background(), pvcsPerNs, PodFilter{Phases: tt.phases})
+		require.NoError(t, err)
+		var names []string
+		for _, pod := range pods {
+			names = append(names, pod.Name)
+		}
+		require.ElementsMatch(t, tt.expected, names)
+	}
+}
+
+func TestUnschedulableSince(t *testing.T) {
+	since := metav1.NewTime(time.Now().Add(-time.Minute).Truncate(time.Second))
+	unschedulable := corev1.Pod{
+		Status: corev1.PodStatus{
+			Phase: corev1.PodPending,
+			Conditions: []corev1.PodCondition{{
+				Type:               corev1.PodScheduled,
+				Status:             corev1.ConditionFalse,
+				Reason:             corev1.PodReasonUnschedulable,
+				LastTransitionTime: since,
+			}},
+		},
+	}
+	got, ok := UnschedulableSince(unschedulable)
+	require.True(t, ok)
+	require.Equal(t, since.Time, got)
+
+	_, ok = UnschedulableSince(corev1.Pod{Status: corev1.PodStatus{Phase: corev1.PodPending}})
+	require.False(t, ok)
+	_, ok = UnschedulableSince(corev1.Pod{Status: corev1.PodStatus{Phase: corev1.PodRunning}})
+	require.False(t, ok)
+}
+
+func TestVolumeMounts(t *testing.T) {
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-pod", Namespace: "test-ns"},
+		Spec: corev1.PodSpec{
+			Volumes: []corev1.Volume{
+				{Name: "data", VolumeSource: corev1.VolumeSource{
+					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "test-pvc"},
+				}},
+				{Name: "other", VolumeSource: corev1.VolumeSource{
+					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "other-pvc"},
+				}},
+			},
+			InitContainers: []corev1.Container{{
+				Name:         "init",
+				VolumeMounts: []corev1.VolumeMount{{Name: "data", MountPath: "/init-data"}},
+			}},
+			Containers: []corev1.Container{{
+				Name: "test-container",
+				VolumeMounts: []corev1.VolumeMount{
+					{Name: "data", MountPath: "/data"},
+					{Name: "other", MountPath: "/other"},
+				},
+			}},
+		},
+	}
+
+	require.Equal(t, []VolumeMount{
+		{Container: "init", Path: "/init-data", PVC: "test-pvc"},
+		{Container: "test-container", Path: "/data", PVC: "test-pvc"},
+	}, VolumeMounts(pod, []string{"test-pvc"}))
+}
+
+func TestFindPodsUsingPVCsDeduplicatesPods(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-pod", Namespace: "test-ns"},
+		Spec: corev1.PodSpec{
+			Volumes: []corev1.Volume{
+				{Name: "shard-0", VolumeSource: corev1.VolumeSource{
+					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "shard-0"},
+				}},
+				{Name: "shard-1", VolumeSource: corev1.VolumeSource{
+					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "shard-1"},
+				}},
+			},
+		},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	finder := New(fake.NewClientset(pod), logger.NewLogger(&bytes.Buffer{}, logger.LevelInfo))
+
+	pods, err := finder.FindPodsUsingPVCs(context.Background(), map[string][]string{
+		"test-ns": {"shard-0", "shard-1"},
+	}, PodFilter{})
+	require.NoError(t, err)
+	require.Len(t, pods, 1)
+}
@@ -0,0 +1,79 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/common"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// IsProtected checks whether the controller or any of its pods has the given annotation set to "true", so that
+// application owners can protect their workloads from ever being scaled down. Only the pods of custom resources
+// are checked.
+func (f *Finder) IsProtected(ctx context.Context, ctrl common.ControllerRef, pods []corev1.Pod, annotation string) (bool, error) {
+	if slices.ContainsFunc(pods, func(pod corev1.Pod) bool { return pod.Annotations[annotation] == "true" }) {
+		return true, nil
+	}
+	meta, err := f.controllerMeta(ctx, ctrl)
+	if err != nil {
+		return false, fmt.Errorf("failed to get %v: %w", ctrl, err)
+	}
+	return meta != nil && meta.Annotations[annotation] == "true", nil
+}
+
+// controllerMeta gets the metadata of the given controller, or nil if it's a standalone pod (whose metadata the
+// caller already has) or a custom resource.
+func (f *Finder) controllerMeta(ctx context.Context, ctrl common.ControllerRef) (*metav1.ObjectMeta, error) {
+	apps := f.clientset.AppsV1()
+	batch := f.clientset.BatchV1()
+	opts := metav1.GetOptions{}
+	switch ctrl.Kind {
+	case common.KindDeployment:
+		d, err := apps.Deployments(ctrl.Namespace).Get(ctx, ctrl.Name, opts)
+		if err != nil {
+			return nil, err
+		}
+		return &d.ObjectMeta, nil
+	case common.KindStatefulSet:
+		sts, err := apps.StatefulSets(ctrl.Namespace).Get(ctx, ctrl.Name, opts)
+		if err != nil {
+			return nil, err
+		}
+		return &sts.ObjectMeta, nil
+	case common.KindReplicaSet:
+		rs, err := apps.ReplicaSets(ctrl.Namespace).Get(ctx, ctrl.Name, opts)
+		if err != nil {
+			return nil, err
+		}
+		return &rs.ObjectMeta, nil
+	case common.KindReplicationController:
+		rc, err := f.clientset.CoreV1().ReplicationControllers(ctrl.Namespace).Get(ctx, ctrl.Name, opts)
+		if err != nil {
+			return nil, err
+		}
+		return &rc.ObjectMeta, nil
+	case common.KindDaemonSet:
+		ds, err := apps.DaemonSets(ctrl.Namespace).Get(ctx, ctrl.Name, opts)
+		if err != nil {
+			return nil, err
+		}
+		return &ds.ObjectMeta, nil
+	case common.KindJob:
+		job, err := batch.Jobs(ctrl.Namespace).Get(ctx, ctrl.Name, opts)
+		if err != nil {
+			return nil, err
+		}
+		return &job.ObjectMeta, nil
+	case common.KindCronJob:
+		cronJob, err := batch.CronJobs(ctrl.Namespace).Get(ctx, ctrl.Name, opts)
+		if err != nil {
+			return nil, err
+		}
+		return &cronJob.ObjectMeta, nil
+	default:
+		return nil, nil
+	}
+}
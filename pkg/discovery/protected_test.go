@@ -0,0 +1,51 @@
+package discovery
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/common"
+	"github.com/dancavallaro/kubectl-unmount/pkg/logger"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestIsProtected(t *testing.T) {
+	protect := map[string]string{common.AnnotationSkip: "true"}
+	clientset := fake.NewClientset(
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "protected", Namespace: "test-ns", Annotations: protect}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "test-ns"}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{
+			Name: "not-protected", Namespace: "test-ns", Annotations: map[string]string{common.AnnotationSkip: "false"},
+		}},
+	)
+	finder := New(clientset, logger.NewLogger(&bytes.Buffer{}, logger.LevelInfo))
+	deployment := func(name string) common.ControllerRef {
+		return common.ControllerRef{Kind: common.KindDeployment, Namespace: "test-ns", Name: name}
+	}
+	protectedPod := corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web-abc", Namespace: "test-ns", Annotations: protect}}
+
+	for _, tc := range []struct {
+		name string
+		ctrl common.ControllerRef
+		pods []corev1.Pod
+		want bool
+	}{
+		{name: "annotated controller", ctrl: deployment("protected"), want: true},
+		{name: "annotated pod", ctrl: deployment("web"), pods: []corev1.Pod{protectedPod}, want: true},
+		{name: "not annotated", ctrl: deployment("web"), pods: []corev1.Pod{{}}, want: false},
+		{name: "annotated with false", ctrl: deployment("not-protected"), want: false},
+		{name: "standalone pod", ctrl: common.ControllerRef{Kind: common.KindPod, Namespace: "test-ns", Name: "web-abc"},
+			pods: []corev1.Pod{protectedPod}, want: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			protected, err := finder.IsProtected(context.Background(), tc.ctrl, tc.pods, common.AnnotationSkip)
+			require.NoError(t, err)
+			require.Equal(t, tc.want, protected)
+		})
+	}
+}
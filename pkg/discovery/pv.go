@@ -0,0 +1,30 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// FindPVCForPV resolves a PersistentVolume to the PVC that it's bound to, via its claimRef.
+// Returns a nil reference if the PV isn't currently claimed by any PVC.
+func (f *Finder) FindPVCForPV(ctx context.Context, name string) (*corev1.ObjectReference, error) {
+	pv, err := f.clientset.CoreV1().PersistentVolumes().Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get persistent volume %s: %w", name, err)
+	}
+
+	if pv.Spec.ClaimRef == nil {
+		f.log.Info("PV %s is not claimed by any PVC", name)
+		return nil, nil
+	}
+	if pv.Status.Phase == corev1.VolumeReleased {
+		f.log.Info("PV %s is Released (its PVC %s/%s has been deleted)",
+			name, pv.Spec.ClaimRef.Namespace, pv.Spec.ClaimRef.Name)
+		return nil, nil
+	}
+
+	return pv.Spec.ClaimRef, nil
+}
@@ -3,28 +3,80 @@ package discovery
 import (
 	"context"
 	"fmt"
+	"regexp"
+	"slices"
 
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // PVCFilter contains criteria for filtering PVCs during discovery.
 type PVCFilter struct {
-	Namespace    string
-	StorageClass string
+	Namespace      string
+	StorageClasses []string
+	// AccessMode only matches PVCs that have this access mode, if set.
+	AccessMode corev1.PersistentVolumeAccessMode
+	// NameRegex only matches PVCs whose name matches this regular expression, if set.
+	NameRegex *regexp.Regexp
+	// BoundOnly skips PVCs that aren't Bound (e.g. Pending ones waiting for their first consumer), since they
+	// can't be mounted by anything yet.
+	BoundOnly bool
+	// CSIDriver only matches PVCs whose PV is provisioned by this CSI driver, if set.
+	CSIDriver string
+	// MinSize only matches PVCs requesting at least this much storage, if set.
+	MinSize *resource.Quantity
+}
+
+// AccessModes maps the abbreviations of access modes (as shown by kubectl get pvc) to the access modes.
+var AccessModes = map[string]corev1.PersistentVolumeAccessMode{
+	"RWO":  corev1.ReadWriteOnce,
+	"ROX":  corev1.ReadOnlyMany,
+	"RWX":  corev1.ReadWriteMany,
+	"RWOP": corev1.ReadWriteOncePod,
 }
 
 // FindPVCs discovers all PVCs that match the given filters.
 // Returns a map from namespace to list of PVC names.
 func (f *Finder) FindPVCs(ctx context.Context, filter PVCFilter) (map[string][]string, error) {
 	pvcsPerNs := make(map[string][]string)
+	f.log.Debug("Listing PVCs in namespace %q", filter.Namespace)
 
 	pvcList, err := f.clientset.CoreV1().PersistentVolumeClaims(filter.Namespace).List(ctx, metav1.ListOptions{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to list persistent volume claims: %w", err)
 	}
+	var csiDrivers map[string]string
+	if filter.CSIDriver != "" {
+		if csiDrivers, err = f.csiDriversByPV(ctx); err != nil {
+			return nil, err
+		}
+	}
 
 	for _, pvc := range pvcList.Items {
-		if !matchesStorageClass(pvc.Spec.StorageClassName, filter.StorageClass) {
+		if !matchesStorageClass(pvc.Spec.StorageClassName, filter.StorageClasses) {
+			f.log.Debug("Skipping PVC %s/%s, its storage class doesn't match", pvc.Namespace, pvc.Name)
+			continue
+		}
+		if filter.NameRegex != nil && !filter.NameRegex.MatchString(pvc.Name) {
+			f.log.Debug("Skipping PVC %s/%s, its name doesn't match %q", pvc.Namespace, pvc.Name, filter.NameRegex)
+			continue
+		}
+		if filter.BoundOnly && pvc.Status.Phase != corev1.ClaimBound {
+			f.log.Debug("Skipping PVC %s/%s, it's not bound (phase %q)", pvc.Namespace, pvc.Name, pvc.Status.Phase)
+			continue
+		}
+		if filter.AccessMode != "" && !slices.Contains(pvc.Spec.AccessModes, filter.AccessMode) {
+			f.log.Debug("Skipping PVC %s/%s, it doesn't have the %s access mode", pvc.Namespace, pvc.Name, filter.AccessMode)
+			continue
+		}
+		if filter.CSIDriver != "" && csiDrivers[pvc.Spec.VolumeName] != filter.CSIDriver {
+			f.log.Debug("Skipping PVC %s/%s, its PV isn't provisioned by CSI driver %s", pvc.Namespace, pvc.Name, filter.CSIDriver)
+			continue
+		}
+		if size := pvc.Spec.Resources.Requests.Storage(); filter.MinSize != nil && size.Cmp(*filter.MinSize) < 0 {
+			f.log.Debug("Skipping PVC %s/%s, its size %s is less than %s", pvc.Namespace, pvc.Name, size, filter.MinSize)
 			continue
 		}
 		pvcsPerNs[pvc.Namespace] = append(pvcsPerNs[pvc.Namespace], pvc.Name)
@@ -33,12 +85,69 @@ func (f *Finder) FindPVCs(ctx context.Context, filter PVCFilter) (map[string][]s
 	return pvcsPerNs, nil
 }
 
-func matchesStorageClass(storageClassName *string, filter string) bool {
-	if filter == "" {
+// csiDriversByPV maps the name of each CSI PersistentVolume to its driver. All PVs are listed at once, rather than
+// getting each candidate PVC's PV separately.
+func (f *Finder) csiDriversByPV(ctx context.Context) (map[string]string, error) {
+	pvList, err := f.clientset.CoreV1().PersistentVolumes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list persistent volumes: %w", err)
+	}
+	drivers := make(map[string]string)
+	for _, pv := range pvList.Items {
+		if pv.Spec.CSI != nil {
+			drivers[pv.Name] = pv.Spec.CSI.Driver
+		}
+	}
+	return drivers, nil
+}
+
+// PVCExists checks whether the given PVC exists.
+func (f *Finder) PVCExists(ctx context.Context, namespace, name string) (bool, error) {
+	_, err := f.clientset.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get persistent volume claim %s/%s: %w", namespace, name, err)
+	}
+	return true, nil
+}
+
+// FindSharedPVCs finds which of the given PVCs have the ReadWriteMany access mode, so they may be mounted by
+// several pods (possibly on different nodes) at once. Returns the PVCs formatted as "namespace/name".
+func (f *Finder) FindSharedPVCs(ctx context.Context, pvcsPerNs map[string][]string) ([]string, error) {
+	return f.FindPVCsWithAccessMode(ctx, pvcsPerNs, corev1.ReadWriteMany)
+}
+
+// FindPVCsWithAccessMode finds which of the given PVCs have the given access mode. Returns the PVCs formatted as
+// "namespace/name".
+func (f *Finder) FindPVCsWithAccessMode(ctx context.Context, pvcsPerNs map[string][]string,
+	accessMode corev1.PersistentVolumeAccessMode) ([]string, error) {
+	var found []string
+	for ns, pvcs := range pvcsPerNs {
+		for _, name := range pvcs {
+			pvc, err := f.clientset.CoreV1().PersistentVolumeClaims(ns).Get(ctx, name, metav1.GetOptions{})
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to get persistent volume claim %s/%s: %w", ns, name, err)
+			}
+			if slices.Contains(pvc.Spec.AccessModes, accessMode) {
+				found = append(found, fmt.Sprintf("%s/%s", ns, name))
+			}
+		}
+	}
+	slices.Sort(found)
+	return found, nil
+}
+
+func matchesStorageClass(storageClassName *string, filter []string) bool {
+	if len(filter) == 0 {
 		return true
 	}
 	if storageClassName == nil {
 		return false
 	}
-	return *storageClassName == filter
+	return slices.Contains(filter, *storageClassName)
 }
@@ -0,0 +1,163 @@
+package discovery
+
+import (
+	"bytes"
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/logger"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
+)
+
+func TestFindPVCsWithMultipleStorageClasses(t *testing.T) {
+	newPVC := func(name string, storageClass *string) *corev1.PersistentVolumeClaim {
+		return &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test-ns"},
+			Spec:       corev1.PersistentVolumeClaimSpec{StorageClassName: storageClass},
+		}
+	}
+	clientset := fake.NewClientset(
+		newPVC("fast-pvc", ptr.To("fast-ssd")),
+		newPVC("retain-pvc", ptr.To("fast-ssd-retain")),
+		newPVC("standard-pvc", ptr.To("standard")),
+		newPVC("no-class-pvc", nil),
+	)
+	finder := New(clientset, logger.NewLogger(&bytes.Buffer{}, logger.LevelInfo))
+
+	pvcsPerNs, err := finder.FindPVCs(context.Background(), PVCFilter{
+		StorageClasses: []string{"fast-ssd", "fast-ssd-retain"},
+	})
+	require.NoError(t, err)
+	require.Len(t, pvcsPerNs, 1)
+	require.ElementsMatch(t, []string{"fast-pvc", "retain-pvc"}, pvcsPerNs["test-ns"])
+}
+
+func TestFindPVCsWithAccessMode(t *testing.T) {
+	newPVC := func(name string, accessMode corev1.PersistentVolumeAccessMode) *corev1.PersistentVolumeClaim {
+		return &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test-ns"},
+			Spec:       corev1.PersistentVolumeClaimSpec{AccessModes: []corev1.PersistentVolumeAccessMode{accessMode}},
+		}
+	}
+	clientset := fake.NewClientset(
+		newPVC("rwo-pvc", corev1.ReadWriteOnce),
+		newPVC("rwx-pvc", corev1.ReadWriteMany),
+	)
+	finder := New(clientset, logger.NewLogger(&bytes.Buffer{}, logger.LevelInfo))
+
+	pvcsPerNs, err := finder.FindPVCs(context.Background(), PVCFilter{AccessMode: AccessModes["RWO"]})
+	require.NoError(t, err)
+	require.Equal(t, map[string][]string{"test-ns": {"rwo-pvc"}}, pvcsPerNs)
+
+	pvcsPerNs, err = finder.FindPVCs(context.Background(), PVCFilter{})
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"rwo-pvc", "rwx-pvc"}, pvcsPerNs["test-ns"])
+}
+
+func TestFindPVCsBoundOnly(t *testing.T) {
+	newPVC := func(name string, phase corev1.PersistentVolumeClaimPhase) *corev1.PersistentVolumeClaim {
+		return &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test-ns"},
+			Status:     corev1.PersistentVolumeClaimStatus{Phase: phase},
+		}
+	}
+	clientset := fake.NewClientset(newPVC("bound-pvc", corev1.ClaimBound), newPVC("pending-pvc", corev1.ClaimPending))
+	var logs bytes.Buffer
+	finder := New(clientset, logger.NewLogger(&logs, logger.LevelDebug))
+
+	pvcsPerNs, err := finder.FindPVCs(context.Background(), PVCFilter{BoundOnly: true})
+	require.NoError(t, err)
+	require.Equal(t, map[string][]string{"test-ns": {"bound-pvc"}}, pvcsPerNs)
+	require.Contains(t, logs.String(), `Skipping PVC test-ns/pending-pvc, it's not bound (phase "Pending")`)
+
+	pvcsPerNs, err = finder.FindPVCs(context.Background(), PVCFilter{})
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"bound-pvc", "pending-pvc"}, pvcsPerNs["test-ns"])
+}
+
+func TestFindPVCsWithCSIDriver(t *testing.T) {
+	newPVC := func(name, pv string) *corev1.PersistentVolumeClaim {
+		return &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test-ns"},
+			Spec:       corev1.PersistentVolumeClaimSpec{VolumeName: pv},
+		}
+	}
+	newPV := func(name string, source corev1.PersistentVolumeSource) *corev1.PersistentVolume {
+		return &corev1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       corev1.PersistentVolumeSpec{PersistentVolumeSource: source},
+		}
+	}
+	clientset := fake.NewClientset(
+		newPVC("ebs-pvc", "pv-ebs"),
+		newPVC("nfs-pvc", "pv-nfs"),
+		newPVC("hostpath-pvc", "pv-hostpath"),
+		newPVC("unbound-pvc", ""),
+		newPV("pv-ebs", corev1.PersistentVolumeSource{CSI: &corev1.CSIPersistentVolumeSource{Driver: "ebs.csi.aws.com"}}),
+		newPV("pv-nfs", corev1.PersistentVolumeSource{CSI: &corev1.CSIPersistentVolumeSource{Driver: "nfs.csi.k8s.io"}}),
+		newPV("pv-hostpath", corev1.PersistentVolumeSource{HostPath: &corev1.HostPathVolumeSource{Path: "/data"}}),
+	)
+	finder := New(clientset, logger.NewLogger(&bytes.Buffer{}, logger.LevelInfo))
+
+	pvcsPerNs, err := finder.FindPVCs(context.Background(), PVCFilter{CSIDriver: "ebs.csi.aws.com"})
+	require.NoError(t, err)
+	require.Equal(t, map[string][]string{"test-ns": {"ebs-pvc"}}, pvcsPerNs)
+}
+
+func TestFindPVCsWithMinSize(t *testing.T) {
+	newPVC := func(name string, size string) *corev1.PersistentVolumeClaim {
+		pvc := &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test-ns"}}
+		if size != "" {
+			pvc.Spec.Resources.Requests = corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(size)}
+		}
+		return pvc
+	}
+	clientset := fake.NewClientset(newPVC("large-pvc", "100Gi"), newPVC("exact-pvc", "10Gi"), newPVC("small-pvc", "500Mi"),
+		newPVC("no-size-pvc", ""))
+	var logs bytes.Buffer
+	finder := New(clientset, logger.NewLogger(&logs, logger.LevelDebug))
+
+	pvcsPerNs, err := finder.FindPVCs(context.Background(), PVCFilter{MinSize: ptr.To(resource.MustParse("10Gi"))})
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"large-pvc", "exact-pvc"}, pvcsPerNs["test-ns"])
+	require.Contains(t, logs.String(), "Skipping PVC test-ns/small-pvc, its size 500Mi is less than 10Gi")
+}
+
+func TestFindPVCsWithNameRegex(t *testing.T) {
+	newPVC := func(name string) *corev1.PersistentVolumeClaim {
+		return &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test-ns"}}
+	}
+	clientset := fake.NewClientset(newPVC("data-mysql-0"), newPVC("data-mysql-1"), newPVC("data-mysql-backup"), newPVC("logs-mysql-0"))
+	finder := New(clientset, logger.NewLogger(&bytes.Buffer{}, logger.LevelInfo))
+
+	pvcsPerNs, err := finder.FindPVCs(context.Background(), PVCFilter{NameRegex: regexp.MustCompile(`^data-mysql-[0-9]+$`)})
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"data-mysql-0", "data-mysql-1"}, pvcsPerNs["test-ns"])
+}
+
+func TestFindSharedPVCs(t *testing.T) {
+	newPVC := func(name string, accessModes ...corev1.PersistentVolumeAccessMode) *corev1.PersistentVolumeClaim {
+		return &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test-ns"},
+			Spec:       corev1.PersistentVolumeClaimSpec{AccessModes: accessModes},
+		}
+	}
+	clientset := fake.NewClientset(
+		newPVC("nfs-pvc", corev1.ReadWriteMany),
+		newPVC("multi-mode-pvc", corev1.ReadWriteOnce, corev1.ReadWriteMany),
+		newPVC("rwo-pvc", corev1.ReadWriteOnce),
+	)
+	finder := New(clientset, logger.NewLogger(&bytes.Buffer{}, logger.LevelInfo))
+
+	shared, err := finder.FindSharedPVCs(context.Background(), map[string][]string{
+		"test-ns": {"nfs-pvc", "multi-mode-pvc", "rwo-pvc", "missing-pvc"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"test-ns/multi-mode-pvc", "test-ns/nfs-pvc"}, shared)
+}
@@ -0,0 +1,83 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/common"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ReadyReplicas gets the number of ready replicas reported in the status of the given controller.
+// Returns false if the controller's kind doesn't report ready replicas.
+func (f *Finder) ReadyReplicas(ctx context.Context, ctrl common.ControllerRef) (int32, bool, error) {
+	apps := f.clientset.AppsV1()
+	switch ctrl.Kind {
+	case common.KindDeployment:
+		d, err := apps.Deployments(ctrl.Namespace).Get(ctx, ctrl.Name, metav1.GetOptions{})
+		if err != nil {
+			return 0, true, fmt.Errorf("failed to get %v: %w", ctrl, err)
+		}
+		return d.Status.ReadyReplicas, true, nil
+	case common.KindReplicaSet:
+		rs, err := apps.ReplicaSets(ctrl.Namespace).Get(ctx, ctrl.Name, metav1.GetOptions{})
+		if err != nil {
+			return 0, true, fmt.Errorf("failed to get %v: %w", ctrl, err)
+		}
+		return rs.Status.ReadyReplicas, true, nil
+	case common.KindReplicationController:
+		rc, err := f.clientset.CoreV1().ReplicationControllers(ctrl.Namespace).Get(ctx, ctrl.Name, metav1.GetOptions{})
+		if err != nil {
+			return 0, true, fmt.Errorf("failed to get %v: %w", ctrl, err)
+		}
+		return rc.Status.ReadyReplicas, true, nil
+	case common.KindStatefulSet:
+		sts, err := apps.StatefulSets(ctrl.Namespace).Get(ctx, ctrl.Name, metav1.GetOptions{})
+		if err != nil {
+			return 0, true, fmt.Errorf("failed to get %v: %w", ctrl, err)
+		}
+		return sts.Status.ReadyReplicas, true, nil
+	default:
+		return 0, false, nil
+	}
+}
+
+// DesiredReplicas gets the number of replicas in the spec of the given controller.
+// Returns false if the controller's kind doesn't have a number of replicas.
+func (f *Finder) DesiredReplicas(ctx context.Context, ctrl common.ControllerRef) (int32, bool, error) {
+	apps := f.clientset.AppsV1()
+	var replicas *int32
+	switch ctrl.Kind {
+	case common.KindDeployment:
+		d, err := apps.Deployments(ctrl.Namespace).Get(ctx, ctrl.Name, metav1.GetOptions{})
+		if err != nil {
+			return 0, true, fmt.Errorf("failed to get %v: %w", ctrl, err)
+		}
+		replicas = d.Spec.Replicas
+	case common.KindReplicaSet:
+		rs, err := apps.ReplicaSets(ctrl.Namespace).Get(ctx, ctrl.Name, metav1.GetOptions{})
+		if err != nil {
+			return 0, true, fmt.Errorf("failed to get %v: %w", ctrl, err)
+		}
+		replicas = rs.Spec.Replicas
+	case common.KindReplicationController:
+		rc, err := f.clientset.CoreV1().ReplicationControllers(ctrl.Namespace).Get(ctx, ctrl.Name, metav1.GetOptions{})
+		if err != nil {
+			return 0, true, fmt.Errorf("failed to get %v: %w", ctrl, err)
+		}
+		replicas = rc.Spec.Replicas
+	case common.KindStatefulSet:
+		sts, err := apps.StatefulSets(ctrl.Namespace).Get(ctx, ctrl.Name, metav1.GetOptions{})
+		if err != nil {
+			return 0, true, fmt.Errorf("failed to get %v: %w", ctrl, err)
+		}
+		replicas = sts.Spec.Replicas
+	default:
+		return 0, false, nil
+	}
+	if replicas == nil {
+		// Replicas defaults to 1 if unset
+		return 1, true, nil
+	}
+	return *replicas, true, nil
+}
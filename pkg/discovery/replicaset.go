@@ -0,0 +1,46 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/common"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const revisionAnnotation = "deployment.kubernetes.io/revision"
+
+// FindOldReplicaSets finds the old ReplicaSets of a Deployment that are fully scaled down (0 desired,
+// ready, and available replicas), but haven't been garbage-collected by the Deployment controller yet.
+// Old ReplicaSets are only cleaned up when the Deployment's revisionHistoryLimit is 0, so this returns
+// nothing for Deployments that keep a revision history.
+func (f *Finder) FindOldReplicaSets(ctx context.Context, ctrl common.ControllerRef) ([]string, error) {
+	deploy, err := f.clientset.AppsV1().Deployments(ctrl.Namespace).Get(ctx, ctrl.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deployment %s/%s: %w", ctrl.Namespace, ctrl.Name, err)
+	}
+	if deploy.Spec.RevisionHistoryLimit == nil || *deploy.Spec.RevisionHistoryLimit != 0 {
+		return nil, nil
+	}
+
+	rsList, err := f.clientset.AppsV1().ReplicaSets(ctrl.Namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list replica sets: %w", err)
+	}
+
+	var old []string
+	for _, rs := range rsList.Items {
+		if !metav1.IsControlledBy(&rs, deploy) {
+			continue
+		}
+		// The current revision is never cleaned up, even when scaled to 0
+		if rs.Annotations[revisionAnnotation] == deploy.Annotations[revisionAnnotation] {
+			continue
+		}
+		scaledDown := rs.Spec.Replicas != nil && *rs.Spec.Replicas == 0
+		if scaledDown && rs.Status.ReadyReplicas == 0 && rs.Status.AvailableReplicas == 0 {
+			old = append(old, rs.Name)
+		}
+	}
+	return old, nil
+}
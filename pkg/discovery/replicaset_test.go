@@ -0,0 +1,85 @@
+package discovery
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/common"
+	"github.com/dancavallaro/kubectl-unmount/pkg/logger"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
+)
+
+func TestFindOldReplicaSets(t *testing.T) {
+	ctx := context.Background()
+	deploy := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "test-deployment",
+			Namespace:   "test-ns",
+			UID:         types.UID("deploy-uid"),
+			Annotations: map[string]string{revisionAnnotation: "2"},
+		},
+		Spec: appsv1.DeploymentSpec{
+			RevisionHistoryLimit: ptr.To[int32](0),
+		},
+	}
+	clientset := fake.NewClientset(
+		deploy,
+		newReplicaSet(deploy, "test-deployment-old", "1"),
+		newReplicaSet(deploy, "test-deployment-current", "2"),
+	)
+	finder := New(clientset, logger.NewLogger(&bytes.Buffer{}, logger.LevelInfo))
+	ctrl := common.ControllerRef{Kind: common.KindDeployment, Namespace: "test-ns", Name: "test-deployment"}
+
+	old, err := finder.FindOldReplicaSets(ctx, ctrl)
+	require.NoError(t, err)
+	require.Equal(t, []string{"test-deployment-old"}, old)
+
+	// Once the old ReplicaSet is garbage-collected, the wait condition is met
+	err = clientset.AppsV1().ReplicaSets("test-ns").Delete(ctx, "test-deployment-old", metav1.DeleteOptions{})
+	require.NoError(t, err)
+	old, err = finder.FindOldReplicaSets(ctx, ctrl)
+	require.NoError(t, err)
+	require.Empty(t, old)
+}
+
+func TestFindOldReplicaSetsWithRevisionHistory(t *testing.T) {
+	deploy := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "test-deployment",
+			Namespace:   "test-ns",
+			UID:         types.UID("deploy-uid"),
+			Annotations: map[string]string{revisionAnnotation: "2"},
+		},
+		Spec: appsv1.DeploymentSpec{
+			RevisionHistoryLimit: ptr.To[int32](10),
+		},
+	}
+	clientset := fake.NewClientset(deploy, newReplicaSet(deploy, "test-deployment-old", "1"))
+	finder := New(clientset, logger.NewLogger(&bytes.Buffer{}, logger.LevelInfo))
+
+	old, err := finder.FindOldReplicaSets(context.Background(), common.ControllerRef{
+		Kind: common.KindDeployment, Namespace: "test-ns", Name: "test-deployment",
+	})
+	require.NoError(t, err)
+	require.Empty(t, old)
+}
+
+func newReplicaSet(deploy *appsv1.Deployment, name, revision string) *appsv1.ReplicaSet {
+	return &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       deploy.Namespace,
+			Annotations:     map[string]string{revisionAnnotation: revision},
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(deploy, appsv1.SchemeGroupVersion.WithKind(common.KindDeployment))},
+		},
+		Spec: appsv1.ReplicaSetSpec{
+			Replicas: ptr.To[int32](0),
+		},
+	}
+}
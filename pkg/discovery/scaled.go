@@ -0,0 +1,144 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/common"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// FindScaledDownControllers finds the Deployments, StatefulSets, (standalone) ReplicaSets, and
+// ReplicationControllers that use any of the given PVCs but are already scaled down to 0 replicas, so they have no pods for FindPodsUsingPVCs to find.
+func (f *Finder) FindScaledDownControllers(ctx context.Context, pvcsPerNs map[string][]string) ([]common.ControllerRef, error) {
+	apps := f.clientset.AppsV1()
+	var scaled []common.ControllerRef
+	for ns, pvcs := range pvcsPerNs {
+		deployments, err := apps.Deployments(ns).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list deployments: %w", err)
+		}
+		for _, d := range deployments.Items {
+			if isScaledDown(d.Spec.Replicas) && templateUsesPVCs(d.Spec.Template, pvcs) {
+				scaled = append(scaled, common.ControllerRef{Kind: common.KindDeployment, Namespace: ns, Name: d.Name})
+			}
+		}
+
+		statefulSets, err := apps.StatefulSets(ns).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list statefulsets: %w", err)
+		}
+		for _, sts := range statefulSets.Items {
+			if !isScaledDown(sts.Spec.Replicas) {
+				continue
+			}
+			usesClaimTemplate := slices.ContainsFunc(sts.Spec.VolumeClaimTemplates, func(tpl corev1.PersistentVolumeClaim) bool {
+				// StatefulSets create a PVC named <template>-<statefulset>-<ordinal> for each replica
+				pattern := regexp.MustCompile("^" + regexp.QuoteMeta(tpl.Name+"-"+sts.Name+"-") + "[0-9]+$")
+				return slices.ContainsFunc(pvcs, pattern.MatchString)
+			})
+			if usesClaimTemplate || templateUsesPVCs(sts.Spec.Template, pvcs) {
+				scaled = append(scaled, common.ControllerRef{Kind: common.KindStatefulSet, Namespace: ns, Name: sts.Name})
+			}
+		}
+
+		replicaSets, err := apps.ReplicaSets(ns).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list replicasets: %w", err)
+		}
+		for _, rs := range replicaSets.Items {
+			// ReplicaSets owned by a Deployment are covered by their Deployment
+			if len(rs.OwnerReferences) == 0 && isScaledDown(rs.Spec.Replicas) && templateUsesPVCs(rs.Spec.Template, pvcs) {
+				scaled = append(scaled, common.ControllerRef{Kind: common.KindReplicaSet, Namespace: ns, Name: rs.Name})
+			}
+		}
+
+		rcs, err := f.clientset.CoreV1().ReplicationControllers(ns).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list replicationcontrollers: %w", err)
+		}
+		for _, rc := range rcs.Items {
+			if isScaledDown(rc.Spec.Replicas) && rc.Spec.Template != nil && templateUsesPVCs(*rc.Spec.Template, pvcs) {
+				scaled = append(scaled, common.ControllerRef{Kind: common.KindReplicationController, Namespace: ns, Name: rc.Name})
+			}
+		}
+	}
+	return scaled, nil
+}
+
+// UnmountedController is a controller that was scaled down by kubectl-unmount, as recorded in its annotations.
+type UnmountedController struct {
+	common.ControllerRef
+	OriginalReplicas string
+	ScaledAt         string
+	TriggeringPVC    string
+}
+
+// FindUnmountedControllers finds the Deployments, StatefulSets, (standalone) ReplicaSets, and ReplicationControllers in the
+// given namespace (or all namespaces, if empty) that were scaled down by kubectl-unmount, i.e. that have its
+// original-replicas annotation.
+func (f *Finder) FindUnmountedControllers(ctx context.Context, namespace string) ([]UnmountedController, error) {
+	apps := f.clientset.AppsV1()
+	var unmounted []UnmountedController
+	add := func(kind string, obj metav1.ObjectMeta) {
+		replicas, ok := obj.Annotations[common.AnnotationOriginalReplicas]
+		if !ok {
+			return
+		}
+		unmounted = append(unmounted, UnmountedController{
+			ControllerRef:    common.ControllerRef{Kind: kind, Namespace: obj.Namespace, Name: obj.Name},
+			OriginalReplicas: replicas,
+			ScaledAt:         obj.Annotations[common.AnnotationScaledAt],
+			TriggeringPVC:    obj.Annotations[common.AnnotationPVCTrigger],
+		})
+	}
+
+	deployments, err := apps.Deployments(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deployments: %w", err)
+	}
+	for _, d := range deployments.Items {
+		add(common.KindDeployment, d.ObjectMeta)
+	}
+
+	statefulSets, err := apps.StatefulSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list statefulsets: %w", err)
+	}
+	for _, sts := range statefulSets.Items {
+		add(common.KindStatefulSet, sts.ObjectMeta)
+	}
+
+	replicaSets, err := apps.ReplicaSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list replicasets: %w", err)
+	}
+	for _, rs := range replicaSets.Items {
+		// A Deployment's annotations are copied onto its ReplicaSets, which are covered by the Deployment
+		if metav1.GetControllerOf(&rs) == nil {
+			add(common.KindReplicaSet, rs.ObjectMeta)
+		}
+	}
+
+	rcs, err := f.clientset.CoreV1().ReplicationControllers(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list replicationcontrollers: %w", err)
+	}
+	for _, rc := range rcs.Items {
+		add(common.KindReplicationController, rc.ObjectMeta)
+	}
+	return unmounted, nil
+}
+
+func isScaledDown(replicas *int32) bool {
+	return replicas != nil && *replicas == 0
+}
+
+// templateUsesPVCs checks whether pods created from the template would use any of the given PVCs.
+func templateUsesPVCs(template corev1.PodTemplateSpec, pvcs []string) bool {
+	pod := corev1.Pod{ObjectMeta: template.ObjectMeta, Spec: template.Spec}
+	return slices.ContainsFunc(pvcs, func(pvc string) bool { return usesPVC(pod, pvc) })
+}
@@ -0,0 +1,107 @@
+package discovery
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/common"
+	"github.com/dancavallaro/kubectl-unmount/pkg/logger"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
+)
+
+func TestFindScaledDownControllers(t *testing.T) {
+	template := corev1.PodTemplateSpec{
+		Spec: corev1.PodSpec{
+			Volumes: []corev1.Volume{{
+				Name: "data",
+				VolumeSource: corev1.VolumeSource{
+					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "test-pvc"},
+				},
+			}},
+		},
+	}
+	newDeployment := func(name string, replicas int32) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test-ns"},
+			Spec:       appsv1.DeploymentSpec{Replicas: ptr.To(replicas), Template: template},
+		}
+	}
+	clientset := fake.NewClientset(
+		newDeployment("scaled-deployment", 0),
+		newDeployment("running-deployment", 1),
+		&appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "test-ns"},
+			Spec: appsv1.StatefulSetSpec{
+				Replicas: ptr.To[int32](0),
+				VolumeClaimTemplates: []corev1.PersistentVolumeClaim{{
+					ObjectMeta: metav1.ObjectMeta{Name: "data"},
+				}},
+			},
+		},
+		&corev1.ReplicationController{
+			ObjectMeta: metav1.ObjectMeta{Name: "legacy", Namespace: "test-ns"},
+			Spec:       corev1.ReplicationControllerSpec{Replicas: ptr.To[int32](0), Template: &template},
+		},
+	)
+	finder := New(clientset, logger.NewLogger(&bytes.Buffer{}, logger.LevelInfo))
+
+	scaled, err := finder.FindScaledDownControllers(context.Background(), map[string][]string{
+		"test-ns": {"test-pvc", "data-db-0"},
+	})
+	require.NoError(t, err)
+	require.ElementsMatch(t, []common.ControllerRef{
+		{Kind: common.KindDeployment, Namespace: "test-ns", Name: "scaled-deployment"},
+		{Kind: common.KindStatefulSet, Namespace: "test-ns", Name: "db"},
+		{Kind: common.KindReplicationController, Namespace: "test-ns", Name: "legacy"},
+	}, scaled)
+}
+
+func TestFindUnmountedControllers(t *testing.T) {
+	annotations := map[string]string{
+		common.AnnotationOriginalReplicas: "3",
+		common.AnnotationScaledAt:         "2024-01-15T10:00:00Z",
+		common.AnnotationPVCTrigger:       "data",
+	}
+	clientset := fake.NewClientset(
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "unmounted", Namespace: "test-ns", Annotations: annotations}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "running", Namespace: "test-ns"}},
+		&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "other-ns", Annotations: annotations}},
+		// The Deployment controller copies the Deployment's annotations onto its ReplicaSets
+		&appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+			Name:        "unmounted-5d4f8",
+			Namespace:   "test-ns",
+			Annotations: annotations,
+			OwnerReferences: []metav1.OwnerReference{
+				{APIVersion: "apps/v1", Kind: common.KindDeployment, Name: "unmounted", Controller: ptr.To(true)},
+			},
+		}},
+	)
+	finder := New(clientset, logger.NewLogger(&bytes.Buffer{}, logger.LevelInfo))
+
+	unmounted, err := finder.FindUnmountedControllers(context.Background(), "")
+	require.NoError(t, err)
+	require.ElementsMatch(t, []UnmountedController{
+		{
+			ControllerRef:    common.ControllerRef{Kind: common.KindDeployment, Namespace: "test-ns", Name: "unmounted"},
+			OriginalReplicas: "3",
+			ScaledAt:         "2024-01-15T10:00:00Z",
+			TriggeringPVC:    "data",
+		},
+		{
+			ControllerRef:    common.ControllerRef{Kind: common.KindStatefulSet, Namespace: "other-ns", Name: "db"},
+			OriginalReplicas: "3",
+			ScaledAt:         "2024-01-15T10:00:00Z",
+			TriggeringPVC:    "data",
+		},
+	}, unmounted)
+
+	unmounted, err = finder.FindUnmountedControllers(context.Background(), "test-ns")
+	require.NoError(t, err)
+	require.Len(t, unmounted, 1)
+}
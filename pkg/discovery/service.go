@@ -0,0 +1,33 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// FindServicesForPods finds the Services in the given namespace that select any of the given pods.
+func (f *Finder) FindServicesForPods(ctx context.Context, namespace string, pods []corev1.Pod) ([]corev1.Service, error) {
+	svcList, err := f.clientset.CoreV1().Services(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list services: %w", err)
+	}
+
+	var services []corev1.Service
+	for _, svc := range svcList.Items {
+		if len(svc.Spec.Selector) == 0 {
+			continue
+		}
+		selector := labels.SelectorFromSet(svc.Spec.Selector)
+		for _, pod := range pods {
+			if selector.Matches(labels.Set(pod.Labels)) {
+				services = append(services, svc)
+				break
+			}
+		}
+	}
+	return services, nil
+}
@@ -0,0 +1,112 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/common"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AdmissionWebhook identifies a webhook of a MutatingWebhookConfiguration or ValidatingWebhookConfiguration.
+type AdmissionWebhook struct {
+	// Kind is the kind of webhook configuration that the webhook is part of.
+	Kind          string
+	Configuration string
+	Name          string
+}
+
+func (w AdmissionWebhook) String() string {
+	return fmt.Sprintf("%s/%s (webhook %s)", w.Kind, w.Configuration, w.Name)
+}
+
+// scaleDownRequest describes the API requests made to scale down a controller of some kind.
+type scaleDownRequest struct {
+	group      string
+	resources  []string
+	operations []admissionregistrationv1.OperationType
+}
+
+var scaleDownRequests = map[string]scaleDownRequest{
+	common.KindDeployment:  {"apps", []string{"deployments", "deployments/scale"}, []admissionregistrationv1.OperationType{admissionregistrationv1.Update}},
+	common.KindStatefulSet: {"apps", []string{"statefulsets", "statefulsets/scale"}, []admissionregistrationv1.OperationType{admissionregistrationv1.Update}},
+	common.KindReplicaSet:  {"apps", []string{"replicasets", "replicasets/scale"}, []admissionregistrationv1.OperationType{admissionregistrationv1.Update}},
+	common.KindReplicationController: {"", []string{"replicationcontrollers", "replicationcontrollers/scale"},
+		[]admissionregistrationv1.OperationType{admissionregistrationv1.Update}},
+	common.KindJob:     {"batch", []string{"jobs"}, []admissionregistrationv1.OperationType{admissionregistrationv1.Update}},
+	common.KindCronJob: {"batch", []string{"cronjobs"}, []admissionregistrationv1.OperationType{admissionregistrationv1.Update}},
+	common.KindPod: {"", []string{"pods", "pods/eviction"},
+		[]admissionregistrationv1.OperationType{admissionregistrationv1.Delete, admissionregistrationv1.Create}},
+}
+
+// FindAdmissionWebhooks finds the mutating and validating admission webhooks whose rules intercept the
+// requests made to scale down controllers of the given kinds, so they might reject the scale down.
+func (f *Finder) FindAdmissionWebhooks(ctx context.Context, kinds []string) ([]AdmissionWebhook, error) {
+	var requests []scaleDownRequest
+	for _, kind := range kinds {
+		if req, ok := scaleDownRequests[kind]; ok {
+			requests = append(requests, req)
+		}
+	}
+	intercepts := func(rules []admissionregistrationv1.RuleWithOperations) bool {
+		return slices.ContainsFunc(requests, func(req scaleDownRequest) bool {
+			return slices.ContainsFunc(rules, func(rule admissionregistrationv1.RuleWithOperations) bool {
+				return ruleMatches(rule, req)
+			})
+		})
+	}
+
+	admission := f.clientset.AdmissionregistrationV1()
+	var webhooks []AdmissionWebhook
+	mutating, err := admission.MutatingWebhookConfigurations().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list mutating webhook configurations: %w", err)
+	}
+	for _, cfg := range mutating.Items {
+		for _, webhook := range cfg.Webhooks {
+			if intercepts(webhook.Rules) {
+				webhooks = append(webhooks, AdmissionWebhook{Kind: "MutatingWebhookConfiguration", Configuration: cfg.Name, Name: webhook.Name})
+			}
+		}
+	}
+
+	validating, err := admission.ValidatingWebhookConfigurations().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list validating webhook configurations: %w", err)
+	}
+	for _, cfg := range validating.Items {
+		for _, webhook := range cfg.Webhooks {
+			if intercepts(webhook.Rules) {
+				webhooks = append(webhooks, AdmissionWebhook{Kind: "ValidatingWebhookConfiguration", Configuration: cfg.Name, Name: webhook.Name})
+			}
+		}
+	}
+	return webhooks, nil
+}
+
+// ruleMatches checks whether the webhook rule intercepts the given request. Namespace and object selectors
+// aren't considered, so this may report webhooks that won't actually be called.
+func ruleMatches(rule admissionregistrationv1.RuleWithOperations, req scaleDownRequest) bool {
+	matchesGroup := slices.Contains(rule.APIGroups, "*") || slices.Contains(rule.APIGroups, req.group)
+	matchesOperation := slices.Contains(rule.Operations, admissionregistrationv1.OperationAll) ||
+		slices.ContainsFunc(req.operations, func(op admissionregistrationv1.OperationType) bool {
+			return slices.Contains(rule.Operations, op)
+		})
+	matchesResource := slices.ContainsFunc(req.resources, func(resource string) bool {
+		base, sub, _ := strings.Cut(resource, "/")
+		return slices.ContainsFunc(rule.Resources, func(r string) bool {
+			switch r {
+			case "*/*", resource:
+				return true
+			case "*":
+				return sub == ""
+			default:
+				return sub != "" && (r == base+"/*" || r == "*/"+sub)
+			}
+		})
+	})
+	return matchesGroup && matchesOperation && matchesResource
+}
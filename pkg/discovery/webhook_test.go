@@ -0,0 +1,58 @@
+package discovery
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/common"
+	"github.com/dancavallaro/kubectl-unmount/pkg/logger"
+	"github.com/stretchr/testify/require"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestFindAdmissionWebhooks(t *testing.T) {
+	rule := func(group, resource string, op admissionregistrationv1.OperationType) admissionregistrationv1.RuleWithOperations {
+		return admissionregistrationv1.RuleWithOperations{
+			Operations: []admissionregistrationv1.OperationType{op},
+			Rule: admissionregistrationv1.Rule{
+				APIGroups: []string{group},
+				Resources: []string{resource},
+			},
+		}
+	}
+	clientset := fake.NewClientset(
+		&admissionregistrationv1.ValidatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: "policy"},
+			Webhooks: []admissionregistrationv1.ValidatingWebhook{
+				{Name: "scale.policy.example.com", Rules: []admissionregistrationv1.RuleWithOperations{
+					rule("apps", "deployments/scale", admissionregistrationv1.Update),
+				}},
+				{Name: "create.policy.example.com", Rules: []admissionregistrationv1.RuleWithOperations{
+					rule("apps", "deployments", admissionregistrationv1.Create),
+				}},
+				{Name: "cronjobs.policy.example.com", Rules: []admissionregistrationv1.RuleWithOperations{
+					rule("batch", "cronjobs", admissionregistrationv1.Update),
+				}},
+			},
+		},
+		&admissionregistrationv1.MutatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: "mesh"},
+			Webhooks: []admissionregistrationv1.MutatingWebhook{
+				{Name: "all.mesh.example.com", Rules: []admissionregistrationv1.RuleWithOperations{
+					rule("*", "*/*", admissionregistrationv1.OperationAll),
+				}},
+			},
+		},
+	)
+	finder := New(clientset, logger.NewLogger(&bytes.Buffer{}, logger.LevelInfo))
+
+	webhooks, err := finder.FindAdmissionWebhooks(context.Background(), []string{common.KindDeployment})
+	require.NoError(t, err)
+	require.Equal(t, []AdmissionWebhook{
+		{Kind: "MutatingWebhookConfiguration", Configuration: "mesh", Name: "all.mesh.example.com"},
+		{Kind: "ValidatingWebhookConfiguration", Configuration: "policy", Name: "scale.policy.example.com"},
+	}, webhooks)
+}
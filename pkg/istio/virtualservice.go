@@ -0,0 +1,168 @@
+package istio
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/logger"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+)
+
+var virtualServiceGVR = schema.GroupVersionResource{
+	Group:    "networking.istio.io",
+	Version:  "v1",
+	Resource: "virtualservices",
+}
+
+// Patch is a JSON patch for a VirtualService, which redirects traffic away from a set of destinations.
+type Patch struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Ops       []Op   `json:"patch"`
+}
+
+// Op is a single JSON patch operation.
+type Op struct {
+	Op    string `json:"op"`
+	Path  string `json:"path"`
+	Value int64  `json:"value"`
+}
+
+// Client generates and applies patches to Istio VirtualServices.
+type Client struct {
+	dynamic   dynamic.Interface
+	discovery discovery.DiscoveryInterface
+	log       *logger.Logger
+}
+
+// New creates a new Client instance.
+func New(dynamic dynamic.Interface, discovery discovery.DiscoveryInterface, log *logger.Logger) Client {
+	return Client{
+		dynamic:   dynamic,
+		discovery: discovery,
+		log:       log,
+	}
+}
+
+// Installed checks whether the Istio VirtualService CRD is installed in the cluster.
+func (c Client) Installed() (bool, error) {
+	_, err := c.discovery.ServerResourcesForGroupVersion(virtualServiceGVR.GroupVersion().String())
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to discover Istio resources: %w", err)
+	}
+	return true, nil
+}
+
+// ServiceHosts returns the hostnames that a VirtualService may use to route traffic to the given Service.
+func ServiceHosts(svc corev1.Service) []string {
+	return []string{
+		svc.Name,
+		fmt.Sprintf("%s.%s", svc.Name, svc.Namespace),
+		fmt.Sprintf("%s.%s.svc", svc.Name, svc.Namespace),
+		fmt.Sprintf("%s.%s.svc.cluster.local", svc.Name, svc.Namespace),
+	}
+}
+
+// GeneratePatches generates patches for the VirtualServices in the given namespace that route HTTP traffic to
+// any of the given hosts. Each patch sets the weight of those destinations to 0, and spreads the traffic evenly
+// across the remaining destinations of the route. Routes with no other destinations are skipped with a warning,
+// since there's nowhere to redirect their traffic to.
+func (c Client) GeneratePatches(ctx context.Context, namespace string, hosts []string) ([]Patch, error) {
+	vsList, err := c.dynamic.Resource(virtualServiceGVR).Namespace(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list virtual services: %w", err)
+	}
+
+	var patches []Patch
+	for _, vs := range vsList.Items {
+		ops, err := c.generateOps(vs, hosts)
+		if err != nil {
+			return nil, err
+		}
+		if len(ops) > 0 {
+			patches = append(patches, Patch{Namespace: vs.GetNamespace(), Name: vs.GetName(), Ops: ops})
+		}
+	}
+	return patches, nil
+}
+
+func (c Client) generateOps(vs unstructured.Unstructured, hosts []string) ([]Op, error) {
+	httpRoutes, _, err := unstructured.NestedSlice(vs.Object, "spec", "http")
+	if err != nil {
+		return nil, fmt.Errorf("invalid virtual service %s/%s: %w", vs.GetNamespace(), vs.GetName(), err)
+	}
+
+	var ops []Op
+	for i, httpRoute := range httpRoutes {
+		routeMap, ok := httpRoute.(map[string]any)
+		if !ok {
+			continue
+		}
+		destinations, _, err := unstructured.NestedSlice(routeMap, "route")
+		if err != nil {
+			return nil, fmt.Errorf("invalid virtual service %s/%s: %w", vs.GetNamespace(), vs.GetName(), err)
+		}
+
+		var targeted, remaining []int
+		for j, dest := range destinations {
+			destMap, ok := dest.(map[string]any)
+			if !ok {
+				continue
+			}
+			host, _, _ := unstructured.NestedString(destMap, "destination", "host")
+			if slices.Contains(hosts, host) {
+				targeted = append(targeted, j)
+			} else {
+				remaining = append(remaining, j)
+			}
+		}
+		if len(targeted) == 0 {
+			continue
+		}
+		if len(remaining) == 0 {
+			c.log.Warn("VirtualService %s/%s has an HTTP route with no other destinations, can't redirect its traffic",
+				vs.GetNamespace(), vs.GetName())
+			continue
+		}
+
+		// Weights are optional, so use "add" which also replaces existing values
+		for _, j := range targeted {
+			ops = append(ops, Op{Op: "add", Path: fmt.Sprintf("/spec/http/%d/route/%d/weight", i, j), Value: 0})
+		}
+		for k, j := range remaining {
+			weight := int64(100 / len(remaining))
+			if k == 0 {
+				weight += int64(100 % len(remaining))
+			}
+			ops = append(ops, Op{Op: "add", Path: fmt.Sprintf("/spec/http/%d/route/%d/weight", i, j), Value: weight})
+		}
+	}
+	return ops, nil
+}
+
+// Apply applies the given patch to its VirtualService.
+func (c Client) Apply(ctx context.Context, patch Patch) error {
+	data, err := json.Marshal(patch.Ops)
+	if err != nil {
+		return fmt.Errorf("failed to encode patch: %w", err)
+	}
+	_, err = c.dynamic.Resource(virtualServiceGVR).Namespace(patch.Namespace).
+		Patch(ctx, patch.Name, types.JSONPatchType, data, metav1.PatchOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to patch virtual service %s/%s: %w", patch.Namespace, patch.Name, err)
+	}
+	c.log.Info("  Redirected traffic in VirtualService %s/%s", patch.Namespace, patch.Name)
+	return nil
+}
@@ -0,0 +1,54 @@
+package istio
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/logger"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestGeneratePatches(t *testing.T) {
+	vs := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "networking.istio.io/v1",
+		"kind":       "VirtualService",
+		"metadata":   map[string]any{"name": "test-vs", "namespace": "test-ns"},
+		"spec": map[string]any{
+			"http": []any{
+				map[string]any{
+					"route": []any{
+						map[string]any{"destination": map[string]any{"host": "old"}, "weight": int64(50)},
+						map[string]any{"destination": map[string]any{"host": "new"}, "weight": int64(50)},
+					},
+				},
+				map[string]any{
+					"route": []any{
+						map[string]any{"destination": map[string]any{"host": "old"}},
+					},
+				},
+			},
+		},
+	}}
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{virtualServiceGVR: "VirtualServiceList"}, vs)
+	var logs bytes.Buffer
+	client := New(dynamicClient, kubefake.NewClientset().Discovery(), logger.NewLogger(&logs, logger.LevelInfo))
+
+	patches, err := client.GeneratePatches(context.Background(), "test-ns", []string{"old"})
+	require.NoError(t, err)
+	require.Equal(t, []Patch{{
+		Namespace: "test-ns",
+		Name:      "test-vs",
+		Ops: []Op{
+			{Op: "add", Path: "/spec/http/0/route/0/weight", Value: 0},
+			{Op: "add", Path: "/spec/http/0/route/1/weight", Value: 100},
+		},
+	}}, patches)
+	require.Contains(t, logs.String(), "has an HTTP route with no other destinations")
+}
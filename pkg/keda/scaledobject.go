@@ -0,0 +1,120 @@
+package keda
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/common"
+	"github.com/dancavallaro/kubectl-unmount/pkg/logger"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+)
+
+var scaledObjectGVR = schema.GroupVersionResource{
+	Group:    "keda.sh",
+	Version:  "v1alpha1",
+	Resource: "scaledobjects",
+}
+
+// AnnotationPaused is the annotation that KEDA checks to stop autoscaling a ScaledObject's target, leaving its
+// replicas as they are.
+const AnnotationPaused = "autoscaling.keda.sh/paused"
+
+// Client pauses and resumes KEDA ScaledObjects, so that KEDA doesn't scale the controllers they target back up.
+type Client struct {
+	dynamic   dynamic.Interface
+	discovery discovery.DiscoveryInterface
+	log       *logger.Logger
+}
+
+// New creates a new Client instance.
+func New(dynamic dynamic.Interface, discovery discovery.DiscoveryInterface, log *logger.Logger) Client {
+	return Client{
+		dynamic:   dynamic,
+		discovery: discovery,
+		log:       log,
+	}
+}
+
+// Installed checks whether the KEDA ScaledObject CRD is installed in the cluster.
+func (c Client) Installed() (bool, error) {
+	_, err := c.discovery.ServerResourcesForGroupVersion(scaledObjectGVR.GroupVersion().String())
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to discover KEDA resources: %w", err)
+	}
+	return true, nil
+}
+
+// FindScaledObject finds the ScaledObject targeting the given controller, or nil if there isn't one.
+func (c Client) FindScaledObject(ctx context.Context, ctrl common.ControllerRef) (*unstructured.Unstructured, error) {
+	soList, err := c.dynamic.Resource(scaledObjectGVR).Namespace(ctrl.Namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list scaled objects: %w", err)
+	}
+	for _, so := range soList.Items {
+		name, _, _ := unstructured.NestedString(so.Object, "spec", "scaleTargetRef", "name")
+		kind, _, _ := unstructured.NestedString(so.Object, "spec", "scaleTargetRef", "kind")
+		if kind == "" {
+			// KEDA targets Deployments unless told otherwise
+			kind = common.KindDeployment
+		}
+		if kind == ctrl.Kind && name == ctrl.Name {
+			return &so, nil
+		}
+	}
+	return nil, nil
+}
+
+// Pause pauses the given ScaledObject, recording that kubectl-unmount did so in an annotation. Returns false if
+// the ScaledObject was already paused, in which case it's left untouched (and isn't resumed by Resume).
+func (c Client) Pause(ctx context.Context, so *unstructured.Unstructured) (bool, error) {
+	if so.GetAnnotations()[AnnotationPaused] == "true" {
+		c.log.Info("  ScaledObject %s/%s is already paused, leaving it as is", so.GetNamespace(), so.GetName())
+		return false, nil
+	}
+	err := c.patchAnnotations(ctx, so, map[string]any{
+		AnnotationPaused:          "true",
+		common.AnnotationPausedBy: "kubectl-unmount",
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to pause ScaledObject %s/%s: %w", so.GetNamespace(), so.GetName(), err)
+	}
+	c.log.Info("  Paused ScaledObject %s/%s", so.GetNamespace(), so.GetName())
+	return true, nil
+}
+
+// Resume reverses Pause: if the given ScaledObject was paused by kubectl-unmount, both annotations are removed so
+// that KEDA resumes autoscaling its target. Returns false if the ScaledObject wasn't paused by kubectl-unmount.
+func (c Client) Resume(ctx context.Context, so *unstructured.Unstructured) (bool, error) {
+	if _, ok := so.GetAnnotations()[common.AnnotationPausedBy]; !ok {
+		return false, nil
+	}
+	err := c.patchAnnotations(ctx, so, map[string]any{
+		AnnotationPaused:          nil,
+		common.AnnotationPausedBy: nil,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to resume ScaledObject %s/%s: %w", so.GetNamespace(), so.GetName(), err)
+	}
+	c.log.Info("  Resumed ScaledObject %s/%s", so.GetNamespace(), so.GetName())
+	return true, nil
+}
+
+func (c Client) patchAnnotations(ctx context.Context, so *unstructured.Unstructured, annotations map[string]any) error {
+	data, err := json.Marshal(map[string]any{"metadata": map[string]any{"annotations": annotations}})
+	if err != nil {
+		return fmt.Errorf("failed to encode patch: %w", err)
+	}
+	_, err = c.dynamic.Resource(scaledObjectGVR).Namespace(so.GetNamespace()).
+		Patch(ctx, so.GetName(), types.MergePatchType, data, metav1.PatchOptions{})
+	return err
+}
@@ -0,0 +1,80 @@
+package keda
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/common"
+	"github.com/dancavallaro/kubectl-unmount/pkg/logger"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+func scaledObject(name string, target map[string]any, annotations map[string]any) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "keda.sh/v1alpha1",
+		"kind":       "ScaledObject",
+		"metadata":   map[string]any{"name": name, "namespace": "test-ns", "annotations": annotations},
+		"spec":       map[string]any{"scaleTargetRef": target},
+	}}
+}
+
+func TestPauseAndResume(t *testing.T) {
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{scaledObjectGVR: "ScaledObjectList"},
+		scaledObject("web", map[string]any{"name": "web"}, nil),
+		scaledObject("db", map[string]any{"kind": common.KindStatefulSet, "name": "db"},
+			map[string]any{AnnotationPaused: "true"}),
+	)
+	var logs bytes.Buffer
+	client := New(dynamicClient, kubefake.NewClientset().Discovery(), logger.NewLogger(&logs, logger.LevelInfo))
+	ctx := context.Background()
+
+	// an unset kind defaults to Deployment
+	so, err := client.FindScaledObject(ctx, common.ControllerRef{Kind: common.KindStatefulSet, Namespace: "test-ns", Name: "web"})
+	require.NoError(t, err)
+	require.Nil(t, so)
+	so, err = client.FindScaledObject(ctx, common.ControllerRef{Kind: common.KindDeployment, Namespace: "test-ns", Name: "web"})
+	require.NoError(t, err)
+	require.Equal(t, "web", so.GetName())
+
+	paused, err := client.Pause(ctx, so)
+	require.NoError(t, err)
+	require.True(t, paused)
+	so, err = dynamicClient.Resource(scaledObjectGVR).Namespace("test-ns").Get(ctx, "web", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Equal(t, "true", so.GetAnnotations()[AnnotationPaused])
+
+	resumed, err := client.Resume(ctx, so)
+	require.NoError(t, err)
+	require.True(t, resumed)
+	so, err = dynamicClient.Resource(scaledObjectGVR).Namespace("test-ns").Get(ctx, "web", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.NotContains(t, so.GetAnnotations(), AnnotationPaused)
+	require.NotContains(t, so.GetAnnotations(), common.AnnotationPausedBy)
+
+	// a ScaledObject that was already paused is left alone, and isn't resumed
+	db, err := client.FindScaledObject(ctx, common.ControllerRef{Kind: common.KindStatefulSet, Namespace: "test-ns", Name: "db"})
+	require.NoError(t, err)
+	paused, err = client.Pause(ctx, db)
+	require.NoError(t, err)
+	require.False(t, paused)
+	resumed, err = client.Resume(ctx, db)
+	require.NoError(t, err)
+	require.False(t, resumed)
+	require.Contains(t, logs.String(), "ScaledObject test-ns/db is already paused")
+}
+
+func TestInstalled(t *testing.T) {
+	client := New(dynamicfake.NewSimpleDynamicClient(runtime.NewScheme()), kubefake.NewClientset().Discovery(),
+		logger.NewLogger(&bytes.Buffer{}, logger.LevelInfo))
+	installed, err := client.Installed()
+	require.NoError(t, err)
+	require.False(t, installed)
+}
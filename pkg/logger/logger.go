@@ -1,44 +1,135 @@
 package logger
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/fatih/color"
 )
 
+// Level is the severity of a log message.
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var levelNames = map[Level]string{
+	LevelDebug: "debug",
+	LevelInfo:  "info",
+	LevelWarn:  "warn",
+	LevelError: "error",
+}
+
+func (l Level) String() string {
+	return levelNames[l]
+}
+
+// ParseLevel parses a level name (debug, info, warn, or error).
+func ParseLevel(s string) (Level, error) {
+	for level, name := range levelNames {
+		if strings.EqualFold(s, name) {
+			return level, nil
+		}
+	}
+	return LevelInfo, fmt.Errorf("invalid log level %q, must be one of debug, info, warn, error", s)
+}
+
 type Logger struct {
-	w io.Writer
+	mu        sync.Mutex
+	w         io.Writer
+	level     Level
+	json      bool
+	verbosity int
+}
+
+// NewLogger creates a Logger that writes plain-text messages at or above the given level.
+func NewLogger(w io.Writer, level Level) *Logger {
+	return &Logger{
+		w:     w,
+		level: level,
+	}
 }
 
-func NewLogger(w io.Writer) *Logger {
+// NewJSONLogger creates a Logger that writes messages at or above the given level as JSON objects,
+// one per line, with level, msg, and time fields.
+func NewJSONLogger(w io.Writer, level Level) *Logger {
 	return &Logger{
-		w: w,
+		w:     w,
+		level: level,
+		json:  true,
 	}
 }
 
+// SetVerbosity sets the verbosity threshold for messages logged with Verbose (0 by default).
+func (l *Logger) SetVerbosity(verbosity int) {
+	l.verbosity = verbosity
+}
+
+// IsVerbose checks whether messages logged with Verbose at the given verbosity would be shown.
+func (l *Logger) IsVerbose(verbosity int) bool {
+	return verbosity <= l.verbosity
+}
+
+// Verbose logs a message that's only shown if the logger's verbosity is at least the given verbosity,
+// regardless of the logger's level.
+func (l *Logger) Verbose(verbosity int, msg string, args ...any) {
+	if !l.IsVerbose(verbosity) {
+		return
+	}
+	l.write(LevelDebug, color.FgHiBlack, msg+"\n", args...)
+}
+
+func (l *Logger) Debug(msg string, args ...any) {
+	l.log(LevelDebug, color.FgHiBlack, msg+"\n", args...)
+}
+
 func (l *Logger) Info(msg string, args ...any) {
-	l.log(color.FgHiCyan, msg+"\n", args...)
+	l.log(LevelInfo, color.FgHiCyan, msg+"\n", args...)
 }
 
 func (l *Logger) Warn(msg string, args ...any) {
-	l.log(color.FgHiYellow, msg+"\n", args...)
+	l.log(LevelWarn, color.FgHiYellow, msg+"\n", args...)
 }
 
 func (l *Logger) Error(err error) {
-	l.log(color.FgHiRed, "%v\n", err)
+	l.log(LevelError, color.FgHiRed, "%v\n", err)
 }
 
+// Instructions prompts the user for input. Prompts are always shown as plain text, regardless of
+// the level and format.
 func (l *Logger) Instructions(msg string, args ...any) {
-	l.log(color.FgHiWhite, "\n"+msg, args...)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	c := color.New(color.FgHiWhite)
+	_, _ = c.Fprint(l.w, fmt.Sprintf("\n"+msg, args...))
 }
 
-func (l *Logger) log(col color.Attribute, msg string, args ...any) {
-	if msg == "" {
-		fmt.Println("")
+func (l *Logger) log(level Level, col color.Attribute, msg string, args ...any) {
+	if level < l.level {
 		return
 	}
+	l.write(level, col, msg, args...)
+}
 
+func (l *Logger) write(level Level, col color.Attribute, msg string, args ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.json {
+		_ = json.NewEncoder(l.w).Encode(map[string]string{
+			"level": level.String(),
+			"msg":   strings.TrimSuffix(fmt.Sprintf(msg, args...), "\n"),
+			"time":  time.Now().UTC().Format(time.RFC3339Nano),
+		})
+		return
+	}
 	c := color.New(col)
 	_, _ = c.Fprint(l.w, fmt.Sprintf(msg, args...))
 }
@@ -0,0 +1,60 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLevelThreshold(t *testing.T) {
+	var buf bytes.Buffer
+	log := NewLogger(&buf, LevelWarn)
+
+	log.Debug("debug message")
+	log.Info("info message")
+	log.Warn("warn message")
+	log.Error(errors.New("error message"))
+
+	require.NotContains(t, buf.String(), "debug message")
+	require.NotContains(t, buf.String(), "info message")
+	require.Contains(t, buf.String(), "warn message\n")
+	require.Contains(t, buf.String(), "error message\n")
+}
+
+func TestVerbosity(t *testing.T) {
+	var buf bytes.Buffer
+	log := NewLogger(&buf, LevelError)
+	log.SetVerbosity(1)
+
+	log.Verbose(1, "verbose message")
+	log.Verbose(2, "debug message")
+
+	require.Contains(t, buf.String(), "verbose message\n")
+	require.NotContains(t, buf.String(), "debug message")
+}
+
+func TestJSONLogger(t *testing.T) {
+	var buf bytes.Buffer
+	log := NewJSONLogger(&buf, LevelInfo)
+
+	log.Debug("hidden")
+	log.Info("found %d pods", 3)
+
+	var line map[string]string
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	require.Equal(t, "info", line["level"])
+	require.Equal(t, "found 3 pods", line["msg"])
+	require.NotEmpty(t, line["time"])
+}
+
+func TestParseLevel(t *testing.T) {
+	level, err := ParseLevel("WARN")
+	require.NoError(t, err)
+	require.Equal(t, LevelWarn, level)
+
+	_, err = ParseLevel("verbose")
+	require.Error(t, err)
+}
@@ -0,0 +1,55 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/mattn/go-isatty"
+)
+
+// ProgressWriter reports the progress of a long-running operation made of several steps, e.g.
+// "[2/5] Waiting for Deployment/ns/name to reach 0 replicas... (12s elapsed)".
+type ProgressWriter interface {
+	// Report shows that the given step (out of total) is in progress, and how long the operation has taken so far.
+	Report(step, total int, msg string, elapsed time.Duration)
+	// Done ends the progress report, once the operation is complete.
+	Done()
+}
+
+// NewProgressWriter creates a ProgressWriter that updates a single line in place if w is a terminal. Otherwise,
+// it writes a line whenever a new step starts (rather than on every report, to not flood logs with them).
+func NewProgressWriter(w io.Writer) ProgressWriter {
+	f, ok := w.(*os.File)
+	return &progressWriter{w: w, tty: ok && isatty.IsTerminal(f.Fd())}
+}
+
+type progressWriter struct {
+	mu   sync.Mutex
+	w    io.Writer
+	tty  bool
+	last string
+}
+
+func (p *progressWriter) Report(step, total int, msg string, elapsed time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	line := fmt.Sprintf("[%d/%d] %s...", step, total, msg)
+	if p.tty {
+		_, _ = fmt.Fprintf(p.w, "\r\033[K%s (%v elapsed)", line, elapsed.Round(time.Second))
+	} else if line != p.last {
+		_, _ = fmt.Fprintf(p.w, "%s (%v elapsed)\n", line, elapsed.Round(time.Second))
+	}
+	p.last = line
+}
+
+func (p *progressWriter) Done() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.tty && p.last != "" {
+		_, _ = fmt.Fprint(p.w, "\r\033[K")
+	}
+	p.last = ""
+}
@@ -0,0 +1,22 @@
+package logger
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProgressWriterWithoutTTY(t *testing.T) {
+	var buf bytes.Buffer
+	progress := NewProgressWriter(&buf)
+
+	progress.Report(1, 2, "Waiting for Deployment/ns/web to reach 0 replicas", 0)
+	progress.Report(1, 2, "Waiting for Deployment/ns/web to reach 0 replicas", 2*time.Second)
+	progress.Report(2, 2, "Waiting for StatefulSet/ns/db to reach 0 replicas", 12400*time.Millisecond)
+	progress.Done()
+
+	require.Equal(t, "[1/2] Waiting for Deployment/ns/web to reach 0 replicas... (0s elapsed)\n"+
+		"[2/2] Waiting for StatefulSet/ns/db to reach 0 replicas... (12s elapsed)\n", buf.String())
+}
@@ -0,0 +1,38 @@
+package metrics
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Gauge is a gauge metric in the DogStatsD format.
+type Gauge struct {
+	Name  string
+	Value int
+	Tags  []string
+}
+
+func (g Gauge) String() string {
+	metric := fmt.Sprintf("%s:%d|g", g.Name, g.Value)
+	if len(g.Tags) > 0 {
+		metric += "|#" + strings.Join(g.Tags, ",")
+	}
+	return metric
+}
+
+// Push sends the given gauges to the DogStatsD server (typically the Datadog Agent) at the given address.
+func Push(address string, gauges ...Gauge) error {
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		return fmt.Errorf("failed to connect to statsd at %s: %w", address, err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	for _, gauge := range gauges {
+		if _, err := fmt.Fprint(conn, gauge.String()); err != nil {
+			return fmt.Errorf("failed to send metric %s: %w", gauge.Name, err)
+		}
+	}
+	return nil
+}
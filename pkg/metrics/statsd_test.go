@@ -0,0 +1,30 @@
+package metrics
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPush(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+
+	err = Push(conn.LocalAddr().String(),
+		Gauge{Name: "kubectl_unmount.controllers_scaled", Value: 3, Tags: []string{"namespace:prod", "dry_run:false"}},
+		Gauge{Name: "kubectl_unmount.pvcs_freed", Value: 2},
+	)
+	require.NoError(t, err)
+
+	buf := make([]byte, 1024)
+	for _, expected := range []string{
+		"kubectl_unmount.controllers_scaled:3|g|#namespace:prod,dry_run:false",
+		"kubectl_unmount.pvcs_freed:2|g",
+	} {
+		n, _, err := conn.ReadFrom(buf)
+		require.NoError(t, err)
+		require.Equal(t, expected, string(buf[:n]))
+	}
+}
@@ -0,0 +1,40 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/discovery"
+	"github.com/dancavallaro/kubectl-unmount/pkg/logger"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestWarnSinglePodPVCs(t *testing.T) {
+	clientset := fake.NewClientset(
+		&corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: "single-pod", Namespace: "test-ns"},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOncePod},
+			},
+		},
+		&corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: "single-node", Namespace: "test-ns"},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			},
+		},
+	)
+	var logs bytes.Buffer
+	log := logger.NewLogger(&logs, logger.LevelInfo)
+
+	err := warnSinglePodPVCs(context.Background(), log, discovery.New(clientset, log),
+		map[string][]string{"test-ns": {"single-pod", "single-node"}})
+	require.NoError(t, err)
+	require.Contains(t, logs.String(), "PVC test-ns/single-pod is ReadWriteOncePod, so only one pod can use it: "+
+		"scaling down its consumer frees it, but it can't be re-attached while the old pod's VolumeAttachment still exists")
+	require.NotContains(t, logs.String(), "single-node")
+}
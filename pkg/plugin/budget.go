@@ -0,0 +1,139 @@
+package plugin
+
+import (
+	"context"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/common"
+	"github.com/dancavallaro/kubectl-unmount/pkg/discovery"
+	"github.com/dancavallaro/kubectl-unmount/pkg/logger"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// disruptionBudget limits how many pods can be terminating at any given time (with --max-disruption-budget),
+// across all the controllers being scaled down. The pods that controllers keep with --replicas don't count, since
+// they never terminate. A nil *disruptionBudget doesn't limit anything.
+type disruptionBudget struct {
+	max       int
+	replicas  int
+	finder    discovery.Finder
+	log       *logger.Logger
+	pvcsPerNs map[string][]string
+	podFilter discovery.PodFilter
+	interval  time.Duration
+
+	mu          sync.Mutex
+	terminating map[types.UID]common.ControllerRef
+}
+
+func newDisruptionBudget(cfg *ConfigFlags, finder discovery.Finder, pvcsPerNs map[string][]string,
+	podFilter discovery.PodFilter) *disruptionBudget {
+	if *cfg.MaxDisruptionBudget == 0 || *cfg.DryRun {
+		return nil
+	}
+	return &disruptionBudget{
+		max:         *cfg.MaxDisruptionBudget,
+		replicas:    *cfg.Replicas,
+		finder:      finder,
+		log:         cfg.logger,
+		pvcsPerNs:   pvcsPerNs,
+		podFilter:   podFilter,
+		interval:    pollInterval,
+		terminating: make(map[types.UID]common.ControllerRef),
+	}
+}
+
+// acquire waits until the given pods of the controller can start terminating without exceeding the budget,
+// and then reserves room for them, counting them as terminating until they're gone (or release is called). A
+// controller with more pods than the whole budget is allowed once nothing else is terminating, since it could
+// never fit otherwise. The budget isn't locked while waiting, so that other controllers can release theirs.
+func (b *disruptionBudget) acquire(ctx context.Context, ctrl common.ControllerRef, pods []corev1.Pod) error {
+	if b == nil {
+		return nil
+	}
+	logged := false
+	for {
+		b.mu.Lock()
+		inFlight := b.inFlight()
+		if inFlight == 0 || inFlight+max(len(pods)-b.kept(ctrl), 0) <= b.max {
+			for _, pod := range pods {
+				b.terminating[pod.UID] = ctrl
+			}
+			b.mu.Unlock()
+			return nil
+		}
+		b.mu.Unlock()
+
+		if !logged {
+			b.log.Info("  Waiting for %d terminating pod(s) before scaling down %v (--max-disruption-budget=%d)",
+				inFlight, ctrl, b.max)
+			logged = true
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(b.interval):
+		}
+		if err := b.refresh(ctx); err != nil {
+			return err
+		}
+	}
+}
+
+// release stops counting the pods of the given controller, whose scale down failed or didn't change anything, so
+// they won't terminate.
+func (b *disruptionBudget) release(ctrl common.ControllerRef) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	maps.DeleteFunc(b.terminating, func(_ types.UID, owner common.ControllerRef) bool {
+		return owner == ctrl
+	})
+}
+
+// kept returns how many of its pods the controller keeps when scaled down: --replicas, except for standalone pods,
+// Jobs and CronJobs, which can't keep any.
+func (b *disruptionBudget) kept(ctrl common.ControllerRef) int {
+	switch ctrl.Kind {
+	case common.KindPod, common.KindJob, common.KindCronJob:
+		return 0
+	}
+	return b.replicas
+}
+
+// inFlight returns how many of the counted pods are still to terminate, i.e. the pods each controller has beyond
+// the ones it keeps.
+func (b *disruptionBudget) inFlight() int {
+	left := make(map[common.ControllerRef]int)
+	for _, ctrl := range b.terminating {
+		left[ctrl]++
+	}
+	n := 0
+	for ctrl, count := range left {
+		n += max(count-b.kept(ctrl), 0)
+	}
+	return n
+}
+
+// refresh stops counting the pods that have terminated.
+func (b *disruptionBudget) refresh(ctx context.Context) error {
+	pods, err := b.finder.FindPodsUsingPVCs(ctx, b.pvcsPerNs, b.podFilter)
+	if err != nil {
+		return err
+	}
+	existing := make(map[types.UID]bool)
+	for _, pod := range pods {
+		existing[pod.UID] = true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	maps.DeleteFunc(b.terminating, func(uid types.UID, _ common.ControllerRef) bool {
+		return !existing[uid]
+	})
+	return nil
+}
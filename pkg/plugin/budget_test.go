@@ -0,0 +1,143 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/common"
+	"github.com/dancavallaro/kubectl-unmount/pkg/discovery"
+	"github.com/dancavallaro/kubectl-unmount/pkg/logger"
+	"github.com/dancavallaro/kubectl-unmount/pkg/scaling"
+	"github.com/stretchr/testify/require"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newBudgetPods(n int) []corev1.Pod {
+	var pods []corev1.Pod
+	for i := range n {
+		pods = append(pods, corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("pod-%d", i),
+				Namespace: "test-ns",
+				UID:       types.UID(fmt.Sprintf("uid-%d", i)),
+			},
+			Spec: corev1.PodSpec{
+				Volumes: []corev1.Volume{{
+					Name: "data",
+					VolumeSource: corev1.VolumeSource{
+						PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "test-pvc"},
+					},
+				}},
+			},
+			Status: corev1.PodStatus{Phase: corev1.PodRunning},
+		})
+	}
+	return pods
+}
+
+func TestDisruptionBudget(t *testing.T) {
+	pods := newBudgetPods(3)
+	clientset := fake.NewClientset(&pods[0], &pods[1], &pods[2])
+	var logs bytes.Buffer
+	log := logger.NewLogger(&logs, logger.LevelInfo)
+	budget := newDisruptionBudget(&ConfigFlags{
+		MaxDisruptionBudget: common.IntP(2),
+		Replicas:            common.IntP(0),
+		DryRun:              common.BoolP(false),
+		logger:              log,
+	}, discovery.New(clientset, log), map[string][]string{"test-ns": {"test-pvc"}}, discovery.PodFilter{})
+	budget.interval = time.Millisecond
+
+	ctx := context.Background()
+	first := common.ControllerRef{Kind: common.KindDeployment, Namespace: "test-ns", Name: "first"}
+	second := common.ControllerRef{Kind: common.KindDeployment, Namespace: "test-ns", Name: "second"}
+	require.NoError(t, budget.acquire(ctx, first, pods[:2]))
+
+	// The second controller's pod only fits in the budget once one of the first controller's pods is gone
+	acquired := make(chan error)
+	go func() {
+		acquired <- budget.acquire(ctx, second, pods[2:])
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("acquired budget while it was exhausted")
+	case <-time.After(50 * time.Millisecond):
+	}
+	require.NoError(t, clientset.CoreV1().Pods("test-ns").Delete(ctx, "pod-0", metav1.DeleteOptions{}))
+	require.NoError(t, <-acquired)
+	require.Contains(t, logs.String(), "Waiting for 2 terminating pod(s) before scaling down Deployment/test-ns/second")
+
+	// A canceled run stops waiting
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	require.ErrorIs(t, budget.acquire(canceled, second, pods[:1]), context.Canceled)
+}
+
+func TestDisruptionBudgetWithReplicas(t *testing.T) {
+	pods := newBudgetPods(4)
+	clientset := fake.NewClientset(&pods[0], &pods[1], &pods[2], &pods[3])
+	log := logger.NewLogger(&bytes.Buffer{}, logger.LevelInfo)
+	budget := newDisruptionBudget(&ConfigFlags{
+		MaxDisruptionBudget: common.IntP(1),
+		Replicas:            common.IntP(1),
+		DryRun:              common.BoolP(false),
+		logger:              log,
+	}, discovery.New(clientset, log), map[string][]string{"test-ns": {"test-pvc"}}, discovery.PodFilter{})
+	budget.interval = time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	first := common.ControllerRef{Kind: common.KindStatefulSet, Namespace: "test-ns", Name: "first"}
+	second := common.ControllerRef{Kind: common.KindStatefulSet, Namespace: "test-ns", Name: "second"}
+	require.NoError(t, budget.acquire(ctx, first, pods[:2]))
+
+	// Once the first controller is down to the pod it keeps, the second one fits in the budget
+	acquired := make(chan error)
+	go func() {
+		acquired <- budget.acquire(ctx, second, pods[2:])
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("acquired budget while it was exhausted")
+	case <-time.After(50 * time.Millisecond):
+	}
+	require.NoError(t, clientset.CoreV1().Pods("test-ns").Delete(ctx, "pod-1", metav1.DeleteOptions{}))
+	require.NoError(t, <-acquired)
+}
+
+func TestDisruptionBudgetReleasedOnFailure(t *testing.T) {
+	pods := newBudgetPods(3)
+	clientset := fake.NewClientset(&pods[0], &pods[1], &pods[2],
+		&batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "second", Namespace: "test-ns"}})
+	var logs bytes.Buffer
+	cfg := &ConfigFlags{
+		MaxDisruptionBudget: common.IntP(2),
+		Replicas:            common.IntP(0),
+		Concurrency:         common.IntP(1),
+		DryRun:              common.BoolP(false),
+		logger:              logger.NewLogger(&logs, logger.LevelInfo),
+		out:                 &bytes.Buffer{},
+	}
+	pvcsPerNs := map[string][]string{"test-ns": {"test-pvc"}}
+	budget := newDisruptionBudget(cfg, discovery.New(clientset, cfg.logger), pvcsPerNs, discovery.PodFilter{})
+	budget.interval = time.Millisecond
+
+	// The first Job doesn't exist, so scaling it down fails and its pods never terminate
+	first := common.ControllerRef{Kind: common.KindJob, Namespace: "test-ns", Name: "first"}
+	second := common.ControllerRef{Kind: common.KindJob, Namespace: "test-ns", Name: "second"}
+	podsByController := map[common.ControllerRef][]corev1.Pod{first: pods[:2], second: pods[2:]}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	errs := scaleDownAll(ctx, cfg, scaling.New(clientset, cfg.logger, scaling.Options{}), budget,
+		[]common.ControllerRef{first, second}, podsByController, pvcsPerNs, nil)
+	require.Error(t, errs[0])
+	require.NoError(t, errs[1])
+	require.NotContains(t, logs.String(), "Waiting for")
+}
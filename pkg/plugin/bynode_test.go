@@ -0,0 +1,44 @@
+package plugin
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/common"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPrintByNode(t *testing.T) {
+	newPod := func(name, node, pvc string) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test-ns"},
+			Spec: corev1.PodSpec{
+				NodeName: node,
+				Volumes: []corev1.Volume{{
+					Name: "data",
+					VolumeSource: corev1.VolumeSource{
+						PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: pvc},
+					},
+				}},
+			},
+		}
+	}
+	db := common.ControllerRef{Kind: common.KindStatefulSet, Namespace: "test-ns", Name: "db"}
+	cache := common.ControllerRef{Kind: common.KindDeployment, Namespace: "test-ns", Name: "cache"}
+	podsByController := map[common.ControllerRef][]corev1.Pod{
+		db:    {newPod("db-0", "node-1", "data-db-0"), newPod("db-1", "node-2", "data-db-1")},
+		cache: {newPod("cache-abc", "node-1", "cache"), newPod("cache-def", "", "cache")},
+	}
+	pvcsPerNs := map[string][]string{"test-ns": {"data-db-0", "data-db-1", "cache"}}
+
+	var out bytes.Buffer
+	printByNode(&out, (&ConfigFlags{}).qualify, []common.ControllerRef{cache, db}, podsByController, pvcsPerNs,
+		map[common.ControllerRef]bool{cache: true}, nil, nil)
+	require.Equal(t, "Node node-1 would release 1 PVC(s): test-ns/data-db-0\n"+
+		"  Deployment/test-ns/cache (PVC: cache) (excluded)\n"+
+		"  StatefulSet/test-ns/db (PVC: data-db-0)\n"+
+		"Node node-2 would release 1 PVC(s): test-ns/data-db-1\n"+
+		"  StatefulSet/test-ns/db (PVC: data-db-1)\n", out.String())
+}
@@ -0,0 +1,82 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// CompletePVCs returns the names of the PVCs in the current namespace that start with prefix, for shell
+// completion of --pvc.
+func CompletePVCs(ctx context.Context, cfg *ConfigFlags, prefix string) ([]string, error) {
+	clientset, err := completionClientset(cfg)
+	if err != nil {
+		return nil, err
+	}
+	namespace, _, err := cfg.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine namespace: %w", err)
+	}
+	return completePVCs(ctx, clientset, namespace, prefix)
+}
+
+// CompleteStorageClasses returns the names of the StorageClasses that start with prefix, for shell completion
+// of --storage-class.
+func CompleteStorageClasses(ctx context.Context, cfg *ConfigFlags, prefix string) ([]string, error) {
+	clientset, err := completionClientset(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return completeStorageClasses(ctx, clientset, prefix)
+}
+
+// completionClientset creates a clientset the same way as for a run, from the flags parsed so far.
+func completionClientset(cfg *ConfigFlags) (kubernetes.Interface, error) {
+	if err := setDefaults(cfg); err != nil {
+		return nil, err
+	}
+	config, err := restConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create clientset: %w", err)
+	}
+	return clientset, nil
+}
+
+func completePVCs(ctx context.Context, clientset kubernetes.Interface, namespace, prefix string) ([]string, error) {
+	pvcList, err := clientset.CoreV1().PersistentVolumeClaims(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list persistent volume claims: %w", err)
+	}
+	var names []string
+	for _, pvc := range pvcList.Items {
+		names = append(names, pvc.Name)
+	}
+	return withPrefix(names, prefix), nil
+}
+
+func completeStorageClasses(ctx context.Context, clientset kubernetes.Interface, prefix string) ([]string, error) {
+	scList, err := clientset.StorageV1().StorageClasses().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list storage classes: %w", err)
+	}
+	var names []string
+	for _, sc := range scList.Items {
+		names = append(names, sc.Name)
+	}
+	return withPrefix(names, prefix), nil
+}
+
+// withPrefix returns the sorted names that start with prefix.
+func withPrefix(names []string, prefix string) []string {
+	names = slices.DeleteFunc(names, func(name string) bool { return !strings.HasPrefix(name, prefix) })
+	slices.Sort(names)
+	return names
+}
@@ -0,0 +1,41 @@
+package plugin
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestCompletePVCs(t *testing.T) {
+	clientset := fake.NewClientset(
+		&corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Namespace: "test-ns", Name: "data-web-1"}},
+		&corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Namespace: "test-ns", Name: "data-web-0"}},
+		&corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Namespace: "test-ns", Name: "logs"}},
+		&corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Namespace: "other-ns", Name: "data-db-0"}},
+	)
+
+	names, err := completePVCs(context.Background(), clientset, "test-ns", "data-")
+	require.NoError(t, err)
+	require.Equal(t, []string{"data-web-0", "data-web-1"}, names)
+
+	names, err = completePVCs(context.Background(), clientset, "test-ns", "")
+	require.NoError(t, err)
+	require.Equal(t, []string{"data-web-0", "data-web-1", "logs"}, names)
+}
+
+func TestCompleteStorageClasses(t *testing.T) {
+	clientset := fake.NewClientset(
+		&storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "standard"}},
+		&storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "fast-ssd"}},
+		&storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "slow-hdd"}},
+	)
+
+	names, err := completeStorageClasses(context.Background(), clientset, "s")
+	require.NoError(t, err)
+	require.Equal(t, []string{"slow-hdd", "standard"}, names)
+}
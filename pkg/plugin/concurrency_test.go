@@ -0,0 +1,65 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/common"
+	"github.com/dancavallaro/kubectl-unmount/pkg/logger"
+	"github.com/dancavallaro/kubectl-unmount/pkg/scaling"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// BenchmarkScaleDownAll scales down 20 Deployments against an API server that takes a few milliseconds to
+// respond to each request, to show the throughput gained by scaling down several controllers at a time.
+func BenchmarkScaleDownAll(b *testing.B) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(5 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodGet {
+			_ = json.NewEncoder(w).Encode(autoscalingv1.Scale{Spec: autoscalingv1.ScaleSpec{Replicas: 1}})
+		} else {
+			_ = json.NewEncoder(w).Encode(appsv1.Deployment{})
+		}
+	}))
+	defer server.Close()
+	clientset, err := kubernetes.NewForConfig(&rest.Config{Host: server.URL, QPS: -1})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	var controllers []common.ControllerRef
+	for i := range 20 {
+		controllers = append(controllers, common.ControllerRef{
+			Kind: common.KindDeployment, Namespace: "test-ns", Name: fmt.Sprintf("deployment-%d", i),
+		})
+	}
+
+	for _, concurrency := range []int{1, 4, 20} {
+		b.Run(fmt.Sprintf("concurrency=%d", concurrency), func(b *testing.B) {
+			log := logger.NewLogger(&bytes.Buffer{}, logger.LevelInfo)
+			cfg := &ConfigFlags{
+				DryRun:      common.BoolP(false),
+				Concurrency: common.IntP(concurrency),
+				logger:      log,
+			}
+			scaler := scaling.New(clientset, log, scaling.Options{})
+			for b.Loop() {
+				for _, err := range scaleDownAll(context.Background(), cfg, scaler, nil, controllers, nil, nil, nil) {
+					if err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/common"
+	"github.com/dancavallaro/kubectl-unmount/pkg/logger"
+)
+
+// confirmAction prompts the user to confirm an action by typing "y" or "yes".
+// Returns true if the user confirms, false otherwise (including for an empty response).
+func confirmAction(ctx context.Context, log *logger.Logger, reader *bufio.Reader, prompt string, skipConfirmation bool) (bool, error) {
+	if skipConfirmation {
+		return true, nil
+	}
+
+	log.Instructions("%s [y/N]: ", prompt)
+
+	response, err := readResponse(ctx, reader)
+	if errors.Is(err, io.EOF) {
+		// Nobody is there to answer, so don't assume a yes
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return response == "y" || response == "yes", nil
+}
+
+// confirmScaleDown shows everything that's about to be scaled down, tallied by kind, and asks the user to confirm
+// it. Dry runs don't modify anything, so they don't need to be confirmed.
+func confirmScaleDown(ctx context.Context, cfg *ConfigFlags, reader *bufio.Reader, controllers []common.ControllerRef) (bool, error) {
+	if *cfg.DryRun || cfg.Confirmed != nil && *cfg.Confirmed {
+		return true, nil
+	}
+
+	var scaled, deletedPods []common.ControllerRef
+	cfg.logger.Info("About to scale down %d controller(s):", len(controllers))
+	for _, ctrl := range controllers {
+		cfg.logger.Info("  %s", cfg.qualify(ctrl.String()))
+		if ctrl.Kind == common.KindPod {
+			deletedPods = append(deletedPods, ctrl)
+		} else {
+			scaled = append(scaled, ctrl)
+		}
+	}
+	cfg.logger.Info("Total: %s", tally(scaled, deletedPods))
+	return confirmAction(ctx, cfg.logger, reader, "Proceed?", false)
+}
+
+// selectControllers prompts the user to confirm each controller individually, answering
+// y (yes), n (no), a (yes to this and all remaining), or q (quit, skipping all remaining).
+// Returns the controllers that the user chose to scale down, and those that they declined.
+func selectControllers(ctx context.Context, log *logger.Logger, reader *bufio.Reader, controllers []common.ControllerRef) ([]common.ControllerRef, []common.ControllerRef, error) {
+	var selected, declined []common.ControllerRef
+	for i, ctrl := range controllers {
+		log.Instructions("Scale down %v? [y/n/a/q]: ", ctrl)
+
+		response, err := readResponse(ctx, reader)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		switch response {
+		case "y", "yes":
+			selected = append(selected, ctrl)
+		case "n", "no":
+			log.Info("Skipping %v", ctrl)
+			declined = append(declined, ctrl)
+		case "a", "all":
+			return append(selected, controllers[i:]...), declined, nil
+		case "q", "quit":
+			for _, skipped := range controllers[i:] {
+				log.Info("Skipping %v", skipped)
+			}
+			return selected, append(declined, controllers[i:]...), nil
+		default:
+			log.Warn("Unrecognized response %q, skipping %v", response, ctrl)
+			declined = append(declined, ctrl)
+		}
+	}
+	return selected, declined, nil
+}
+
+// readResponse reads a line of user input, giving up if the context is cancelled first (e.g. by Ctrl-C).
+func readResponse(ctx context.Context, reader *bufio.Reader) (string, error) {
+	type line struct {
+		text string
+		err  error
+	}
+	ch := make(chan line, 1)
+	go func() {
+		text, err := reader.ReadString('\n')
+		ch <- line{text, err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case response := <-ch:
+		if response.err != nil {
+			return "", fmt.Errorf("failed to read user input: %w", response.err)
+		}
+		return strings.TrimSpace(strings.ToLower(response.text)), nil
+	}
+}
@@ -0,0 +1,51 @@
+package plugin
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/common"
+	"github.com/dancavallaro/kubectl-unmount/pkg/logger"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfirmScaleDown(t *testing.T) {
+	controllers := []common.ControllerRef{
+		{Kind: common.KindStatefulSet, Namespace: "test-ns", Name: "db"},
+		{Kind: common.KindDeployment, Namespace: "test-ns", Name: "web"},
+		{Kind: common.KindPod, Namespace: "test-ns", Name: "debug"},
+	}
+	confirm := func(input string, dryRun bool) (bool, string) {
+		var logs bytes.Buffer
+		cfg := &ConfigFlags{
+			DryRun:    common.BoolP(dryRun),
+			Confirmed: common.BoolP(false),
+			logger:    logger.NewLogger(&logs, logger.LevelInfo),
+		}
+		confirmed, err := confirmScaleDown(context.Background(), cfg, bufio.NewReader(strings.NewReader(input)), controllers)
+		require.NoError(t, err)
+		return confirmed, logs.String()
+	}
+
+	confirmed, logs := confirm("y\n", false)
+	require.True(t, confirmed)
+	require.Contains(t, logs, "About to scale down 3 controller(s):")
+	require.Contains(t, logs, "  StatefulSet/test-ns/db\n")
+	require.Contains(t, logs, "  Pod/test-ns/debug\n")
+	require.Contains(t, logs, "Total: 1 StatefulSet, 1 Deployment, 1 Pod deleted (3 total)")
+	require.Contains(t, logs, "Proceed? [y/N]: ")
+
+	confirmed, _ = confirm("n\n", false)
+	require.False(t, confirmed)
+	confirmed, _ = confirm("\n", false)
+	require.False(t, confirmed, "an empty response should default to no")
+	confirmed, _ = confirm("", false)
+	require.False(t, confirmed, "EOF should default to no")
+
+	confirmed, logs = confirm("", true)
+	require.True(t, confirmed, "dry runs shouldn't need confirming")
+	require.NotContains(t, logs, "Proceed?")
+}
@@ -0,0 +1,74 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// runContexts runs the plugin in each of the contexts given with --contexts (or all of the kubeconfig's contexts,
+// with --all-contexts), one after the other. An error in one context doesn't stop the others from running: they're
+// all reported at the end. The returned Result combines the outcome in every context, and breaks it down by
+// context in Result.Contexts.
+func runContexts(ctx context.Context, cfg *ConfigFlags) (*Result, error) {
+	names := *cfg.Contexts
+	if cfg.AllContexts != nil && *cfg.AllContexts {
+		rawConfig, err := cfg.ToRawKubeConfigLoader().RawConfig()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read kubeconfig: %w", err)
+		}
+		names = slices.Sorted(maps.Keys(rawConfig.Contexts))
+	}
+
+	// Each context's clients are created from the same flags, so only --context changes between runs
+	original := *cfg.Context
+	defer func() {
+		*cfg.Context = original
+		cfg.contextName = ""
+	}()
+
+	combined := &Result{DryRun: *cfg.DryRun, Contexts: make(map[string]*Result)}
+	var errs []error
+	for _, name := range names {
+		if ctx.Err() != nil {
+			break
+		}
+		cfg.logger.Info("Running in context %s...", name)
+		*cfg.Context = name
+		cfg.contextName = name
+		result, err := runInContext(ctx, cfg)
+		if result != nil {
+			combined.Contexts[name] = result
+			combined.add(result)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("context %s: %w", name, err))
+		}
+	}
+
+	for _, name := range names {
+		if result, ok := combined.Contexts[name]; ok {
+			cfg.logger.Info("Context %s: %s", name, result.summary())
+		}
+	}
+	for _, err := range errs {
+		cfg.logger.Error(err)
+	}
+	if ctx.Err() != nil {
+		return combined, ctx.Err()
+	}
+	if len(errs) > 0 {
+		return combined, fmt.Errorf("encountered errors in %d of %d contexts: %w", len(errs), len(names), errors.Join(errs...))
+	}
+	return combined, nil
+}
+
+// qualify prefixes the given output with the context currently being run in, when running in several.
+func (cfg *ConfigFlags) qualify(s string) string {
+	if cfg.contextName == "" {
+		return s
+	}
+	return cfg.contextName + "/" + s
+}
@@ -0,0 +1,81 @@
+package plugin
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+func TestRunPluginInContexts(t *testing.T) {
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"kind":"PersistentVolumeClaimList","apiVersion":"v1","items":[]}`))
+	}))
+	defer healthy.Close()
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "broken", http.StatusInternalServerError)
+	}))
+	defer broken.Close()
+
+	kubeconfig := filepath.Join(t.TempDir(), "kubeconfig")
+	require.NoError(t, os.WriteFile(kubeconfig, []byte(fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- name: healthy
+  cluster:
+    server: %s
+- name: broken
+  cluster:
+    server: %s
+contexts:
+- name: healthy
+  context:
+    cluster: healthy
+    user: test
+- name: broken
+  context:
+    cluster: broken
+    user: test
+users:
+- name: test
+  user:
+    token: test
+`, healthy.URL, broken.URL)), 0o600))
+
+	run := func(configure func(cfg *ConfigFlags)) (*Result, string, error) {
+		result, _, logs, err := runPlugin(func(cfg *ConfigFlags) {
+			cfg.ConfigFlags = *genericclioptions.NewConfigFlags(false)
+			*cfg.KubeConfig = kubeconfig
+			*cfg.MaxRetries = 0
+			configure(cfg)
+		})
+		return result, logs, err
+	}
+
+	// An error in one context doesn't keep the others from running
+	result, logs, err := run(func(cfg *ConfigFlags) {
+		*cfg.Contexts = []string{"broken", "healthy"}
+	})
+	require.ErrorContains(t, err, "encountered errors in 1 of 2 contexts: context broken: failed to list persistent volume claims")
+	require.Contains(t, logs, "Running in context broken...")
+	require.Contains(t, logs, "Running in context healthy...")
+	require.Contains(t, result.Contexts, "healthy")
+
+	_, logs, err = run(func(cfg *ConfigFlags) {
+		*cfg.AllContexts = true
+	})
+	require.ErrorContains(t, err, "encountered errors in 1 of 2 contexts")
+	require.Contains(t, logs, "Running in context healthy...")
+
+	_, _, err = run(func(cfg *ConfigFlags) {
+		*cfg.Contexts = []string{"healthy"}
+		*cfg.Context = "broken"
+	})
+	require.EqualError(t, err, "--context can't be used together with --contexts or --all-contexts")
+}
@@ -0,0 +1,38 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/common"
+	"github.com/dancavallaro/kubectl-unmount/pkg/discovery"
+	"github.com/dancavallaro/kubectl-unmount/pkg/logger"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
+)
+
+func TestPrintDiffWithReplicas(t *testing.T) {
+	newDeployment := func(name string, replicas int32) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test-ns"},
+			Spec:       appsv1.DeploymentSpec{Replicas: ptr.To(replicas)},
+		}
+	}
+	clientset := fake.NewClientset(newDeployment("web", 3), newDeployment("worker", 1))
+	log := logger.NewLogger(&bytes.Buffer{}, logger.LevelInfo)
+	controllers := []common.ControllerRef{
+		{Kind: common.KindDeployment, Namespace: "test-ns", Name: "web"},
+		{Kind: common.KindDeployment, Namespace: "test-ns", Name: "worker"},
+		{Kind: common.KindPod, Namespace: "test-ns", Name: "debug"},
+	}
+
+	var out bytes.Buffer
+	require.NoError(t, printDiff(context.Background(), &out, discovery.New(clientset, log), controllers, 2, nil, nil))
+	require.Equal(t, "  Deployment/test-ns/web: 3 -> 2\n"+
+		"  Deployment/test-ns/worker: 1 -> 1\n"+
+		"  Pod/test-ns/debug: running -> deleted\n", out.String())
+}
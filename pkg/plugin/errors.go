@@ -0,0 +1,41 @@
+package plugin
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	// ExitCodeNothingToDo is returned when nothing was scaled down.
+	ExitCodeNothingToDo = 2
+	// ExitCodePartialFailure is returned when some controllers were scaled down, but others failed.
+	ExitCodePartialFailure = 3
+	// ExitCodeCancelled is returned when the user declined the confirmation prompt.
+	ExitCodeCancelled = 4
+	// ExitCodeNodeSchedulable is returned when the targeted node hasn't been cordoned.
+	ExitCodeNodeSchedulable = 5
+)
+
+// errInterrupted is recorded for controllers that weren't scaled down because the run was interrupted first.
+var errInterrupted = errors.New("interrupted before scaling down")
+
+// ExitError is an error that should cause the plugin to exit with a specific status code.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func newExitError(code int, format string, args ...any) *ExitError {
+	return &ExitError{
+		Code: code,
+		Err:  fmt.Errorf(format, args...),
+	}
+}
+
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
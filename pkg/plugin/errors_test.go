@@ -0,0 +1,184 @@
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/common"
+	"github.com/stretchr/testify/require"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestExitCode(t *testing.T) {
+	deployment := common.ControllerRef{Kind: common.KindDeployment, Namespace: "test-ns", Name: "web"}
+	statefulSet := common.ControllerRef{Kind: common.KindStatefulSet, Namespace: "test-ns", Name: "db"}
+	scaleDownErr := errors.New("encountered 1 errors scaling down")
+	tests := []struct {
+		name     string
+		result   Result
+		err      error
+		wantCode int
+	}{
+		{name: "success", result: Result{Scaled: []common.ControllerRef{deployment}}},
+		{name: "nothing to do", result: Result{}, wantCode: ExitCodeNothingToDo},
+		{
+			name:     "partial failure",
+			result:   Result{Scaled: []common.ControllerRef{deployment}, Failed: []common.ControllerRef{statefulSet}},
+			err:      scaleDownErr,
+			wantCode: ExitCodePartialFailure,
+		},
+		{name: "cancelled", result: Result{Cancelled: true}, wantCode: ExitCodeCancelled},
+		{name: "total failure", result: Result{Failed: []common.ControllerRef{statefulSet}}, err: scaleDownErr, wantCode: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := exitCode(&tt.result, tt.err)
+			var exitErr *ExitError
+			switch {
+			case tt.wantCode == 0:
+				require.NoError(t, err)
+			case errors.As(err, &exitErr):
+				require.Equal(t, tt.wantCode, exitErr.Code)
+			default:
+				require.Equal(t, 1, tt.wantCode)
+				require.ErrorIs(t, err, scaleDownErr)
+			}
+		})
+	}
+}
+
+func TestRunPluginExitCodes(t *testing.T) {
+	tests := []struct {
+		name      string
+		pods      []string
+		configure func(cfg *ConfigFlags)
+		wantCode  int
+	}{
+		{name: "success", pods: []string{"app"}},
+		{name: "error", pods: []string{"broken"}, wantCode: 1},
+		{name: "nothing to do", wantCode: ExitCodeNothingToDo},
+		{name: "partial failure", pods: []string{"app", "broken"}, wantCode: ExitCodePartialFailure},
+		{
+			name: "cancelled",
+			pods: []string{"app"},
+			configure: func(cfg *ConfigFlags) {
+				*cfg.Confirmed = false
+				cfg.in = strings.NewReader("n\n")
+			},
+			wantCode: ExitCodeCancelled,
+		},
+		{
+			name:      "node schedulable",
+			pods:      []string{"app"},
+			configure: func(cfg *ConfigFlags) { *cfg.NodeName = "node-1" },
+			wantCode:  ExitCodeNodeSchedulable,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kubeconfig := newFakeCluster(t, tt.pods...)
+			_, _, _, err := runPlugin(func(cfg *ConfigFlags) {
+				cfg.ConfigFlags = *genericclioptions.NewConfigFlags(false)
+				*cfg.KubeConfig = kubeconfig
+				*cfg.MaxRetries = 0
+				cfg.recorder = record.NewFakeRecorder(10)
+				if tt.configure != nil {
+					tt.configure(cfg)
+				}
+			})
+			requireExitCode(t, err, tt.wantCode)
+		})
+	}
+}
+
+// requireExitCode asserts that the plugin would exit with the given status code because of err.
+func requireExitCode(t *testing.T, err error, code int) {
+	t.Helper()
+	var exitErr *ExitError
+	switch code {
+	case 0:
+		require.NoError(t, err)
+	case 1:
+		require.Error(t, err)
+		require.False(t, errors.As(err, &exitErr), "unexpected exit code for %v", err)
+	default:
+		require.ErrorAs(t, err, &exitErr)
+		require.Equal(t, code, exitErr.Code, "unexpected exit code for %v", err)
+	}
+}
+
+// newFakeCluster serves an API server with a PVC test-ns/data used by the given standalone pods, and returns
+// the path of a kubeconfig for it. Evicting a pod removes it, except for pods named "broken". Only the PVCs,
+// pods and node-1 exist, other lists are empty.
+func newFakeCluster(t *testing.T, pods ...string) string {
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		// Lists are requested at /api/v1/<resource> or /api/v1/namespaces/<namespace>/<resource> (or under /apis/<group>)
+		segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		depth := len(segments)
+		if segments[0] == "apis" {
+			depth--
+		}
+		switch {
+		case r.URL.Path == "/api/v1/persistentvolumeclaims":
+			_, _ = fmt.Fprintf(w, `{"kind":"PersistentVolumeClaimList","apiVersion":"v1","items":[{
+				"metadata":{"name":"data","namespace":"test-ns"},
+				"spec":{"storageClassName":%q,"volumeName":"pv-data"},
+				"status":{"phase":"Bound"}
+			}]}`, storageClassName)
+		case r.URL.Path == "/api/v1/namespaces/test-ns/pods" && r.Method == http.MethodGet:
+			var items []string
+			for _, name := range pods {
+				items = append(items, fmt.Sprintf(`{
+					"metadata":{"name":%q,"namespace":"test-ns","uid":%q},
+					"spec":{"volumes":[{"name":"data","persistentVolumeClaim":{"claimName":"data"}}]},
+					"status":{"phase":"Running"}
+				}`, name, name))
+			}
+			_, _ = fmt.Fprintf(w, `{"kind":"PodList","apiVersion":"v1","items":[%s]}`, strings.Join(items, ","))
+		case r.URL.Path == "/api/v1/nodes/node-1":
+			_, _ = w.Write([]byte(`{"kind":"Node","apiVersion":"v1","metadata":{"name":"node-1"}}`))
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/eviction") && segments[5] != "broken":
+			pods = slices.DeleteFunc(pods, func(name string) bool { return name == segments[5] })
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"kind":"Status","apiVersion":"v1","status":"Success"}`))
+		case r.Method == http.MethodGet && (depth == 3 || depth == 5):
+			_, _ = w.Write([]byte(`{"items":[]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	kubeconfig := filepath.Join(t.TempDir(), "kubeconfig")
+	require.NoError(t, os.WriteFile(kubeconfig, []byte(fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- name: test
+  cluster:
+    server: %s
+contexts:
+- name: test
+  context:
+    cluster: test
+    user: test
+current-context: test
+users:
+- name: test
+  user:
+    token: test
+`, server.URL)), 0o600))
+	return kubeconfig
+}
@@ -0,0 +1,52 @@
+package plugin
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/common"
+)
+
+// isExcluded checks whether the controller matches any of the --exclude-namespace, --exclude-controller, or
+// --skip-controller filters, or is of the class of resources left alone by --only-controllers or --only-pods.
+// Excluded controllers are formatted as either "kind/name" or just "name", and skipped controllers as
+// "kind/namespace/name".
+func isExcluded(cfg *ConfigFlags, ctrl common.ControllerRef) bool {
+	isPod := ctrl.Kind == common.KindPod
+	if cfg.OnlyControllers != nil && *cfg.OnlyControllers && isPod || cfg.OnlyPods != nil && *cfg.OnlyPods && !isPod {
+		return true
+	}
+	if cfg.ExcludeNamespaces != nil && slices.Contains(*cfg.ExcludeNamespaces, ctrl.Namespace) {
+		return true
+	}
+	if cfg.SkipControllers != nil && slices.ContainsFunc(*cfg.SkipControllers, func(skipped string) bool {
+		kind, namespace, name, _ := parseSkippedController(skipped)
+		return strings.EqualFold(kind, ctrl.Kind) && namespace == ctrl.Namespace && name == ctrl.Name
+	}) {
+		return true
+	}
+	if cfg.ExcludeControllers == nil {
+		return false
+	}
+	for _, excluded := range *cfg.ExcludeControllers {
+		kind, name, found := strings.Cut(excluded, "/")
+		if !found {
+			name, kind = kind, ""
+		}
+		if name == ctrl.Name && (kind == "" || strings.EqualFold(kind, ctrl.Kind)) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseSkippedController parses a controller given with --skip-controller, which must be fully qualified as
+// "kind/namespace/name" so that it can't match controllers other than the intended one.
+func parseSkippedController(skipped string) (kind, namespace, name string, err error) {
+	parts := strings.Split(skipped, "/")
+	if len(parts) != 3 || slices.Contains(parts, "") {
+		return "", "", "", fmt.Errorf("invalid --skip-controller %q, must be formatted as kind/namespace/name", skipped)
+	}
+	return parts[0], parts[1], parts[2], nil
+}
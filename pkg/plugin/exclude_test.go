@@ -0,0 +1,38 @@
+package plugin
+
+import (
+	"testing"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOnlyControllersOrPods(t *testing.T) {
+	deployment := common.ControllerRef{Kind: common.KindDeployment, Namespace: "test-ns", Name: "app"}
+	pod := common.ControllerRef{Kind: common.KindPod, Namespace: "test-ns", Name: "sentinel"}
+
+	cfg := &ConfigFlags{OnlyControllers: common.BoolP(true)}
+	require.False(t, isExcluded(cfg, deployment))
+	require.True(t, isExcluded(cfg, pod))
+
+	cfg = &ConfigFlags{OnlyPods: common.BoolP(true)}
+	require.True(t, isExcluded(cfg, deployment))
+	require.False(t, isExcluded(cfg, pod))
+
+	cfg = &ConfigFlags{StorageClass: &[]string{"standard"}, OnlyControllers: common.BoolP(true), OnlyPods: common.BoolP(true)}
+	require.EqualError(t, validate(cfg), "--only-controllers and --only-pods can't be used together")
+}
+
+func TestSkipControllers(t *testing.T) {
+	skipped := common.ControllerRef{Kind: common.KindDeployment, Namespace: "billing", Name: "postgres"}
+	other := common.ControllerRef{Kind: common.KindDeployment, Namespace: "analytics", Name: "postgres"}
+	cfg := &ConfigFlags{StorageClass: &[]string{"standard"}, SkipControllers: &[]string{"deployment/billing/postgres"}}
+	require.NoError(t, validate(cfg))
+	require.True(t, isExcluded(cfg, skipped))
+	require.False(t, isExcluded(cfg, other))
+
+	for _, invalid := range []string{"postgres", "deployment/postgres", "deployment//postgres", "deployment/billing/postgres/extra"} {
+		*cfg.SkipControllers = []string{invalid}
+		require.ErrorContains(t, validate(cfg), "must be formatted as kind/namespace/name", invalid)
+	}
+}
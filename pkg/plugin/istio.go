@@ -0,0 +1,59 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/common"
+	"github.com/dancavallaro/kubectl-unmount/pkg/discovery"
+	"github.com/dancavallaro/kubectl-unmount/pkg/istio"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// istioPatches generates the VirtualService patches that redirect traffic away from the Services backed by
+// the given controllers. Returns no patches if Istio isn't installed in the cluster.
+func istioPatches(ctx context.Context, cfg *ConfigFlags, clientset kubernetes.Interface, finder discovery.Finder,
+	istioClient istio.Client, controllers []common.ControllerRef, podsByController map[common.ControllerRef][]corev1.Pod) ([]istio.Patch, error) {
+	_, err := clientset.CoreV1().Namespaces().Get(ctx, *cfg.IstioNamespace, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		cfg.logger.Info("Istio namespace %s not found, skipping VirtualService patches", *cfg.IstioNamespace)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get namespace %s: %w", *cfg.IstioNamespace, err)
+	}
+	installed, err := istioClient.Installed()
+	if err != nil {
+		return nil, err
+	}
+	if !installed {
+		cfg.logger.Info("Istio CRDs are not installed, skipping VirtualService patches")
+		return nil, nil
+	}
+
+	hostsPerNs := make(map[string][]string)
+	for _, ctrl := range controllers {
+		services, err := finder.FindServicesForPods(ctx, ctrl.Namespace, podsByController[ctrl])
+		if err != nil {
+			return nil, err
+		}
+		for _, svc := range services {
+			hostsPerNs[ctrl.Namespace] = append(hostsPerNs[ctrl.Namespace], istio.ServiceHosts(svc)...)
+		}
+	}
+
+	var patches []istio.Patch
+	for _, ns := range slices.Sorted(maps.Keys(hostsPerNs)) {
+		nsPatches, err := istioClient.GeneratePatches(ctx, ns, hostsPerNs[ns])
+		if err != nil {
+			return nil, err
+		}
+		patches = append(patches, nsPatches...)
+	}
+	return patches, nil
+}
@@ -0,0 +1,57 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/common"
+	"github.com/dancavallaro/kubectl-unmount/pkg/keda"
+)
+
+// pauseScaledObjects pauses the KEDA ScaledObjects targeting the given controllers, since KEDA would otherwise
+// scale them right back up. Does nothing if KEDA isn't installed in the cluster.
+func pauseScaledObjects(ctx context.Context, cfg *ConfigFlags, kedaClient keda.Client, controllers []common.ControllerRef,
+	result *Result) error {
+	installed, err := kedaClient.Installed()
+	if err != nil || !installed {
+		return err
+	}
+	for _, ctrl := range controllers {
+		so, err := kedaClient.FindScaledObject(ctx, ctrl)
+		if err != nil {
+			return err
+		}
+		if so == nil {
+			continue
+		}
+		name := fmt.Sprintf("%s/%s", so.GetNamespace(), so.GetName())
+		if *cfg.DryRun {
+			cfg.logger.Info("  (dry-run, skipping pausing ScaledObject %s)", name)
+			result.PausedScaledObjects = append(result.PausedScaledObjects, name)
+			continue
+		}
+		paused, err := kedaClient.Pause(ctx, so)
+		if err != nil {
+			return err
+		}
+		if paused {
+			result.PausedScaledObjects = append(result.PausedScaledObjects, name)
+		}
+	}
+	return nil
+}
+
+// resumeScaledObject resumes the KEDA ScaledObject targeting the given controller, if it was paused when the
+// controller was scaled down.
+func resumeScaledObject(ctx context.Context, cfg *ConfigFlags, kedaClient keda.Client, ctrl common.ControllerRef) error {
+	so, err := kedaClient.FindScaledObject(ctx, ctrl)
+	if err != nil || so == nil {
+		return err
+	}
+	if *cfg.DryRun {
+		cfg.logger.Info("  (dry-run, skipping resuming ScaledObject %s/%s)", so.GetNamespace(), so.GetName())
+		return nil
+	}
+	_, err = kedaClient.Resume(ctx, so)
+	return err
+}
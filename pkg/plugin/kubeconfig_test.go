@@ -0,0 +1,94 @@
+package plugin
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/kubernetes"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+current-context: active
+clusters:
+- name: active
+  cluster:
+    server: https://active.example.com
+- name: other
+  cluster:
+    server: https://other.example.com
+contexts:
+- name: active
+  context:
+    cluster: active
+    user: test
+- name: other
+  context:
+    cluster: other
+    user: test
+users:
+- name: test
+  user:
+    token: test
+`
+
+func TestKubeContext(t *testing.T) {
+	kubeconfig := filepath.Join(t.TempDir(), "kubeconfig")
+	require.NoError(t, os.WriteFile(kubeconfig, []byte(testKubeconfig), 0o600))
+
+	tests := []struct {
+		name    string
+		context string
+		host    string
+	}{
+		{name: "uses the active context by default", host: "https://active.example.com"},
+		{name: "uses the context given by --context", context: "other", host: "https://other.example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &ConfigFlags{ConfigFlags: *genericclioptions.NewConfigFlags(false)}
+			*cfg.KubeConfig = kubeconfig
+			*cfg.Context = tt.context
+
+			config, err := cfg.ToRESTConfig()
+			require.NoError(t, err)
+			require.Equal(t, tt.host, config.Host)
+		})
+	}
+}
+
+func TestImpersonation(t *testing.T) {
+	var headers http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header.Clone()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"kind":"PersistentVolumeClaimList","apiVersion":"v1","items":[]}`))
+	}))
+	defer server.Close()
+
+	kubeconfig := filepath.Join(t.TempDir(), "kubeconfig")
+	require.NoError(t, os.WriteFile(kubeconfig, []byte(testKubeconfig), 0o600))
+
+	cfg := &ConfigFlags{ConfigFlags: *genericclioptions.NewConfigFlags(false)}
+	*cfg.KubeConfig = kubeconfig
+	*cfg.APIServer = server.URL
+	*cfg.Impersonate = "system:serviceaccount:test-ns:unmounter"
+	*cfg.ImpersonateGroup = []string{"ops", "admins"}
+
+	config, err := cfg.ToRESTConfig()
+	require.NoError(t, err)
+	clientset, err := kubernetes.NewForConfig(config)
+	require.NoError(t, err)
+	_, err = clientset.CoreV1().PersistentVolumeClaims("").List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+
+	require.Equal(t, "system:serviceaccount:test-ns:unmounter", headers.Get("Impersonate-User"))
+	require.Equal(t, []string{"ops", "admins"}, headers.Values("Impersonate-Group"))
+}
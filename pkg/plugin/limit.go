@@ -0,0 +1,19 @@
+package plugin
+
+import "fmt"
+
+// checkMaxResources guards against a mis-scoped selector scaling down much more than intended: it fails if more
+// than --max-resources controllers (including standalone pods) would be acted on. In dry-run mode nothing is
+// modified, so it only warns.
+func checkMaxResources(cfg *ConfigFlags, n int) error {
+	if *cfg.MaxResources == 0 || n <= *cfg.MaxResources {
+		return nil
+	}
+	if *cfg.DryRun {
+		cfg.logger.Warn("%d controllers and pods matched, more than --max-resources=%d, so a real run would be aborted",
+			n, *cfg.MaxResources)
+		return nil
+	}
+	return fmt.Errorf("aborting, %d controllers and pods matched, more than --max-resources=%d "+
+		"(narrow the selector, or raise --max-resources if this is intended)", n, *cfg.MaxResources)
+}
@@ -0,0 +1,28 @@
+package plugin
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/common"
+	"github.com/dancavallaro/kubectl-unmount/pkg/logger"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckMaxResources(t *testing.T) {
+	var logs bytes.Buffer
+	cfg := &ConfigFlags{
+		MaxResources: common.IntP(2),
+		DryRun:       common.BoolP(false),
+		logger:       logger.NewLogger(&logs, logger.LevelInfo),
+	}
+	require.NoError(t, checkMaxResources(cfg, 2))
+	require.ErrorContains(t, checkMaxResources(cfg, 3), "3 controllers and pods matched, more than --max-resources=2")
+
+	*cfg.DryRun = true
+	require.NoError(t, checkMaxResources(cfg, 3))
+	require.Contains(t, logs.String(), "a real run would be aborted")
+
+	*cfg.DryRun, *cfg.MaxResources = false, 0
+	require.NoError(t, checkMaxResources(cfg, 1000))
+}
@@ -0,0 +1,203 @@
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/common"
+	"github.com/dancavallaro/kubectl-unmount/pkg/discovery"
+	"github.com/dancavallaro/kubectl-unmount/pkg/keda"
+	"github.com/dancavallaro/kubectl-unmount/pkg/scaling"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// planAPIVersion is the version of the plan file schema. It must be changed whenever the schema changes
+// incompatibly, so that plans written by other versions of kubectl-unmount are rejected instead of misread.
+const planAPIVersion = "kubectl-unmount/v1"
+
+// planFile records what a dry run (with --plan-file) would scale down, so that exactly those operations can be
+// applied later with --apply-plan, without selecting PVCs and pods again (which may have changed in between).
+type planFile struct {
+	APIVersion  string              `json:"apiVersion"`
+	Kind        string              `json:"kind"`
+	PlannedAt   time.Time           `json:"plannedAt"`
+	Cluster     string              `json:"cluster"`
+	Controllers []plannedController `json:"controllers"`
+}
+
+type plannedController struct {
+	// APIVersion is only set for custom resources, see common.ControllerRef.
+	APIVersion string `json:"apiVersion,omitempty"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace"`
+	Name       string `json:"name"`
+	// Replicas is the controller's number of replicas when the plan was made, if it has one.
+	Replicas       *int32   `json:"replicas,omitempty"`
+	TriggeringPVCs []string `json:"triggeringPVCs"`
+}
+
+func (c plannedController) ref() common.ControllerRef {
+	return common.ControllerRef{Kind: c.Kind, Namespace: c.Namespace, Name: c.Name, APIVersion: c.APIVersion}
+}
+
+// writePlanFile writes the controllers that the dry run would scale down (or delete), which have the given
+// number of replicas, to the file given with --plan-file.
+func writePlanFile(cfg *ConfigFlags, result *Result, replicas map[common.ControllerRef]int32,
+	podsByController map[common.ControllerRef][]corev1.Pod, pvcsPerNs map[string][]string) error {
+	file := planFile{
+		APIVersion:  planAPIVersion,
+		Kind:        "Plan",
+		PlannedAt:   time.Now().UTC(),
+		Cluster:     clusterName(cfg),
+		Controllers: []plannedController{},
+	}
+	for _, ctrl := range append(result.Scaled, result.DeletedPods...) {
+		planned := plannedController{
+			APIVersion:     ctrl.APIVersion,
+			Kind:           ctrl.Kind,
+			Namespace:      ctrl.Namespace,
+			Name:           ctrl.Name,
+			TriggeringPVCs: triggerPVCs(podsByController[ctrl], pvcsPerNs),
+		}
+		if n, ok := replicas[ctrl]; ok {
+			planned.Replicas = &n
+		}
+		file.Controllers = append(file.Controllers, planned)
+	}
+
+	f, err := os.Create(*cfg.PlanFile)
+	if err != nil {
+		return fmt.Errorf("failed to write --plan-file: %w", err)
+	}
+	if err := printJSON(f, file); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write --plan-file: %w", err)
+	}
+	return nil
+}
+
+// readPlanFile reads the file given with --apply-plan, rejecting plans of other schema versions.
+func readPlanFile(path string) (planFile, error) {
+	var file planFile
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return file, fmt.Errorf("failed to read --apply-plan file: %w", err)
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return file, fmt.Errorf("failed to parse --apply-plan file %s: %w", path, err)
+	}
+	if file.APIVersion != planAPIVersion {
+		return file, fmt.Errorf("unsupported --apply-plan file %s: apiVersion is %q, expected %q", path, file.APIVersion, planAPIVersion)
+	}
+	return file, nil
+}
+
+// applyPlan scales down the controllers recorded in the file given with --apply-plan. The cluster isn't searched
+// for PVCs again, but a warning is logged for controllers whose replicas changed since the plan was made, and
+// PodDisruptionBudgets are checked against the pods still using the planned PVCs.
+func applyPlan(ctx context.Context, cfg *ConfigFlags, finder discovery.Finder, kedaClient keda.Client, scaler scaling.Scaler,
+	podFilter discovery.PodFilter, result *Result) error {
+	file, err := readPlanFile(*cfg.ApplyPlan)
+	if err != nil {
+		return err
+	}
+	if cluster := clusterName(cfg); cluster != file.Cluster {
+		cfg.logger.Warn("%s was planned for cluster %s, but the current cluster is %s", *cfg.ApplyPlan, file.Cluster, cluster)
+	}
+
+	cfg.logger.Info("Applying plan made at %s: %d controller(s) to scale down", file.PlannedAt.Format(time.RFC3339), len(file.Controllers))
+	var controllers []common.ControllerRef
+	replicasBefore := make(map[common.ControllerRef]int32)
+	pvcsPerNs := make(map[string][]string)
+	for _, planned := range file.Controllers {
+		ctrl := planned.ref()
+		controllers = append(controllers, ctrl)
+		for _, pvc := range planned.TriggeringPVCs {
+			if !slices.Contains(pvcsPerNs[ctrl.Namespace], pvc) {
+				pvcsPerNs[ctrl.Namespace] = append(pvcsPerNs[ctrl.Namespace], pvc)
+			}
+		}
+		_, _ = fmt.Fprintf(cfg.out, "  %v (PVCs: %s)\n", ctrl, strings.Join(planned.TriggeringPVCs, ", "))
+
+		replicas, ok, err := finder.DesiredReplicas(ctx, ctrl)
+		if err != nil {
+			return err
+		}
+		if ok {
+			replicasBefore[ctrl] = replicas
+		}
+		if ok && planned.Replicas != nil && replicas != *planned.Replicas {
+			cfg.logger.Warn("%v now has %d replicas, it had %d when the plan was made", ctrl, replicas, *planned.Replicas)
+		}
+	}
+	result.Controllers = controllers
+	if len(controllers) == 0 {
+		cfg.logger.Info("Nothing to do")
+		return nil
+	}
+	if err := checkMaxResources(cfg, len(controllers)); err != nil {
+		return err
+	}
+
+	// The plan doesn't record pods, so find the ones still using its PVCs (ignoring pods of unplanned controllers)
+	pods, err := finder.FindPodsUsingPVCs(ctx, pvcsPerNs, podFilter)
+	if err != nil {
+		return err
+	}
+	podsByController, err := finder.GroupPodsByController(ctx, pods)
+	if err != nil {
+		return err
+	}
+	maps.DeleteFunc(podsByController, func(ctrl common.ControllerRef, _ []corev1.Pod) bool {
+		return !slices.Contains(controllers, ctrl)
+	})
+	blockingPDBs, err := findBlockingPDBs(ctx, cfg, finder, podsByController, nil)
+	if err != nil {
+		return err
+	}
+
+	skipConfirmation := cfg.Confirmed != nil && *cfg.Confirmed
+	confirmed, err := confirmAction(ctx, cfg.logger, bufio.NewReader(cfg.in),
+		"The controllers listed above will be scaled down. Proceed?", skipConfirmation)
+	if err != nil {
+		return err
+	}
+	if !confirmed {
+		cfg.logger.Info("Operation cancelled by user")
+		result.Cancelled = true
+		return nil
+	}
+
+	if err := disableHPAs(ctx, cfg, finder, scaler, controllers, result); err != nil {
+		return err
+	}
+	if err := pauseScaledObjects(ctx, cfg, kedaClient, controllers, result); err != nil {
+		return err
+	}
+	errs := scaleDownAll(ctx, cfg, scaler, nil, controllers, podsByController, pvcsPerNs, blockingPDBs)
+	result.recordScaleDown(controllers, errs)
+	if *cfg.OutputFile != "" {
+		if err := writeRestoreFile(cfg, result, replicasBefore); err != nil {
+			return err
+		}
+		cfg.logger.Info("Wrote scaled down controllers to %s, restore them with --restore-from=%s", *cfg.OutputFile, *cfg.OutputFile)
+	}
+	if errs := slices.DeleteFunc(errs, func(err error) bool {
+		return err == nil || errors.Is(err, scaling.ErrNotScalable)
+	}); len(errs) > 0 {
+		return fmt.Errorf("encountered %d errors scaling down: %w", len(errs), errors.Join(errs...))
+	}
+	cfg.logger.Info(result.summary())
+	return nil
+}
@@ -0,0 +1,119 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/common"
+	"github.com/dancavallaro/kubectl-unmount/pkg/discovery"
+	"github.com/dancavallaro/kubectl-unmount/pkg/keda"
+	"github.com/dancavallaro/kubectl-unmount/pkg/scaling"
+	"github.com/stretchr/testify/require"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
+)
+
+func TestPlanFile(t *testing.T) {
+	cronJob := common.ControllerRef{Kind: common.KindCronJob, Namespace: "test-ns", Name: "backup"}
+	clientset := fake.NewClientset(
+		&batchv1.CronJob{ObjectMeta: metav1.ObjectMeta{Namespace: "test-ns", Name: "backup"}, Spec: batchv1.CronJobSpec{Suspend: ptr.To(false)}},
+	)
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "test-ns", Name: "backup-0"},
+		Spec: corev1.PodSpec{Volumes: []corev1.Volume{{
+			Name:         "data",
+			VolumeSource: corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "data"}},
+		}}},
+	}
+
+	var logs, out bytes.Buffer
+	path := filepath.Join(t.TempDir(), "plan.json")
+	cfg := newTestConfig(&out, &logs)
+	cfg.PlanFile, cfg.ApplyPlan = common.StringP(path), common.StringP(path)
+	planned := &Result{Scaled: []common.ControllerRef{cronJob}}
+	require.NoError(t, writePlanFile(cfg, planned, nil,
+		map[common.ControllerRef][]corev1.Pod{cronJob: {pod}}, map[string][]string{"test-ns": {"data"}}))
+
+	file, err := readPlanFile(path)
+	require.NoError(t, err)
+	require.Equal(t, planAPIVersion, file.APIVersion)
+	require.Equal(t, []plannedController{{
+		Kind: common.KindCronJob, Namespace: "test-ns", Name: "backup", TriggeringPVCs: []string{"data"},
+	}}, file.Controllers)
+
+	ctx := context.Background()
+	result := &Result{}
+	scaler := scaling.New(clientset, cfg.logger, scaling.Options{})
+	require.NoError(t, applyPlan(ctx, cfg, discovery.New(clientset, cfg.logger), newTestKedaClient(clientset, cfg), scaler,
+		discovery.PodFilter{}, result))
+	require.Equal(t, []common.ControllerRef{cronJob}, result.Scaled)
+	require.Contains(t, out.String(), "CronJob/test-ns/backup (PVCs: data)")
+
+	c, err := clientset.BatchV1().CronJobs("test-ns").Get(ctx, "backup", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.True(t, *c.Spec.Suspend)
+}
+
+func TestApplyPlan(t *testing.T) {
+	db := common.ControllerRef{Kind: common.KindJob, Namespace: "test-ns", Name: "db"}
+	cache := common.ControllerRef{Kind: common.KindJob, Namespace: "test-ns", Name: "cache"}
+	owner := func(name string) metav1.OwnerReference {
+		return metav1.OwnerReference{APIVersion: "batch/v1", Kind: common.KindJob, Name: name, Controller: ptr.To(true)}
+	}
+	dbPod, cachePod := newWatchedPod("db-0", "data", owner("db")), newWatchedPod("cache-0", "cache", owner("cache"))
+	cachePod.Labels = map[string]string{"app": "cache"}
+	clientset := fake.NewClientset(
+		&batchv1.Job{ObjectMeta: metav1.ObjectMeta{Namespace: "test-ns", Name: "db"}, Spec: batchv1.JobSpec{Suspend: ptr.To(false)}},
+		&batchv1.Job{ObjectMeta: metav1.ObjectMeta{Namespace: "test-ns", Name: "cache"}, Spec: batchv1.JobSpec{Suspend: ptr.To(false)}},
+		dbPod, cachePod,
+		&policyv1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "test-ns", Name: "cache"},
+			Spec:       policyv1.PodDisruptionBudgetSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "cache"}}},
+			Status:     policyv1.PodDisruptionBudgetStatus{CurrentHealthy: 1, DesiredHealthy: 1},
+		},
+	)
+
+	var logs, out bytes.Buffer
+	path := filepath.Join(t.TempDir(), "plan.json")
+	cfg := newTestConfig(&out, &logs)
+	cfg.PlanFile, cfg.ApplyPlan = common.StringP(path), common.StringP(path)
+	// The dry run that made the plan doesn't refuse to scale down controllers blocked by a PodDisruptionBudget
+	require.NoError(t, writePlanFile(cfg, &Result{Scaled: []common.ControllerRef{db, cache}}, nil,
+		map[common.ControllerRef][]corev1.Pod{db: {*dbPod}, cache: {*cachePod}},
+		map[string][]string{"test-ns": {"data", "cache"}}))
+
+	ctx := context.Background()
+	result := &Result{}
+	scaler := scaling.New(clientset, cfg.logger, scaling.Options{})
+	err := applyPlan(ctx, cfg, discovery.New(clientset, cfg.logger), newTestKedaClient(clientset, cfg), scaler,
+		discovery.PodFilter{}, result)
+	require.ErrorContains(t, err, "refusing to scale down Job/test-ns/cache, it would violate PodDisruptionBudget test-ns/cache")
+	require.Equal(t, []common.ControllerRef{db}, result.Scaled)
+	require.Equal(t, []common.ControllerRef{cache}, result.Failed)
+
+	job, err := clientset.BatchV1().Jobs("test-ns").Get(ctx, "db", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.True(t, *job.Spec.Suspend)
+	require.Equal(t, "data", job.Annotations[common.AnnotationPVCTrigger])
+}
+
+// newTestKedaClient creates a KEDA client for a cluster without KEDA installed.
+func newTestKedaClient(clientset *fake.Clientset, cfg *ConfigFlags) keda.Client {
+	return keda.New(dynamicfake.NewSimpleDynamicClient(runtime.NewScheme()), clientset.Discovery(), cfg.logger)
+}
+
+func TestReadPlanFileRejectsOtherVersions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plan.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"apiVersion": "kubectl-unmount/v2", "controllers": []}`), 0o644))
+	_, err := readPlanFile(path)
+	require.ErrorContains(t, err, `apiVersion is "kubectl-unmount/v2"`)
+}
@@ -3,166 +3,1538 @@ package plugin
 import (
 	"bufio"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"maps"
+	"net/http"
 	"os"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/dancavallaro/kubectl-unmount/pkg/common"
 	"github.com/dancavallaro/kubectl-unmount/pkg/discovery"
+	"github.com/dancavallaro/kubectl-unmount/pkg/istio"
+	"github.com/dancavallaro/kubectl-unmount/pkg/keda"
 	"github.com/dancavallaro/kubectl-unmount/pkg/logger"
+	"github.com/dancavallaro/kubectl-unmount/pkg/metrics"
 	"github.com/dancavallaro/kubectl-unmount/pkg/scaling"
 	"github.com/dancavallaro/kubectl-unmount/pkg/spinner"
+	"golang.org/x/time/rate"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
 )
 
 type ConfigFlags struct {
 	genericclioptions.ConfigFlags
 
-	Confirmed    *bool
-	DryRun       *bool
-	StorageClass *string
-	PVCName      *string
+	Confirmed          *bool
+	Verbose            *bool
+	Verbosity          *int
+	Interactive        *bool
+	DryRun             *bool
+	DryRunDiff         *bool
+	DryRunServer       *bool
+	ByNode             *bool
+	IgnorePDB          *bool
+	Force              *bool
+	DisableEviction    *bool
+	CheckPDBViolations *bool
 
-	logger *logger.Logger
-	out    io.Writer
+	CheckAdmissionWebhooks *bool
+
+	StorageClass       *[]string
+	AccessMode         *string
+	IncludeUnbound     *bool
+	CSIDriver          *string
+	MinSize            *string
+	PVCName            *[]string
+	PVName             *string
+	PVCRegex           *string
+	FromStdin          *bool
+	NamespaceSelector  *string
+	Selector           *string
+	FieldSelector      *string
+	AnnotationSelector *string
+	NodeName           *string
+	PodStatusFilter    *[]string
+
+	ExcludeNamespaces  *[]string
+	ExcludeControllers *[]string
+	SkipControllers    *[]string
+	OnlyControllers    *bool
+	OnlyPods           *bool
+	// ProtectAnnotation is the annotation that protects controllers (or their pods) set to "true" from being
+	// scaled down, or empty to not check it.
+	ProtectAnnotation *string
+
+	Concurrency         *int
+	MaxDisruptionBudget *int
+	// MaxResources is the maximum number of controllers (including standalone pods) to act on, or 0 for no limit.
+	MaxResources *int
+	// Replicas is the number of replicas to scale controllers down to, e.g. 1 to only stop the extra writers of a
+	// ReadWriteMany volume. Standalone pods are still deleted, and Jobs and CronJobs still suspended.
+	Replicas                 *int
+	MaxRetries               *int
+	RateLimit                *float64
+	Burst                    *int
+	GracePeriod              *int64
+	ScaleAnnotations         *map[string]string
+	PreValidation            *bool
+	SkipIfScaled             *bool
+	SkipUnschedulableCheck   *bool
+	Cordon                   *bool
+	LeaveHPA                 *bool
+	Uncordon                 *bool
+	Wait                     *bool
+	WaitTimeout              *time.Duration
+	MaxWaitForSchedule       *time.Duration
+	WaitForReplicaSetCleanup *bool
+	VerifyDetach             *bool
+	CleanAttachments         *bool
+	CheckCustomFinalizers    *bool
+	RemoveCustomFinalizers   *[]string
+
+	DatadogMetrics *bool
+	StatsdAddress  *string
+
+	Output         *string
+	PatchIstioVS   *bool
+	IstioNamespace *string
+
+	CloudProvider *string
+
+	LogLevel *string
+	LogJSON  *bool
+
+	// Contexts are the kubeconfig contexts to run in one after the other (instead of --context), if set.
+	Contexts *[]string
+	// AllContexts runs in every context of the kubeconfig, one after the other.
+	AllContexts *bool
+
+	// Timings logs how long each phase of the run took, at the end.
+	Timings *bool
+
+	// Watch keeps running until interrupted, scaling down the controllers of new pods that mount the targeted
+	// PVCs as they're created.
+	Watch *bool
+	// WatchCooldown is how long to leave a controller alone after scaling it down with --watch, so as not to
+	// fight whatever keeps scaling it back up.
+	WatchCooldown *time.Duration
+
+	// Timeout is the deadline for the whole run (unlike --request-timeout, which applies to each request).
+	Timeout *time.Duration
+
+	// OutputFile is the path to write the scaled down controllers to (as JSON), for --restore-from, if set.
+	OutputFile *string
+	// RestoreFrom is the path of a file written with --output-file, whose controllers are scaled back up
+	// instead of scaling anything down, if set.
+	RestoreFrom *string
+	// PlanFile is the path to write the plan computed by a dry run to (as JSON), for --apply-plan, if set.
+	PlanFile *string
+	// ApplyPlan is the path of a file written with --plan-file, whose controllers are scaled down without
+	// selecting PVCs and pods again, if set.
+	ApplyPlan *string
+
+	// GenerateRunbook is the path to write a Markdown runbook documenting the operation to, if set.
+	GenerateRunbook *string
+	// Version is the version of kubectl-unmount, included in the runbook.
+	Version string
+
+	logger   *logger.Logger
+	progress logger.ProgressWriter
+	recorder record.EventRecorder
+	in       io.Reader
+	out      io.Writer
+	// contextName is the context currently being run in, when running in several (with --contexts or
+	// --all-contexts), so that the output can tell them apart.
+	contextName string
+}
+
+// NewConfigFlags creates a configuration with the flags' defaults, for running the plugin (or finding the resources
+// it would affect) without going through the command line. Fields left nil are set to these defaults when running.
+func NewConfigFlags() *ConfigFlags {
+	return &ConfigFlags{
+		ConfigFlags:              *genericclioptions.NewConfigFlags(false),
+		Confirmed:                common.BoolP(false),
+		Verbose:                  common.BoolP(false),
+		Verbosity:                common.IntP(0),
+		Interactive:              common.BoolP(false),
+		DryRun:                   common.BoolP(false),
+		DryRunDiff:               common.BoolP(false),
+		ByNode:                   common.BoolP(false),
+		DryRunServer:             common.BoolP(false),
+		IgnorePDB:                common.BoolP(false),
+		Force:                    common.BoolP(false),
+		DisableEviction:          common.BoolP(false),
+		CheckPDBViolations:       common.BoolP(false),
+		CheckAdmissionWebhooks:   common.BoolP(false),
+		PVCName:                  &[]string{},
+		PVName:                   common.StringP(""),
+		PVCRegex:                 common.StringP(""),
+		NamespaceSelector:        common.StringP(""),
+		FromStdin:                common.BoolP(false),
+		Selector:                 common.StringP(""),
+		FieldSelector:            common.StringP(""),
+		AnnotationSelector:       common.StringP(""),
+		NodeName:                 common.StringP(""),
+		PodStatusFilter:          &[]string{"Running", "Pending"},
+		ExcludeNamespaces:        &[]string{},
+		ExcludeControllers:       &[]string{},
+		SkipControllers:          &[]string{},
+		OnlyControllers:          common.BoolP(false),
+		OnlyPods:                 common.BoolP(false),
+		ProtectAnnotation:        common.StringP(common.AnnotationSkip),
+		Timings:                  common.BoolP(false),
+		Watch:                    common.BoolP(false),
+		WatchCooldown:            common.DurationP(time.Minute),
+		StorageClass:             &[]string{},
+		AccessMode:               common.StringP(""),
+		IncludeUnbound:           common.BoolP(false),
+		CSIDriver:                common.StringP(""),
+		MinSize:                  common.StringP(""),
+		Concurrency:              common.IntP(1),
+		MaxDisruptionBudget:      common.IntP(0),
+		MaxResources:             common.IntP(100),
+		Replicas:                 common.IntP(0),
+		MaxRetries:               common.IntP(3),
+		RateLimit:                common.Float64P(10),
+		Burst:                    common.IntP(20),
+		GracePeriod:              common.Int64P(-1),
+		ScaleAnnotations:         &map[string]string{},
+		PreValidation:            common.BoolP(false),
+		SkipIfScaled:             common.BoolP(false),
+		SkipUnschedulableCheck:   common.BoolP(false),
+		Cordon:                   common.BoolP(false),
+		LeaveHPA:                 common.BoolP(false),
+		Uncordon:                 common.BoolP(false),
+		Wait:                     common.BoolP(false),
+		WaitTimeout:              common.DurationP(5 * time.Minute),
+		MaxWaitForSchedule:       common.DurationP(0),
+		WaitForReplicaSetCleanup: common.BoolP(false),
+		VerifyDetach:             common.BoolP(false),
+		CleanAttachments:         common.BoolP(false),
+		CheckCustomFinalizers:    common.BoolP(false),
+		RemoveCustomFinalizers:   &[]string{},
+		DatadogMetrics:           common.BoolP(false),
+		StatsdAddress:            common.StringP("127.0.0.1:8125"),
+		Output:                   common.StringP(""),
+		PatchIstioVS:             common.BoolP(false),
+		IstioNamespace:           common.StringP("istio-system"),
+		CloudProvider:            common.StringP(""),
+		LogLevel:                 common.StringP("info"),
+		LogJSON:                  common.BoolP(false),
+		Contexts:                 &[]string{},
+		AllContexts:              common.BoolP(false),
+		Timeout:                  common.DurationP(0),
+		OutputFile:               common.StringP(""),
+		RestoreFrom:              common.StringP(""),
+		PlanFile:                 common.StringP(""),
+		ApplyPlan:                common.StringP(""),
+		GenerateRunbook:          common.StringP(""),
+	}
+}
+
+// setDefaults sets the flags left nil to their defaults, and sets up the logger, the progress writer, and the
+// standard streams, unless they were set already (e.g. by tests).
+func setDefaults(cfg *ConfigFlags) error {
+	// Callers of FindAffectedResources and RunPlugin may only set the flags they need
+	defaults := reflect.ValueOf(NewConfigFlags()).Elem()
+	fields := reflect.ValueOf(cfg).Elem()
+	for i := range fields.NumField() {
+		if field := fields.Field(i); field.Kind() == reflect.Pointer && field.IsNil() && field.CanSet() {
+			field.Set(defaults.Field(i))
+		}
+	}
+	if cfg.logger == nil {
+		level, err := logger.ParseLevel(*cfg.LogLevel)
+		if err != nil {
+			return err
+		}
+		if *cfg.LogJSON {
+			cfg.logger = logger.NewJSONLogger(os.Stderr, level)
+		} else {
+			cfg.logger = logger.NewLogger(os.Stderr, level)
+		}
+	}
+	if cfg.progress == nil {
+		// Progress is reported on stderr along with the logs, but would break JSON logs
+		progressOut := io.Writer(os.Stderr)
+		if *cfg.LogJSON {
+			progressOut = io.Discard
+		}
+		cfg.progress = logger.NewProgressWriter(progressOut)
+	}
+	if cfg.out == nil {
+		cfg.out = os.Stdout
+	}
+	if cfg.in == nil {
+		cfg.in = os.Stdin
+	}
+	return nil
+}
+
+// restConfig reads the kubeconfig, logging each API request if --verbosity is set.
+func restConfig(cfg *ConfigFlags) (*rest.Config, error) {
+	config, err := cfg.ToRESTConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read kubeconfig: %w", err)
+	}
+	if *cfg.Verbosity > 0 {
+		cfg.logger.SetVerbosity(*cfg.Verbosity)
+		config.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+			return &loggingTransport{next: rt, log: cfg.logger}
+		})
+	}
+	if cfg.RateLimit != nil && *cfg.RateLimit > 0 {
+		// Replace client-go's own (lower) default rate limit, which applies to each client separately
+		config.QPS = -1
+		limiter := rate.NewLimiter(rate.Limit(*cfg.RateLimit), *cfg.Burst)
+		config.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+			return &rateLimitedTransport{next: rt, limiter: limiter}
+		})
+	}
+	return config, nil
 }
 
-func RunPlugin(pluginCfg *ConfigFlags) error {
-	ctx := context.Background()
-	if pluginCfg.logger == nil {
-		pluginCfg.logger = logger.NewLogger(os.Stderr)
+// RunPlugin runs the plugin with the given configuration. In addition to the human-readable output, it
+// returns a Result describing which resources were affected. If the context is cancelled (e.g. by Ctrl-C),
+// it stops scaling down controllers and returns the context's error, and the Result describes which
+// controllers were already modified.
+func RunPlugin(ctx context.Context, pluginCfg *ConfigFlags) (*Result, error) {
+	if err := setDefaults(pluginCfg); err != nil {
+		return nil, err
 	}
-	if pluginCfg.out == nil {
-		pluginCfg.out = os.Stdout
+
+	if err := validate(pluginCfg); err != nil {
+		return nil, err
+	}
+
+	if *pluginCfg.OutputFile != "" {
+		if err := checkWritable("output-file", *pluginCfg.OutputFile); err != nil {
+			return nil, err
+		}
 	}
+	if *pluginCfg.PlanFile != "" {
+		if err := checkWritable("plan-file", *pluginCfg.PlanFile); err != nil {
+			return nil, err
+		}
+	}
+
+	if *pluginCfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *pluginCfg.Timeout)
+		defer cancel()
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, timeoutError(ctx, pluginCfg, err)
+	}
+
+	var result *Result
+	var err error
+	if multipleContexts(pluginCfg) {
+		result, err = runContexts(ctx, pluginCfg)
+	} else {
+		result, err = runInContext(ctx, pluginCfg)
+	}
+	if result == nil {
+		return nil, err
+	}
+	if err != nil && ctx.Err() != nil {
+		return result, timeoutError(ctx, pluginCfg, err)
+	}
+	return result, exitCode(result, err)
+}
+
+// multipleContexts returns whether to run in several kubeconfig contexts, with --contexts or --all-contexts.
+func multipleContexts(cfg *ConfigFlags) bool {
+	return cfg.Contexts != nil && len(*cfg.Contexts) > 0 || cfg.AllContexts != nil && *cfg.AllContexts
+}
 
-	config, err := pluginCfg.ToRESTConfig()
+// runInContext creates the clients for the configured kubeconfig context, and runs the plugin with them.
+func runInContext(ctx context.Context, cfg *ConfigFlags) (*Result, error) {
+	config, err := restConfig(cfg)
 	if err != nil {
-		return fmt.Errorf("failed to read kubeconfig: %w", err)
+		return nil, err
 	}
 
 	clientset, err := kubernetes.NewForConfig(config)
 	if err != nil {
-		return fmt.Errorf("failed to create clientset: %w", err)
+		return nil, fmt.Errorf("failed to create clientset: %w", err)
 	}
 
-	return run(ctx, pluginCfg, clientset)
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dynamic client: %w", err)
+	}
+
+	if cfg.recorder == nil {
+		broadcaster := record.NewBroadcaster()
+		broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: clientset.CoreV1().Events("")})
+		defer broadcaster.Shutdown()
+		cfg.recorder = broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: "kubectl-unmount"})
+		// Record the events of each context's run with its own clientset
+		defer func() { cfg.recorder = nil }()
+	}
+
+	return run(ctx, cfg, clientset, dynamicClient)
+}
+
+// exitCode wraps the outcome of a run in an ExitError, if it should cause the plugin to exit with a specific
+// status code.
+func exitCode(result *Result, err error) error {
+	switch {
+	case err != nil && len(result.Failed) > 0 && (len(result.Scaled) > 0 || len(result.DeletedPods) > 0 || len(result.Restored) > 0):
+		return &ExitError{Code: ExitCodePartialFailure, Err: err}
+	case err == nil && result.Cancelled && result.NothingToDo():
+		return newExitError(ExitCodeCancelled, "cancelled by user")
+	case err == nil && result.NothingToDo():
+		return newExitError(ExitCodeNothingToDo, "nothing to do")
+	}
+	return err
+}
+
+// timeoutError replaces an error caused by --timeout expiring (i.e. with the context's deadline exceeded)
+// with one describing the timeout.
+func timeoutError(ctx context.Context, cfg *ConfigFlags, err error) error {
+	if *cfg.Timeout > 0 && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("operation timed out after %v", *cfg.Timeout)
+	}
+	return err
 }
 
-func run(ctx context.Context, cfg *ConfigFlags, clientset *kubernetes.Clientset) error {
+func run(ctx context.Context, cfg *ConfigFlags, clientset *kubernetes.Clientset, dynamicClient dynamic.Interface) (*Result, error) {
 	finder := discovery.New(clientset, cfg.logger)
+	istioClient := istio.New(dynamicClient, clientset.Discovery(), cfg.logger)
+	kedaClient := keda.New(dynamicClient, clientset.Discovery(), cfg.logger)
+	result := &Result{DryRun: *cfg.DryRun}
+	timer := newTimings()
+	if *cfg.Timings {
+		defer timer.log(cfg.logger)
+	}
+
+	filter, podFilter := newFilters(cfg)
 
-	filter := discovery.PVCFilter{}
-	if cfg.Namespace != nil {
-		filter.Namespace = *cfg.Namespace
+	if *cfg.Uncordon {
+		return result, uncordonNodes(ctx, cfg, finder, newScaler(cfg, clientset, dynamicClient), result)
+	}
+	if *cfg.RestoreFrom != "" {
+		return result, restoreFrom(ctx, cfg, finder, kedaClient, newScaler(cfg, clientset, dynamicClient), result)
 	}
-	if cfg.StorageClass != nil {
-		filter.StorageClass = *cfg.StorageClass
+	if *cfg.ApplyPlan != "" {
+		return result, applyPlan(ctx, cfg, finder, kedaClient, newScaler(cfg, clientset, dynamicClient), podFilter, result)
+	}
+	if *cfg.Watch {
+		return result, watchPods(ctx, cfg, clientset, finder, newScaler(cfg, clientset, dynamicClient), filter, podFilter, result)
 	}
 
-	cfg.logger.Info("Finding volumes...")
-	var pvcsPerNs map[string][]string
-	if *cfg.PVCName == "" {
-		var err error
-		pvcsPerNs, err = finder.FindPVCs(ctx, filter)
+	// With --cordon, the node is cordoned below instead
+	if node := targetNode(podFilter); node != "" && !*cfg.SkipUnschedulableCheck && !*cfg.Cordon {
+		unschedulable, err := finder.IsNodeUnschedulable(ctx, node)
 		if err != nil {
-			return err
+			return result, err
 		}
-		if len(pvcsPerNs) == 0 {
-			cfg.logger.Info("No matching PVCs found, nothing to do")
-			return nil
-		}
-	} else {
-		pvcsPerNs = map[string][]string{
-			*cfg.Namespace: {*cfg.PVCName},
+		if !unschedulable {
+			return result, newExitError(ExitCodeNodeSchedulable,
+				"Node %s is still schedulable; did you forget to run kubectl cordon?", node)
 		}
 	}
 
-	cfg.logger.Info("Finding pods...")
-	pods, err := finder.FindPodsUsingPVCs(ctx, pvcsPerNs)
+	sel, err := selectResources(ctx, cfg, finder, filter, podFilter, timer)
 	if err != nil {
-		return err
+		return result, err
 	}
-	if len(pods) == 0 {
-		cfg.logger.Info("No pods found, nothing to do")
-		return nil
+	result.setPVCs(sel.PVCs)
+	result.setPods(sel.Pods)
+	result.Controllers = sel.Controllers
+	result.Skipped = append(result.Skipped, sel.AlreadyScaled...)
+	if len(sel.Controllers) == 0 {
+		return result, nil
 	}
-	cfg.logger.Info("Found %d pods to scale down", len(pods))
+	pvcsPerNs, pods, podsByController, controllers := sel.PVCs, sel.Pods, sel.PodsByController, sel.Controllers
 
-	controllers, err := finder.FindControllers(ctx, pods)
-	if err != nil {
-		return err
+	if err := warnSharedPVCs(ctx, cfg.logger, finder, pods, pvcsPerNs); err != nil {
+		return result, err
 	}
-	if len(controllers) == 0 {
-		cfg.logger.Info("No controllers found to scale down")
-		return nil
+	if err := warnSinglePodPVCs(ctx, cfg.logger, finder, pvcsPerNs); err != nil {
+		return result, err
+	}
+	if *cfg.Replicas > 1 {
+		if err := warnSingleNodePVCs(ctx, cfg, finder, pvcsPerNs); err != nil {
+			return result, err
+		}
+	}
+	if *cfg.CheckCustomFinalizers {
+		warnCustomFinalizers(cfg.logger, pods)
 	}
+
 	cfg.logger.Info("Found %d controllers to scale down", len(controllers))
+	if node := targetNode(podFilter); node != "" {
+		cfg.logger.Info("%d volume(s) would be detached from node %s", len(discovery.PVCsUsedBy(pods, pvcsPerNs)), node)
+	}
+	if *cfg.DryRun {
+		if podFilter.LabelSelector != "" {
+			cfg.logger.Info("  (dry-run, pods limited to label selector: %s)", podFilter.LabelSelector)
+		}
+		if podFilter.FieldSelector != "" {
+			cfg.logger.Info("  (dry-run, pods limited to field selector: %s)", podFilter.FieldSelector)
+		}
+	}
+
+	excluded := make(map[common.ControllerRef]bool)
+	protected := make(map[common.ControllerRef]bool)
+	for _, ctrl := range controllers {
+		if isExcluded(cfg, ctrl) {
+			excluded[ctrl] = true
+			continue
+		}
+		if *cfg.ProtectAnnotation == "" {
+			continue
+		}
+		isProtected, err := finder.IsProtected(ctx, ctrl, podsByController[ctrl], *cfg.ProtectAnnotation)
+		if err != nil {
+			return result, err
+		}
+		if isProtected {
+			cfg.logger.Info("%v is protected by its %s annotation, it won't be scaled down", ctrl, *cfg.ProtectAnnotation)
+			// Protected controllers are left alone just like excluded ones
+			excluded[ctrl], protected[ctrl] = true, true
+		}
+	}
+
+	blockingPDBs, err := findBlockingPDBs(ctx, cfg, finder, podsByController, excluded)
+	if err != nil {
+		return result, err
+	}
+
+	if *cfg.Output == OutputIstioVSPatch {
+		patches, err := istioPatches(ctx, cfg, clientset, finder, istioClient, controllers, podsByController)
+		if err != nil {
+			return result, err
+		}
+		if patches == nil {
+			patches = []istio.Patch{}
+		}
+		return result, printJSON(cfg.out, patches)
+	}
 
 	// Print the affected controllers on stdout (other logs are on stderr)
-	for _, controller := range controllers {
-		_, _ = fmt.Fprintf(cfg.out, "  %v\n", controller)
+	if *cfg.Output == OutputNDJSON || *cfg.Output == OutputJSONLines {
+		if err := printControllerLines(cfg.out, cfg.contextName, controllers, podsByController, pvcsPerNs, excluded, protected, blockingPDBs); err != nil {
+			return result, err
+		}
+	} else if *cfg.Output == OutputAnsibleInventory {
+		if err := printAnsibleInventory(ctx, cfg.out, finder, controllers, podsByController, pvcsPerNs, excluded, blockingPDBs); err != nil {
+			return result, err
+		}
+	} else if *cfg.DryRun && *cfg.DryRunDiff {
+		if err := printDiff(ctx, cfg.out, finder, controllers, int32(*cfg.Replicas), excluded, blockingPDBs); err != nil {
+			return result, err
+		}
+	} else if *cfg.ByNode {
+		printByNode(cfg.out, cfg.qualify, controllers, podsByController, pvcsPerNs, excluded, protected, blockingPDBs)
+	} else {
+		for _, controller := range controllers {
+			pvcs := triggerPVCs(podsByController[controller], pvcsPerNs)
+			_, _ = fmt.Fprintf(cfg.out, "  %s\n", cfg.qualify(describeController(controller, pvcs, excluded, protected, blockingPDBs)))
+		}
 	}
 
+	if len(excluded) > 0 {
+		for _, ctrl := range controllers {
+			if protected[ctrl] {
+				cfg.logger.Warn("Protected %v has %d pod(s) that will keep the targeted PVCs mounted", ctrl, len(podsByController[ctrl]))
+				result.Skipped = append(result.Skipped, ctrl)
+			} else if excluded[ctrl] {
+				cfg.logger.Warn("Excluded %v has %d pod(s) that will keep the targeted PVCs mounted", ctrl, len(podsByController[ctrl]))
+				result.Skipped = append(result.Skipped, ctrl)
+			}
+		}
+		controllers = slices.DeleteFunc(slices.Clone(controllers), func(ctrl common.ControllerRef) bool {
+			return excluded[ctrl]
+		})
+		if len(controllers) == 0 {
+			cfg.logger.Info("All controllers are excluded, nothing to do")
+			return result, nil
+		}
+	}
+	if err := checkMaxResources(cfg, len(controllers)); err != nil {
+		return result, err
+	}
+
+	if *cfg.CheckPDBViolations {
+		violations, err := finder.FindPDBViolations(ctx, podsOf(controllers, podsByController))
+		if err != nil {
+			return result, err
+		}
+		for _, v := range violations {
+			cfg.logger.Warn("Scale down would violate PodDisruptionBudget %s/%s (%d healthy, %d required, %d would be removed)",
+				v.PDB.Namespace, v.PDB.Name, v.PDB.Status.CurrentHealthy, v.PDB.Status.DesiredHealthy, v.Removed)
+		}
+		if len(violations) > 0 && !*cfg.DryRun && !*cfg.IgnorePDB && !*cfg.Force {
+			return result, fmt.Errorf("aborting, scale down would violate %d PodDisruptionBudget(s) (use --ignore-pdb or --force to override)", len(violations))
+		}
+	}
+
+	if *cfg.CheckAdmissionWebhooks {
+		if err := warnAdmissionWebhooks(ctx, cfg.logger, finder, controllers); err != nil {
+			return result, err
+		}
+	}
+
+	// Kept separate, so that waiting for the user doesn't count towards other phases
+	timer.phase("confirming")
+	reader := bufio.NewReader(cfg.in)
 	skipConfirmation := cfg.Confirmed != nil && *cfg.Confirmed
-	confirmed, err := confirmAction(cfg.logger, "Scale down the controllers listed above?", skipConfirmation)
+	if *cfg.Interactive && !skipConfirmation {
+		var declined []common.ControllerRef
+		controllers, declined, err = selectControllers(ctx, cfg.logger, reader, controllers)
+		if err != nil {
+			return result, err
+		}
+		result.Skipped = append(result.Skipped, declined...)
+		if len(controllers) == 0 {
+			cfg.logger.Info("No controllers selected, nothing to do")
+			return result, nil
+		}
+	} else {
+		confirmed, err := confirmScaleDown(ctx, cfg, reader, controllers)
+		if err != nil {
+			return result, err
+		}
+		if !confirmed {
+			cfg.logger.Info("Operation cancelled by user")
+			result.Cancelled = true
+			return result, nil
+		}
+	}
+
+	timer.phase("scaling down")
+	if *cfg.PatchIstioVS {
+		patches, err := istioPatches(ctx, cfg, clientset, finder, istioClient, controllers, podsByController)
+		if err != nil {
+			return result, err
+		}
+		for _, patch := range patches {
+			if *cfg.DryRun {
+				cfg.logger.Info("  (dry-run, skipping VirtualService patch: %s/%s)", patch.Namespace, patch.Name)
+				continue
+			}
+			if err := istioClient.Apply(ctx, patch); err != nil {
+				return result, err
+			}
+		}
+	}
+
+	scaler := newScaler(cfg, clientset, dynamicClient)
+	if *cfg.Cordon {
+		if err := cordonNodes(ctx, cfg, scaler, podsOf(controllers, podsByController), result); err != nil {
+			return result, err
+		}
+	}
+
+	if err := disableHPAs(ctx, cfg, finder, scaler, controllers, result); err != nil {
+		return result, err
+	}
+	if err := pauseScaledObjects(ctx, cfg, kedaClient, controllers, result); err != nil {
+		return result, err
+	}
+
+	cfg.logger.Info("Scaling down %d controller(s)...", len(controllers))
+	var replicasBefore map[common.ControllerRef]int32
+	if *cfg.GenerateRunbook != "" || *cfg.OutputFile != "" || *cfg.PlanFile != "" {
+		if replicasBefore, err = desiredReplicas(ctx, finder, controllers); err != nil {
+			return result, err
+		}
+	}
+	budget := newDisruptionBudget(cfg, finder, pvcsPerNs, podFilter)
+	scaleErrs := scaleDownAll(ctx, cfg, scaler, budget, controllers, podsByController, pvcsPerNs, blockingPDBs)
+	result.recordScaleDown(controllers, scaleErrs)
+	if *cfg.GenerateRunbook != "" {
+		if err := newRunbook(cfg, result, replicasBefore).write(*cfg.GenerateRunbook); err != nil {
+			return result, err
+		}
+		cfg.logger.Info("Wrote runbook to %s", *cfg.GenerateRunbook)
+	}
+	if *cfg.OutputFile != "" {
+		if err := writeRestoreFile(cfg, result, replicasBefore); err != nil {
+			return result, err
+		}
+		cfg.logger.Info("Wrote scaled down controllers to %s, restore them with --restore-from=%s", *cfg.OutputFile, *cfg.OutputFile)
+	}
+	if *cfg.PlanFile != "" {
+		if err := writePlanFile(cfg, result, replicasBefore, podsByController, pvcsPerNs); err != nil {
+			return result, err
+		}
+		cfg.logger.Info("Wrote plan to %s, apply it with --apply-plan=%s", *cfg.PlanFile, *cfg.PlanFile)
+	}
+	if ctx.Err() != nil {
+		logInterrupted(cfg.logger, result)
+		return result, fmt.Errorf("interrupted while scaling down: %w", ctx.Err())
+	}
+	if errs := slices.DeleteFunc(scaleErrs, func(err error) bool {
+		return err == nil || errors.Is(err, scaling.ErrNotScalable)
+	}); len(errs) > 0 {
+		return result, fmt.Errorf("encountered %d errors scaling down: %w", len(errs), errors.Join(errs...))
+	}
+
+	if !*cfg.DryRun {
+		timer.phase("waiting")
+		// Skipped controllers (e.g. custom resources without a scale subresource) keep their pods
+		if err := waitForScaleDown(ctx, cfg, finder, scaler, result.scaledDown(), podsByController, pvcsPerNs, podFilter); err != nil {
+			return result, err
+		}
+	}
+
+	cfg.logger.Info("Scale down complete: %d scaled down, %d skipped",
+		len(result.Scaled)+len(result.DeletedPods), len(result.Skipped))
+	if !*cfg.LogJSON {
+		cfg.logger.Info(result.summary())
+		if isSet(cfg.NamespaceSelector) {
+			for _, line := range result.namespaceSummaries() {
+				cfg.logger.Info("  %s", line)
+			}
+		}
+	}
+
+	if *cfg.DatadogMetrics {
+		pushMetrics(cfg, len(result.Scaled)+len(result.DeletedPods), len(discovery.PVCsUsedBy(pods, pvcsPerNs)))
+	}
+
+	return result, nil
+}
+
+// findPVCsInSelectedNamespaces finds the PVCs matching the filter in each namespace matching --namespace-selector.
+func findPVCsInSelectedNamespaces(ctx context.Context, cfg *ConfigFlags, finder discovery.Finder,
+	filter discovery.PVCFilter) (map[string][]string, error) {
+	namespaces, err := finder.FindNamespaces(ctx, *cfg.NamespaceSelector)
+	if err != nil {
+		return nil, err
+	}
+	if len(namespaces) == 0 {
+		cfg.logger.Info("No namespaces match --namespace-selector=%s", *cfg.NamespaceSelector)
+		return nil, nil
+	}
+	cfg.logger.Info("Namespaces matching --namespace-selector=%s: %s", *cfg.NamespaceSelector, strings.Join(namespaces, ", "))
+
+	pvcsPerNs := make(map[string][]string)
+	for _, ns := range namespaces {
+		filter.Namespace = ns
+		found, err := finder.FindPVCs(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		maps.Copy(pvcsPerNs, found)
+	}
+	return pvcsPerNs, nil
+}
+
+// newScaler creates a Scaler configured by the given flags.
+func newScaler(cfg *ConfigFlags, clientset kubernetes.Interface, dynamicClient dynamic.Interface) scaling.Scaler {
+	opts := scaling.Options{
+		DryRun:       *cfg.DryRun,
+		ServerDryRun: *cfg.DryRunServer,
+		UseEviction:  !*cfg.DisableEviction && !*cfg.Force,
+		Recorder:     cfg.recorder,
+		Annotations:  *cfg.ScaleAnnotations,
+		MaxRetries:   *cfg.MaxRetries,
+		Dynamic:      dynamicClient,
+		Replicas:     int32(*cfg.Replicas),
+	}
+	if *cfg.GracePeriod >= 0 {
+		opts.GracePeriod = cfg.GracePeriod
+	}
+	return scaling.New(clientset, cfg.logger, opts)
+}
+
+// cordonNodes cordons the nodes running the given pods, so that their replacements (e.g. from controllers
+// that weren't scaled down) don't land on them and re-mount the PVCs.
+func cordonNodes(ctx context.Context, cfg *ConfigFlags, scaler scaling.Scaler, pods []corev1.Pod, result *Result) error {
+	nodes := discovery.NodesOf(pods)
+	cfg.logger.Info("Cordoning %d node(s)...", len(nodes))
+	for _, node := range nodes {
+		cordoned, err := scaler.Cordon(ctx, node)
+		if err != nil {
+			return err
+		}
+		if cordoned {
+			result.CordonedNodes = append(result.CordonedNodes, node)
+		}
+	}
+	return nil
+}
+
+// findBlockingPDBs finds the PodDisruptionBudget (if any) that scaling down each of the controllers (other than
+// the excluded ones) would violate, unless --ignore-pdb or --force is set.
+func findBlockingPDBs(ctx context.Context, cfg *ConfigFlags, finder discovery.Finder, podsByController map[common.ControllerRef][]corev1.Pod,
+	excluded map[common.ControllerRef]bool) (map[common.ControllerRef]*policyv1.PodDisruptionBudget, error) {
+	blockingPDBs := make(map[common.ControllerRef]*policyv1.PodDisruptionBudget)
+	if *cfg.IgnorePDB || *cfg.Force {
+		return blockingPDBs, nil
+	}
+	for ctrl, ctrlPods := range podsByController {
+		if excluded[ctrl] {
+			continue
+		}
+		pdb, err := finder.FindBlockingPDB(ctx, ctrl.Namespace, ctrlPods)
+		if err != nil {
+			return nil, err
+		}
+		if pdb != nil {
+			blockingPDBs[ctrl] = pdb
+		}
+	}
+	return blockingPDBs, nil
+}
+
+// disableHPAs keeps the HorizontalPodAutoscalers targeting the given controllers from scaling them back up,
+// unless --leave-hpa is set.
+func disableHPAs(ctx context.Context, cfg *ConfigFlags, finder discovery.Finder, scaler scaling.Scaler,
+	controllers []common.ControllerRef, result *Result) error {
+	for _, ctrl := range controllers {
+		hpa, err := finder.FindHPA(ctx, ctrl)
+		if err != nil {
+			return err
+		}
+		if hpa == nil {
+			continue
+		}
+		if *cfg.Replicas > 0 {
+			cfg.logger.Warn("HorizontalPodAutoscaler %s/%s is still active, and may scale %v back up from --replicas=%d",
+				hpa.Namespace, hpa.Name, ctrl, *cfg.Replicas)
+			continue
+		}
+		if *cfg.LeaveHPA {
+			if hpa.Spec.MinReplicas != nil && *hpa.Spec.MinReplicas == 0 {
+				cfg.logger.Warn("HorizontalPodAutoscaler %s/%s scales to zero and may scale %v back up", hpa.Namespace, hpa.Name, ctrl)
+			}
+			continue
+		}
+		disabled, err := scaler.DisableHPA(ctx, hpa)
+		if err != nil {
+			return err
+		}
+		if disabled {
+			result.DisabledHPAs = append(result.DisabledHPAs, fmt.Sprintf("%s/%s", hpa.Namespace, hpa.Name))
+		}
+	}
+	return nil
+}
+
+// uncordonNodes reverses --cordon, uncordoning the nodes cordoned by kubectl-unmount (only the one
+// given with --node, if set).
+func uncordonNodes(ctx context.Context, cfg *ConfigFlags, finder discovery.Finder, scaler scaling.Scaler, result *Result) error {
+	nodes, err := finder.FindCordonedNodes(ctx, *cfg.NodeName)
 	if err != nil {
 		return err
 	}
-	if !confirmed {
-		cfg.logger.Info("Operation cancelled by user")
+	if len(nodes) == 0 {
+		cfg.logger.Info("No nodes cordoned by kubectl-unmount found, nothing to do")
 		return nil
 	}
+	cfg.logger.Info("Uncordoning %d node(s)...", len(nodes))
+	for _, node := range nodes {
+		if err := scaler.Uncordon(ctx, node); err != nil {
+			return err
+		}
+		result.UncordonedNodes = append(result.UncordonedNodes, node.Name)
+	}
+	return nil
+}
 
-	cfg.logger.Info("Scaling down %d controller(s)...", len(controllers))
-	scaler := scaling.New(clientset, cfg.logger, *cfg.DryRun)
-	errors := 0
-	for _, ctrl := range controllers {
-		if err := scaler.ScaleDown(ctx, ctrl); err != nil {
-			cfg.logger.Error(err)
-			errors++
-			// Continue with other controllers even if one fails
+// scaleDownAll scales down the given controllers, running up to --concurrency operations at a time (and waiting
+// for pods to terminate in between, if the disruption budget is exhausted).
+// It continues with other controllers even if one fails (but stops once the context is cancelled), and returns the error (if any) encountered
+// for each controller, in the same order as the controllers.
+func scaleDownAll(ctx context.Context, cfg *ConfigFlags, scaler scaling.Scaler, budget *disruptionBudget, controllers []common.ControllerRef,
+	podsByController map[common.ControllerRef][]corev1.Pod, pvcsPerNs map[string][]string,
+	blockingPDBs map[common.ControllerRef]*policyv1.PodDisruptionBudget) []error {
+	results := make([]error, len(controllers))
+	progress := spinner.NewProgress(cfg.out, "Scaling down %d/%d controllers...", len(controllers))
+	defer progress.Stop()
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for range *cfg.Concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				ctrl := controllers[i]
+				if ctx.Err() != nil {
+					// Don't start any more operations once interrupted
+					results[i] = errInterrupted
+					continue
+				}
+				if pdb, ok := blockingPDBs[ctrl]; ok && !*cfg.DryRun {
+					results[i] = fmt.Errorf("refusing to scale down %v, it would violate PodDisruptionBudget %s/%s (use --ignore-pdb or --force to override)",
+						ctrl, pdb.Namespace, pdb.Name)
+				} else if err := budget.acquire(ctx, ctrl, podsByController[ctrl]); err != nil {
+					results[i] = err
+					if ctx.Err() != nil {
+						results[i] = errInterrupted
+					}
+				} else {
+					var changed bool
+					changed, results[i] = scaler.ScaleDown(ctx, ctrl, triggerPVCs(podsByController[ctrl], pvcsPerNs))
+					if !changed {
+						// Its pods won't terminate, so they mustn't hold up other controllers
+						budget.release(ctrl)
+					}
+				}
+				if results[i] == nil && ctrl.Kind != common.KindPod {
+					results[i] = scaler.OverrideGracePeriod(ctx, podsByController[ctrl])
+				}
+				if errors.Is(results[i], scaling.ErrNotScalable) {
+					cfg.logger.Warn("%v, skipping", results[i])
+				} else if results[i] != nil {
+					cfg.logger.Error(results[i])
+				}
+				progress.Increment()
+			}
+		}()
+	}
+	for i := range controllers {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+
+	return results
+}
+
+func printJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	return nil
+}
+
+// logVolumeMounts logs where each container of the given pods mounts the targeted PVCs.
+func logVolumeMounts(log *logger.Logger, pods []corev1.Pod, pvcsPerNs map[string][]string) {
+	for _, pod := range pods {
+		for _, mount := range discovery.VolumeMounts(pod, pvcsPerNs[pod.Namespace]) {
+			log.Info("  %s/%s -> %s (PVC %s/%s)", pod.Name, mount.Container, mount.Path, pod.Namespace, mount.PVC)
 		}
 	}
+}
 
-	if errors > 0 {
-		return fmt.Errorf("encountered %d errors scaling down", errors)
+// logInterrupted logs which controllers were already modified when the run was interrupted, so that the
+// operator knows where it stopped.
+func logInterrupted(log *logger.Logger, result *Result) {
+	log.Warn("Interrupted, no more controllers will be scaled down")
+	for _, ctrl := range slices.Concat(result.Scaled, result.DeletedPods) {
+		log.Warn("  Already scaled down: %v", ctrl)
+	}
+	for _, ctrl := range result.Failed {
+		log.Warn("  Failed or interrupted while scaling down: %v", ctrl)
 	}
+}
 
-	if !*cfg.DryRun {
-		<-spinner.Wait("Waiting for pods to scale down... ", func() (bool, error) {
-			pods, err := finder.FindPodsUsingPVCs(ctx, pvcsPerNs)
+// controllerLine is a line of JSON Lines output describing an affected controller.
+type controllerLine struct {
+	// Context is the kubeconfig context the controller is in, when running in several.
+	Context   string `json:"context,omitempty"`
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	// TriggeringPVC lists the targeted PVCs used by the controller's pods, separated by commas.
+	TriggeringPVC string `json:"triggeringPVC"`
+	Excluded      bool   `json:"excluded,omitempty"`
+	// Protected is set for controllers protected by --protect-annotation, which are also excluded.
+	Protected    bool   `json:"protected,omitempty"`
+	BlockedByPDB string `json:"blockedByPDB,omitempty"`
+}
+
+// printControllerLines prints the affected controllers as JSON Lines, with one JSON object per controller.
+func printControllerLines(w io.Writer, contextName string, controllers []common.ControllerRef,
+	podsByController map[common.ControllerRef][]corev1.Pod, pvcsPerNs map[string][]string,
+	excluded, protected map[common.ControllerRef]bool, blockingPDBs map[common.ControllerRef]*policyv1.PodDisruptionBudget) error {
+	enc := json.NewEncoder(w)
+	for _, ctrl := range controllers {
+		line := controllerLine{
+			Context:       contextName,
+			Kind:          ctrl.Kind,
+			Namespace:     ctrl.Namespace,
+			Name:          ctrl.Name,
+			TriggeringPVC: strings.Join(triggerPVCs(podsByController[ctrl], pvcsPerNs), ","),
+			Excluded:      excluded[ctrl],
+			Protected:     protected[ctrl],
+		}
+		if pdb, ok := blockingPDBs[ctrl]; ok {
+			line.BlockedByPDB = fmt.Sprintf("%s/%s", pdb.Namespace, pdb.Name)
+		}
+		if err := enc.Encode(line); err != nil {
+			return fmt.Errorf("failed to write output: %w", err)
+		}
+	}
+	return nil
+}
+
+// printDiff prints how each of the affected controllers would change, e.g. "Deployment/ns/name: 3 -> 0"
+// or "Pod/ns/name: running -> deleted".
+func printDiff(ctx context.Context, w io.Writer, finder discovery.Finder, controllers []common.ControllerRef, target int32,
+	excluded map[common.ControllerRef]bool, blockingPDBs map[common.ControllerRef]*policyv1.PodDisruptionBudget) error {
+	for _, ctrl := range controllers {
+		var before, after string
+		switch ctrl.Kind {
+		case common.KindPod:
+			before, after = "running", "deleted"
+		case common.KindJob, common.KindCronJob:
+			before, after = "active", "suspended"
+		default:
+			replicas, ok, err := finder.DesiredReplicas(ctx, ctrl)
 			if err != nil {
-				return false, err
+				return err
+			}
+			if !ok {
+				_, _ = fmt.Fprintf(w, "  %v: unchanged (cannot be scaled down)\n", ctrl)
+				continue
+			}
+			before, after = strconv.Itoa(int(replicas)), strconv.Itoa(int(min(replicas, target)))
+		}
+
+		if excluded[ctrl] {
+			_, _ = fmt.Fprintf(w, "  %v: %s -> %s (excluded)\n", ctrl, before, before)
+		} else if pdb, ok := blockingPDBs[ctrl]; ok {
+			_, _ = fmt.Fprintf(w, "  %v: %s -> %s (blocked by PodDisruptionBudget %s/%s)\n", ctrl, before, before, pdb.Namespace, pdb.Name)
+		} else {
+			_, _ = fmt.Fprintf(w, "  %v: %s -> %s\n", ctrl, before, after)
+		}
+	}
+	return nil
+}
+
+// ansibleInventory is an Ansible dynamic inventory, in the JSON format expected from inventory scripts.
+type ansibleInventory struct {
+	All  ansibleGroup `json:"all"`
+	Meta struct {
+		HostVars map[string]ansibleHostVars `json:"hostvars"`
+	} `json:"_meta"`
+}
+
+type ansibleGroup struct {
+	Hosts []string `json:"hosts"`
+}
+
+type ansibleHostVars struct {
+	Kind           string `json:"kind"`
+	Namespace      string `json:"namespace"`
+	Name           string `json:"name"`
+	TriggeringPVC  string `json:"triggering_pvc"`
+	ReplicasBefore *int32 `json:"replicas_before,omitempty"`
+	Excluded       bool   `json:"excluded,omitempty"`
+	BlockedByPDB   string `json:"blocked_by_pdb,omitempty"`
+}
+
+// printAnsibleInventory prints the affected controllers as an Ansible dynamic inventory, with one host per
+// controller (named like "Deployment/ns/name"), so that playbooks can target them after they're scaled down.
+func printAnsibleInventory(ctx context.Context, w io.Writer, finder discovery.Finder, controllers []common.ControllerRef,
+	podsByController map[common.ControllerRef][]corev1.Pod, pvcsPerNs map[string][]string,
+	excluded map[common.ControllerRef]bool, blockingPDBs map[common.ControllerRef]*policyv1.PodDisruptionBudget) error {
+	inventory := ansibleInventory{All: ansibleGroup{Hosts: []string{}}}
+	inventory.Meta.HostVars = make(map[string]ansibleHostVars)
+	for _, ctrl := range controllers {
+		vars := ansibleHostVars{
+			Kind:          ctrl.Kind,
+			Namespace:     ctrl.Namespace,
+			Name:          ctrl.Name,
+			TriggeringPVC: strings.Join(triggerPVCs(podsByController[ctrl], pvcsPerNs), ","),
+			Excluded:      excluded[ctrl],
+		}
+		replicas, ok, err := finder.DesiredReplicas(ctx, ctrl)
+		if err != nil {
+			return err
+		}
+		if ok {
+			vars.ReplicasBefore = &replicas
+		}
+		if pdb, ok := blockingPDBs[ctrl]; ok {
+			vars.BlockedByPDB = fmt.Sprintf("%s/%s", pdb.Namespace, pdb.Name)
+		}
+		inventory.All.Hosts = append(inventory.All.Hosts, ctrl.String())
+		inventory.Meta.HostVars[ctrl.String()] = vars
+	}
+	return printJSON(w, inventory)
+}
+
+// describeController describes an affected controller (and the targeted PVCs its pods use) for the text output.
+func describeController(ctrl common.ControllerRef, pvcs []string, excluded, protected map[common.ControllerRef]bool,
+	blockingPDBs map[common.ControllerRef]*policyv1.PodDisruptionBudget) string {
+	line := fmt.Sprintf("%v (PVC: %s)", ctrl, strings.Join(pvcs, ","))
+	if protected[ctrl] {
+		return line + " (protected)"
+	}
+	if excluded[ctrl] {
+		return line + " (excluded)"
+	}
+	if pdb, ok := blockingPDBs[ctrl]; ok {
+		return fmt.Sprintf("%s (blocked by PodDisruptionBudget %s/%s)", line, pdb.Namespace, pdb.Name)
+	}
+	return line
+}
+
+// printByNode prints the affected controllers (with --by-node) grouped by the nodes their pods run on, along with
+// the PVCs that each node would release. PVCs of excluded or PDB-blocked controllers stay mounted, so they aren't
+// released. Pods that aren't scheduled to a node have nothing attached, so they're left out.
+func printByNode(w io.Writer, qualify func(string) string, controllers []common.ControllerRef, podsByController map[common.ControllerRef][]corev1.Pod,
+	pvcsPerNs map[string][]string, excluded, protected map[common.ControllerRef]bool,
+	blockingPDBs map[common.ControllerRef]*policyv1.PodDisruptionBudget) {
+	for _, node := range discovery.NodesOf(podsOf(controllers, podsByController)) {
+		var lines []string
+		var released []corev1.Pod
+		for _, ctrl := range controllers {
+			onNode := slices.DeleteFunc(slices.Clone(podsByController[ctrl]), func(pod corev1.Pod) bool {
+				return pod.Spec.NodeName != node
+			})
+			if len(onNode) == 0 {
+				continue
+			}
+			lines = append(lines, describeController(ctrl, triggerPVCs(onNode, pvcsPerNs), excluded, protected, blockingPDBs))
+			if _, blocked := blockingPDBs[ctrl]; !excluded[ctrl] && !blocked {
+				released = append(released, onNode...)
 			}
-			return len(pods) == 0, nil
-		}, func(err error) {
-			cfg.logger.Error(err)
-		}, 2*time.Second)
+		}
+		pvcs := discovery.PVCsUsedBy(released, pvcsPerNs)
+		_, _ = fmt.Fprintf(w, "Node %s would release %d PVC(s): %s\n", qualify(node), len(pvcs), strings.Join(pvcs, ", "))
+		for _, line := range lines {
+			_, _ = fmt.Fprintf(w, "  %s\n", qualify(line))
+		}
 	}
+}
 
-	cfg.logger.Info("Scale down complete")
+// triggerPVCs returns the names of the targeted PVCs used by the given pods, which are all in the same namespace.
+func triggerPVCs(pods []corev1.Pod, pvcsPerNs map[string][]string) []string {
+	var names []string
+	for _, pvc := range discovery.PVCsUsedBy(pods, pvcsPerNs) {
+		_, name, _ := strings.Cut(pvc, "/")
+		names = append(names, name)
+	}
+	return names
+}
 
+// desiredReplicas gets the number of replicas of each of the given controllers that have one.
+func desiredReplicas(ctx context.Context, finder discovery.Finder, controllers []common.ControllerRef) (map[common.ControllerRef]int32, error) {
+	replicas := make(map[common.ControllerRef]int32)
+	for _, ctrl := range controllers {
+		n, ok, err := finder.DesiredReplicas(ctx, ctrl)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			replicas[ctrl] = n
+		}
+	}
+	return replicas, nil
+}
+
+// podsOf returns all the pods owned by the given controllers.
+func podsOf(controllers []common.ControllerRef, podsByController map[common.ControllerRef][]corev1.Pod) []corev1.Pod {
+	var pods []corev1.Pod
+	for _, ctrl := range controllers {
+		pods = append(pods, podsByController[ctrl]...)
+	}
+	return pods
+}
+
+// logCloudVolumes logs the cloud provider's identifier for the volume backing each of the given PVCs, so that they
+// can be cross-referenced with the provider's storage dashboards.
+func logCloudVolumes(ctx context.Context, cfg *ConfigFlags, finder discovery.Finder, pvcsPerNs map[string][]string) error {
+	volumes, err := finder.FindCloudVolumes(ctx, pvcsPerNs, *cfg.CloudProvider)
+	if err != nil {
+		return err
+	}
+	for _, pvc := range slices.Sorted(maps.Keys(volumes)) {
+		cfg.logger.Info("  PVC %s is backed by %s volume %s", pvc, *cfg.CloudProvider, volumes[pvc])
+	}
+	return nil
+}
+
+// pushMetrics pushes gauges describing the operation to the DogStatsD server. Failures are only logged,
+// since the scale down itself has already succeeded.
+func pushMetrics(cfg *ConfigFlags, controllersScaled, pvcsFreed int) {
+	tags := []string{fmt.Sprintf("dry_run:%t", *cfg.DryRun)}
+	if cfg.Namespace != nil && *cfg.Namespace != "" {
+		tags = append([]string{fmt.Sprintf("namespace:%s", *cfg.Namespace)}, tags...)
+	}
+
+	err := metrics.Push(*cfg.StatsdAddress,
+		metrics.Gauge{Name: "kubectl_unmount.controllers_scaled", Value: controllersScaled, Tags: tags},
+		metrics.Gauge{Name: "kubectl_unmount.pvcs_freed", Value: pvcsFreed, Tags: tags},
+	)
+	if err != nil {
+		cfg.logger.Warn("Failed to push metrics to %s: %v", *cfg.StatsdAddress, err)
+	}
+}
+
+// targetNode returns the name of the node that the pod filter is limited to, if any.
+func targetNode(filter discovery.PodFilter) string {
+	if filter.FieldSelector == "" {
+		return ""
+	}
+	selector, err := fields.ParseSelector(filter.FieldSelector)
+	if err != nil {
+		return ""
+	}
+	node, _ := selector.RequiresExactMatch("spec.nodeName")
+	return node
+}
+
+// warnAdmissionWebhooks warns about admission webhooks that intercept the requests made to scale down the
+// given controllers, since they could reject them.
+func warnAdmissionWebhooks(ctx context.Context, log *logger.Logger, finder discovery.Finder, controllers []common.ControllerRef) error {
+	var kinds []string
+	for _, ctrl := range controllers {
+		if !slices.Contains(kinds, ctrl.Kind) {
+			kinds = append(kinds, ctrl.Kind)
+		}
+	}
+	webhooks, err := finder.FindAdmissionWebhooks(ctx, kinds)
+	if err != nil {
+		return err
+	}
+	for _, webhook := range webhooks {
+		log.Warn("Admission webhook %v may intercept (and reject) scaling down", webhook)
+	}
+	if len(webhooks) > 0 {
+		log.Warn("Use --dry-run=server to check whether the webhooks allow scaling down")
+	}
 	return nil
 }
 
-// confirmAction prompts the user to confirm an action by typing "yes".
-// Returns true if the user confirms, false otherwise.
-func confirmAction(log *logger.Logger, prompt string, skipConfirmation bool) (bool, error) {
-	if skipConfirmation {
-		return true, nil
+// warnSharedPVCs warns about ReadWriteMany PVCs, which stay attached until every pod mounting them has stopped,
+// so scaling down any one of their consumers doesn't free them.
+func warnSharedPVCs(ctx context.Context, log *logger.Logger, finder discovery.Finder, pods []corev1.Pod,
+	pvcsPerNs map[string][]string) error {
+	shared, err := finder.FindSharedPVCs(ctx, pvcsPerNs)
+	if err != nil {
+		return err
 	}
+	for _, pvc := range shared {
+		ns, name, _ := strings.Cut(pvc, "/")
+		log.Warn("PVC %s is ReadWriteMany and has %d consumer(s): all of them will be scaled down, "+
+			"but the volume is only detached once the last one stops", pvc, discovery.CountPodsUsingPVC(pods, ns, name))
+	}
+	return nil
+}
 
-	log.Instructions("%s\nType 'yes' to continue: ", prompt)
+// warnSinglePodPVCs warns about ReadWriteOncePod PVCs, which only one pod in the whole cluster can use at a time.
+// Scaling down that pod frees them, but a new pod can't attach them until the old VolumeAttachment is deleted.
+func warnSinglePodPVCs(ctx context.Context, log *logger.Logger, finder discovery.Finder,
+	pvcsPerNs map[string][]string) error {
+	singlePod, err := finder.FindPVCsWithAccessMode(ctx, pvcsPerNs, corev1.ReadWriteOncePod)
+	if err != nil {
+		return err
+	}
+	for _, pvc := range singlePod {
+		log.Warn("PVC %s is ReadWriteOncePod, so only one pod can use it: scaling down its consumer frees it, "+
+			"but it can't be re-attached while the old pod's VolumeAttachment still exists", pvc)
+	}
+	return nil
+}
 
-	reader := bufio.NewReader(os.Stdin)
-	response, err := reader.ReadString('\n')
+// warnSingleNodePVCs warns about ReadWriteOnce PVCs when scaling down to several --replicas, since they can only be
+// mounted from a single node: replicas scheduled on other nodes get stuck waiting for the volume.
+func warnSingleNodePVCs(ctx context.Context, cfg *ConfigFlags, finder discovery.Finder, pvcsPerNs map[string][]string) error {
+	singleNode, err := finder.FindPVCsWithAccessMode(ctx, pvcsPerNs, corev1.ReadWriteOnce)
 	if err != nil {
-		return false, fmt.Errorf("failed to read user input: %w", err)
+		return err
+	}
+	for _, pvc := range singleNode {
+		cfg.logger.Warn("PVC %s is ReadWriteOnce, so the %d replicas left by --replicas can't mount it at the same "+
+			"time unless they're on the same node", pvc, *cfg.Replicas)
 	}
+	return nil
+}
 
-	response = strings.TrimSpace(strings.ToLower(response))
-	return response == "yes", nil
+// warnCustomFinalizers warns about any non-standard finalizers on the given pods, which
+// could block them from terminating after being scaled down.
+func warnCustomFinalizers(log *logger.Logger, pods []corev1.Pod) {
+	found := false
+	for _, pod := range pods {
+		finalizers := discovery.CustomFinalizers(pod)
+		if len(finalizers) == 0 {
+			continue
+		}
+		found = true
+		log.Warn("Pod %s/%s has custom finalizers that may block termination: %s",
+			pod.Namespace, pod.Name, strings.Join(finalizers, ", "))
+	}
+	if found {
+		log.Warn("Use --remove-custom-finalizer to remove them if pods get stuck terminating")
+	}
+}
+
+const (
+	// OutputIstioVSPatch prints the Istio VirtualService patches that would redirect traffic away from
+	// the affected controllers, instead of scaling them down.
+	OutputIstioVSPatch = "istio-vs-patch"
+	// OutputNDJSON prints the affected controllers as newline-delimited JSON, one object per line.
+	OutputNDJSON = "ndjson"
+	// OutputJSONLines is an alias for OutputNDJSON.
+	OutputJSONLines = "jsonlines"
+	// OutputAnsibleInventory prints the affected controllers as an Ansible dynamic inventory.
+	OutputAnsibleInventory = "ansible-inventory"
+)
+
+var outputFormats = []string{"", OutputIstioVSPatch, OutputNDJSON, OutputJSONLines, OutputAnsibleInventory}
+
+// validateSelection checks that the PVCs to unmount are selected unambiguously: by exactly one of --pvc, --pv,
+// or --storage-class, or else explicitly scoped to all PVCs in a namespace or on a node. Accidentally matching
+// every PVC could be catastrophic, so this fails fast instead.
+func validateSelection(cfg *ConfigFlags) error {
+	if cfg.Uncordon != nil && *cfg.Uncordon || isSet(cfg.RestoreFrom) {
+		// Nothing is unmounted, only what a previous run did is reversed
+		return nil
+	}
+	if isSet(cfg.ApplyPlan) {
+		// The controllers to scale down were already selected by the run that wrote the plan
+		return nil
+	}
+	var selected []string
+	if cfg.PVCName != nil && len(*cfg.PVCName) > 0 {
+		selected = append(selected, "--pvc")
+	}
+	if isSet(cfg.PVName) {
+		selected = append(selected, "--pv")
+	}
+	if cfg.StorageClass != nil && len(*cfg.StorageClass) > 0 {
+		selected = append(selected, "--storage-class")
+	}
+	if isSet(cfg.PVCRegex) {
+		selected = append(selected, "--pvc-regex")
+	}
+	if cfg.FromStdin != nil && *cfg.FromStdin {
+		selected = append(selected, "--from-stdin")
+	}
+
+	switch {
+	case len(selected) > 1:
+		return fmt.Errorf("must specify exactly one of --pvc, --pv, --storage-class, --pvc-regex, --from-stdin, got %s", strings.Join(selected, " and "))
+	case len(selected) == 0 && !isSet(cfg.Namespace) && !isSet(cfg.NamespaceSelector) && !isSet(cfg.NodeName):
+		return errors.New("must specify exactly one of --pvc, --pv, --storage-class, --pvc-regex, --from-stdin (or unmount all PVCs in " +
+			"a namespace, in labeled namespaces, or on a node with --namespace, --namespace-selector, or --node)")
+	}
+	if isSet(cfg.NamespaceSelector) {
+		if isSet(cfg.Namespace) {
+			return errors.New("--namespace-selector and --namespace can't be used together")
+		}
+		// These select PVCs by name (or from a literal list), not by searching namespaces
+		for _, flag := range []string{"--pvc", "--pv", "--from-stdin"} {
+			if slices.Contains(selected, flag) {
+				return fmt.Errorf("--namespace-selector can't be used together with %s", flag)
+			}
+		}
+		if _, err := labels.Parse(*cfg.NamespaceSelector); err != nil {
+			return fmt.Errorf("invalid --namespace-selector: %w", err)
+		}
+	}
+	if isSet(cfg.PVCRegex) {
+		if _, err := regexp.Compile(*cfg.PVCRegex); err != nil {
+			return fmt.Errorf("invalid --pvc-regex: %w", err)
+		}
+	}
+	return nil
+}
+
+// parseAnnotationSelector parses an --annotation-selector, formatted as comma-separated key=value pairs.
+func parseAnnotationSelector(selector string) (map[string]string, error) {
+	annotations := make(map[string]string)
+	for _, pair := range strings.Split(selector, ",") {
+		key, value, found := strings.Cut(pair, "=")
+		if !found || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("invalid --annotation-selector %q, must be formatted as key=value[,key=value...]", selector)
+		}
+		annotations[strings.TrimSpace(key)] = value
+	}
+	return annotations, nil
+}
+
+func isSet(flag *string) bool {
+	return flag != nil && *flag != ""
+}
+
+var podPhases = []corev1.PodPhase{
+	corev1.PodPending,
+	corev1.PodRunning,
+	corev1.PodSucceeded,
+	corev1.PodFailed,
+	corev1.PodUnknown,
+}
+
+// validate checks the provided flags for errors that can be detected without talking to the API server.
+func validate(cfg *ConfigFlags) error {
+	if err := validateSelection(cfg); err != nil {
+		return err
+	}
+	if cfg.OnlyControllers != nil && *cfg.OnlyControllers && cfg.OnlyPods != nil && *cfg.OnlyPods {
+		return errors.New("--only-controllers and --only-pods can't be used together")
+	}
+	if cfg.Cordon != nil && *cfg.Cordon && cfg.Uncordon != nil && *cfg.Uncordon {
+		return errors.New("--cordon and --uncordon can't be used together")
+	}
+	if isSet(cfg.RestoreFrom) && (cfg.Uncordon != nil && *cfg.Uncordon || isSet(cfg.OutputFile)) {
+		return errors.New("--restore-from can't be used together with --uncordon or --output-file")
+	}
+	if isSet(cfg.PlanFile) && !(cfg.DryRun != nil && *cfg.DryRun) {
+		return errors.New("--plan-file requires --dry-run, apply the plan afterwards with --apply-plan")
+	}
+	if isSet(cfg.ApplyPlan) && (cfg.Uncordon != nil && *cfg.Uncordon || isSet(cfg.RestoreFrom) || isSet(cfg.PlanFile)) {
+		return errors.New("--apply-plan can't be used together with --uncordon, --restore-from, or --plan-file")
+	}
+	if cfg.Output != nil && !slices.Contains(outputFormats, *cfg.Output) {
+		return fmt.Errorf("invalid output format %q, must be one of %v", *cfg.Output, outputFormats[1:])
+	}
+	usesIstio := cfg.PatchIstioVS != nil && *cfg.PatchIstioVS || cfg.Output != nil && *cfg.Output == OutputIstioVSPatch
+	if usesIstio && (cfg.IstioNamespace == nil || *cfg.IstioNamespace == "") {
+		return errors.New("--istio-namespace is required when generating or applying VirtualService patches")
+	}
+	if cfg.CleanAttachments != nil && *cfg.CleanAttachments && !(cfg.Wait != nil && *cfg.Wait) {
+		return errors.New("--clean-attachments requires --wait, so that attachments are only deleted once the controllers are scaled down")
+	}
+	if multipleContexts(cfg) {
+		if isSet(cfg.Context) {
+			return errors.New("--context can't be used together with --contexts or --all-contexts")
+		}
+		if cfg.Contexts != nil && len(*cfg.Contexts) > 0 && *cfg.AllContexts {
+			return errors.New("--contexts and --all-contexts can't be used together")
+		}
+		// These write a single file, or read stdin only once
+		if isSet(cfg.OutputFile) || isSet(cfg.RestoreFrom) || isSet(cfg.GenerateRunbook) || isSet(cfg.PlanFile) ||
+			isSet(cfg.ApplyPlan) || cfg.FromStdin != nil && *cfg.FromStdin {
+			return errors.New("--output-file, --restore-from, --generate-runbook, --plan-file, --apply-plan, and --from-stdin " +
+				"can't be used when running in several contexts")
+		}
+		// These print a single JSON document
+		if cfg.Output != nil && (*cfg.Output == OutputIstioVSPatch || *cfg.Output == OutputAnsibleInventory) {
+			return fmt.Errorf("--output=%s can't be used when running in several contexts", *cfg.Output)
+		}
+	}
+	if cfg.ByNode != nil && *cfg.ByNode && (isSet(cfg.Output) || cfg.DryRunDiff != nil && *cfg.DryRunDiff) {
+		return errors.New("--by-node can't be used together with --output or --dry-run=diff")
+	}
+	if cfg.FromStdin != nil && *cfg.FromStdin && !(cfg.Confirmed != nil && *cfg.Confirmed) && !(cfg.DryRun != nil && *cfg.DryRun) {
+		return errors.New("--from-stdin requires --yes or --dry-run, since stdin can't also be used to confirm")
+	}
+	if cfg.Watch != nil && *cfg.Watch {
+		if !(cfg.Confirmed != nil && *cfg.Confirmed) && !(cfg.DryRun != nil && *cfg.DryRun) {
+			return errors.New("--watch requires --yes or --dry-run, since nobody is there to confirm each scale down")
+		}
+		if multipleContexts(cfg) || cfg.FromStdin != nil && *cfg.FromStdin || isSet(cfg.PVName) || isSet(cfg.NamespaceSelector) ||
+			cfg.Interactive != nil && *cfg.Interactive {
+			return errors.New("--watch can't be used together with --contexts, --all-contexts, --from-stdin, --pv, " +
+				"--namespace-selector, or --interactive")
+		}
+		if cfg.WatchCooldown != nil && *cfg.WatchCooldown < 0 {
+			return fmt.Errorf("--watch-cooldown must not be negative, got %v", *cfg.WatchCooldown)
+		}
+	}
+	if isSet(cfg.AnnotationSelector) {
+		if _, err := parseAnnotationSelector(*cfg.AnnotationSelector); err != nil {
+			return err
+		}
+	}
+	if cfg.SkipControllers != nil {
+		for _, skipped := range *cfg.SkipControllers {
+			if _, _, _, err := parseSkippedController(skipped); err != nil {
+				return err
+			}
+		}
+	}
+	if isSet(cfg.AccessMode) {
+		if _, ok := discovery.AccessModes[*cfg.AccessMode]; !ok {
+			return fmt.Errorf("invalid access mode %q, must be one of %v", *cfg.AccessMode, slices.Sorted(maps.Keys(discovery.AccessModes)))
+		}
+	}
+	if isSet(cfg.MinSize) {
+		if _, err := resource.ParseQuantity(*cfg.MinSize); err != nil {
+			return fmt.Errorf("invalid --min-size %q, must be a quantity like 10Gi: %w", *cfg.MinSize, err)
+		}
+	}
+	if cfg.CloudProvider != nil && *cfg.CloudProvider != "" && !slices.Contains(discovery.CloudProviders, *cfg.CloudProvider) {
+		return fmt.Errorf("invalid cloud provider %q, must be one of %v", *cfg.CloudProvider, discovery.CloudProviders)
+	}
+	if cfg.ScaleAnnotations != nil {
+		for key := range *cfg.ScaleAnnotations {
+			if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+				return fmt.Errorf("invalid annotation key %q in --scale-annotations: %s", key, strings.Join(errs, "; "))
+			}
+			if strings.HasPrefix(key, "kubectl-unmount/") {
+				return fmt.Errorf("invalid annotation key %q in --scale-annotations, the kubectl-unmount/ prefix is reserved", key)
+			}
+		}
+	}
+	if cfg.Verbosity != nil && (*cfg.Verbosity < 0 || *cfg.Verbosity > verbosityBodies) {
+		return fmt.Errorf("--verbosity must be between 0 and %d, got %d", verbosityBodies, *cfg.Verbosity)
+	}
+	if cfg.MaxDisruptionBudget != nil && *cfg.MaxDisruptionBudget < 0 {
+		return fmt.Errorf("--max-disruption-budget must not be negative, got %d", *cfg.MaxDisruptionBudget)
+	}
+	if cfg.MaxResources != nil && *cfg.MaxResources < 0 {
+		return fmt.Errorf("--max-resources must not be negative, got %d", *cfg.MaxResources)
+	}
+	if cfg.Replicas != nil && *cfg.Replicas < 0 {
+		return fmt.Errorf("--replicas must not be negative, got %d", *cfg.Replicas)
+	}
+	if cfg.Replicas != nil && *cfg.Replicas > 0 &&
+		(cfg.VerifyDetach != nil && *cfg.VerifyDetach || cfg.CleanAttachments != nil && *cfg.CleanAttachments) {
+		return errors.New("--verify-detach and --clean-attachments can't be used with --replicas, since the " +
+			"remaining replicas keep the volumes attached")
+	}
+	if cfg.MaxRetries != nil && *cfg.MaxRetries < 0 {
+		return fmt.Errorf("--max-retries must not be negative, got %d", *cfg.MaxRetries)
+	}
+	if cfg.RateLimit != nil && *cfg.RateLimit < 0 {
+		return fmt.Errorf("--rate-limit must not be negative, got %v", *cfg.RateLimit)
+	}
+	if cfg.Burst != nil && *cfg.Burst < 1 {
+		return fmt.Errorf("--burst must be at least 1, got %d", *cfg.Burst)
+	}
+	if cfg.Concurrency != nil && *cfg.Concurrency < 1 {
+		return fmt.Errorf("--concurrency must be at least 1, got %d", *cfg.Concurrency)
+	}
+	if cfg.PodStatusFilter != nil {
+		for _, phase := range *cfg.PodStatusFilter {
+			if !slices.Contains(podPhases, corev1.PodPhase(phase)) {
+				return fmt.Errorf("invalid pod status %q, must be one of %v", phase, podPhases)
+			}
+		}
+	}
+	if cfg.Selector != nil && *cfg.Selector != "" {
+		if _, err := labels.Parse(*cfg.Selector); err != nil {
+			return fmt.Errorf("invalid label selector %q: %w", *cfg.Selector, err)
+		}
+	}
+	if cfg.FieldSelector != nil && *cfg.FieldSelector != "" {
+		selector, err := fields.ParseSelector(*cfg.FieldSelector)
+		if err != nil {
+			return fmt.Errorf("invalid field selector %q: %w", *cfg.FieldSelector, err)
+		}
+		if cfg.NodeName != nil && *cfg.NodeName != "" {
+			for _, req := range selector.Requirements() {
+				if req.Field == "spec.nodeName" {
+					return errors.New("cannot specify both --node and a spec.nodeName --field-selector")
+				}
+			}
+		}
+	}
+	return nil
 }
@@ -7,15 +7,18 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/dancavallaro/kubectl-unmount/pkg/common"
 	"github.com/dancavallaro/kubectl-unmount/pkg/logger"
 	"github.com/stretchr/testify/require"
 	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/utils/ptr"
 	"sigs.k8s.io/e2e-framework/klient"
 	"sigs.k8s.io/e2e-framework/klient/k8s"
@@ -117,45 +120,258 @@ func TestRunPlugin(t *testing.T) {
 		}).
 		Assess("Verify expected Pod is running", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
 			ns := ctx.Value("podNS").(string)
-			out, logs, err := runPlugin(func(cfg *ConfigFlags) {
+			_, out, logs, err := runPlugin(func(cfg *ConfigFlags) {
 				*cfg.DryRun = true
+				*cfg.Verbose = true
+				*cfg.Verbosity = 1
 				*cfg.Namespace = ns
 			})
 			require.NoError(t, err)
+			require.Contains(t, logs, fmt.Sprintf("GET /api/v1/namespaces/%s/persistentvolumeclaims: 200 OK", ns))
 			require.Contains(t, logs, "Found 1 pods to scale down")
+			require.Contains(t, logs, "test-pod/test-container -> /data")
 			require.Contains(t, logs, "Found 1 controllers to scale down")
-			require.ElementsMatch(t, []string{fmt.Sprintf("Pod/%s/test-pod", ns)}, out)
+			require.ElementsMatch(t, []string{fmt.Sprintf("Pod/%s/test-pod (PVC: test-pvc)", ns)}, out)
 			return ctx
 		}).
 		Assess("Verify expected Deployment Pod is running", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
 			ns := ctx.Value("deployNS").(string)
-			out, logs, err := runPlugin(func(cfg *ConfigFlags) {
+			_, out, logs, err := runPlugin(func(cfg *ConfigFlags) {
 				*cfg.DryRun = true
 				*cfg.Namespace = ns
 			})
 			require.NoError(t, err)
 			require.Contains(t, logs, "Found 1 pods to scale down")
 			require.Contains(t, logs, "Found 1 controllers to scale down")
-			require.ElementsMatch(t, []string{fmt.Sprintf("Deployment/%s/test-deployment", ns)}, out)
+			require.ElementsMatch(t, []string{fmt.Sprintf("Deployment/%s/test-deployment (PVC: test-pvc)", ns)}, out)
+			return ctx
+		}).
+		Assess("Print controllers as JSON Lines", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			ns := ctx.Value("podNS").(string)
+			for _, format := range []string{OutputNDJSON, OutputJSONLines} {
+				t.Run(format, func(t *testing.T) {
+					_, out, _, err := runPlugin(func(cfg *ConfigFlags) {
+						*cfg.DryRun = true
+						*cfg.Namespace = ns
+						*cfg.Output = format
+					})
+					require.NoError(t, err)
+					require.Equal(t, []string{fmt.Sprintf(`{"kind":"Pod","namespace":"%s","name":"test-pod","triggeringPVC":"test-pvc"}`, ns)}, out)
+				})
+			}
+			return ctx
+		}).
+		Assess("Print controllers as Ansible inventory", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			ns := ctx.Value("deployNS").(string)
+			_, out, _, err := runPlugin(func(cfg *ConfigFlags) {
+				*cfg.DryRun = true
+				*cfg.Namespace = ns
+				*cfg.Output = OutputAnsibleInventory
+			})
+			require.NoError(t, err)
+			host := fmt.Sprintf("Deployment/%s/test-deployment", ns)
+			require.JSONEq(t, fmt.Sprintf(`{
+				"all": {"hosts": [%q]},
+				"_meta": {"hostvars": {%q: {"kind": "Deployment", "namespace": %q, "name": "test-deployment", "triggering_pvc": "test-pvc", "replicas_before": 1}}}
+			}`, host, host, ns), strings.Join(out, ""))
+			return ctx
+		}).
+		Assess("Print dry-run diff", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			_, out, _, err := runPlugin(func(cfg *ConfigFlags) {
+				*cfg.DryRun = true
+				*cfg.DryRunDiff = true
+			})
+			require.NoError(t, err)
+			require.ElementsMatch(t, []string{
+				fmt.Sprintf("Pod/%s/test-pod: running -> deleted", ctx.Value("podNS").(string)),
+				fmt.Sprintf("Deployment/%s/test-deployment: 1 -> 0", ctx.Value("deployNS").(string)),
+			}, out)
+			return ctx
+		}).
+		Assess("Select Pod by PV name", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			ns := ctx.Value("podNS").(string)
+			pvc := &corev1.PersistentVolumeClaim{}
+			if err := cfg.Client().Resources().Get(ctx, "test-pvc", ns, pvc); err != nil {
+				t.Fatal(err)
+			}
+			_, out, logs, err := runPlugin(func(cfg *ConfigFlags) {
+				*cfg.DryRun = true
+				*cfg.PVName = pvc.Spec.VolumeName
+				cfg.StorageClass = &[]string{}
+			})
+			require.NoError(t, err)
+			require.Contains(t, logs, "Found 1 pods to scale down")
+			require.ElementsMatch(t, []string{fmt.Sprintf("Pod/%s/test-pod (PVC: test-pvc)", ns)}, out)
+			return ctx
+		}).
+		Assess("Exclude Pods on other nodes", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			ns := ctx.Value("podNS").(string)
+			pod := &corev1.Pod{}
+			if err := cfg.Client().Resources().Get(ctx, "test-pod", ns, pod); err != nil {
+				t.Fatal(err)
+			}
+
+			// The node isn't cordoned
+			_, _, _, err := runPlugin(func(cfg *ConfigFlags) {
+				*cfg.DryRun = true
+				*cfg.Namespace = ns
+				*cfg.NodeName = pod.Spec.NodeName
+			})
+			requireExitCode(t, err, ExitCodeNodeSchedulable)
+
+			_, out, logs, err := runPlugin(func(cfg *ConfigFlags) {
+				*cfg.DryRun = true
+				*cfg.Namespace = ns
+				*cfg.NodeName = pod.Spec.NodeName
+				*cfg.SkipUnschedulableCheck = true
+			})
+			require.NoError(t, err)
+			require.Contains(t, logs, fmt.Sprintf("1 volume(s) would be detached from node %s", pod.Spec.NodeName))
+			require.ElementsMatch(t, []string{fmt.Sprintf("Pod/%s/test-pod (PVC: test-pvc)", ns)}, out)
+
+			result, _, logs, err := runPlugin(func(cfg *ConfigFlags) {
+				*cfg.DryRun = true
+				*cfg.Namespace = ns
+				*cfg.NodeName = pod.Spec.NodeName
+				*cfg.Cordon = true
+			})
+			require.NoError(t, err)
+			require.Contains(t, logs, fmt.Sprintf("(dry-run, skipping cordon of Node %s)", pod.Spec.NodeName))
+			require.Equal(t, []string{pod.Spec.NodeName}, result.CordonedNodes)
+
+			_, out, logs, err = runPlugin(func(cfg *ConfigFlags) {
+				*cfg.DryRun = true
+				*cfg.Namespace = ns
+				*cfg.NodeName = "some-other-node"
+				*cfg.SkipUnschedulableCheck = true
+			})
+			requireExitCode(t, err, ExitCodeNothingToDo)
+			require.Contains(t, logs, "No pods found, nothing to do")
+			require.Empty(t, out)
+			return ctx
+		}).
+		Assess("Report already scaled down controllers", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			result, _, logs, err := runPlugin(func(cfg *ConfigFlags) {
+				*cfg.DryRun = true
+				*cfg.SkipIfScaled = true
+			})
+			require.NoError(t, err)
+			deployment := fmt.Sprintf("Deployment/%s/test-deployment", ctx.Value("deployNS").(string))
+			require.Contains(t, logs, deployment+" is already scaled down, skipping")
+			require.Len(t, result.Skipped, 1)
+			require.Equal(t, deployment, result.Skipped[0].String())
+			return ctx
+		}).
+		Assess("Exclude controllers", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			deployNS := ctx.Value("deployNS").(string)
+			result, out, _, err := runPlugin(func(cfg *ConfigFlags) {
+				*cfg.DryRun = true
+				*cfg.ExcludeControllers = []string{"deployment/test-deployment"}
+			})
+			require.NoError(t, err)
+			require.Contains(t, out, fmt.Sprintf("Deployment/%s/test-deployment (PVC: test-pvc) (excluded)", deployNS))
+			require.Equal(t, []common.ControllerRef{{
+				Kind: common.KindDeployment, Namespace: deployNS, Name: "test-deployment",
+			}}, result.Skipped)
+			require.Len(t, result.DeletedPods, 1)
+			return ctx
+		}).
+		Assess("Declining the confirmation prompt doesn't modify anything", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			result, _, logs, err := runPlugin(func(cfg *ConfigFlags) {
+				*cfg.Confirmed = false
+				cfg.in = strings.NewReader("n\n")
+			})
+			requireExitCode(t, err, ExitCodeCancelled)
+			require.Contains(t, logs, "Proceed? [y/N]: ")
+			require.Contains(t, logs, "Operation cancelled by user")
+			require.True(t, result.Cancelled)
+			require.Empty(t, result.Scaled)
+			require.Empty(t, result.DeletedPods)
+
+			deployment := &appsv1.Deployment{}
+			if err := cfg.Client().Resources().Get(ctx, "test-deployment", ctx.Value("deployNS").(string), deployment); err != nil {
+				t.Fatal(err)
+			}
+			require.Equal(t, int32(1), *deployment.Spec.Replicas)
+			return ctx
+		}).
+		Assess("Interactively select controllers", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// Controllers are prompted in sorted order, so the Deployment comes before the Pod
+			result, _, logs, err := runPlugin(func(cfg *ConfigFlags) {
+				*cfg.DryRun = true
+				*cfg.Confirmed = false
+				*cfg.Interactive = true
+				cfg.in = strings.NewReader("y\nn\n")
+			})
+			require.NoError(t, err)
+			require.Contains(t, logs, "Scaling down 1 controller(s)...")
+			require.Contains(t, logs, "Scale down complete: 1 scaled down, 1 skipped")
+			require.Equal(t, []common.ControllerRef{{
+				Kind: common.KindDeployment, Namespace: ctx.Value("deployNS").(string), Name: "test-deployment",
+			}}, result.Scaled)
+			require.Equal(t, []common.ControllerRef{{
+				Kind: common.KindPod, Namespace: ctx.Value("podNS").(string), Name: "test-pod",
+			}}, result.Skipped)
 			return ctx
 		}).
 		Assess("Scale down affected controllers", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
-			out, logs, err := runPlugin(func(cfg *ConfigFlags) {
+			recorder := record.NewFakeRecorder(10)
+			result, out, logs, err := runPlugin(func(cfg *ConfigFlags) {
+				cfg.recorder = recorder
+				*cfg.ScaleAnnotations = map[string]string{"ticket-id": "CHG-1234"}
 				*cfg.DryRun = false
+				*cfg.Wait = true
+				*cfg.WaitTimeout = 2 * time.Minute
+				*cfg.Confirmed = false
+				cfg.in = strings.NewReader("y\n")
 			})
 			require.NoError(t, err)
+			require.Contains(t, logs, "Total: 1 Deployment, 1 Pod deleted (2 total)")
+			require.Contains(t, logs, "Proceed? [y/N]: ")
 			require.Contains(t, logs, "Scale down complete")
+			require.Len(t, result.Scaled, 1)
+			require.Len(t, result.DeletedPods, 1)
+			require.Empty(t, result.Failed)
 			require.ElementsMatch(t, []string{
-				fmt.Sprintf("Pod/%s/test-pod", ctx.Value("podNS").(string)),
-				fmt.Sprintf("Deployment/%s/test-deployment", ctx.Value("deployNS").(string)),
+				fmt.Sprintf("Pod/%s/test-pod (PVC: test-pvc)", ctx.Value("podNS").(string)),
+				fmt.Sprintf("Deployment/%s/test-deployment (PVC: test-pvc)", ctx.Value("deployNS").(string)),
 			}, out)
+
+			for _, ns := range []string{ctx.Value("podNS").(string), ctx.Value("deployNS").(string)} {
+				pods := &corev1.PodList{}
+				if err := cfg.Client().Resources(ns).List(ctx, pods); err != nil {
+					t.Fatal(err)
+				}
+				require.Empty(t, pods.Items)
+			}
+
+			deployment := &appsv1.Deployment{}
+			if err := cfg.Client().Resources().Get(ctx, "test-deployment", ctx.Value("deployNS").(string), deployment); err != nil {
+				t.Fatal(err)
+			}
+			require.Equal(t, "kubectl-unmount", deployment.Annotations[common.AnnotationScaledBy])
+			require.Equal(t, "1", deployment.Annotations[common.AnnotationOriginalReplicas])
+			require.Equal(t, "test-pvc", deployment.Annotations[common.AnnotationPVCTrigger])
+			require.NotEmpty(t, deployment.Annotations[common.AnnotationScaledAt])
+			require.Equal(t, "CHG-1234", deployment.Annotations["ticket-id"])
+			require.Equal(t, "ticket-id", deployment.Annotations[common.AnnotationCustomAnnotations])
+
+			close(recorder.Events)
+			var events []string
+			for event := range recorder.Events {
+				events = append(events, event)
+			}
+			require.ElementsMatch(t, []string{
+				"Normal Unmounted kubectl-unmount scaled replicas from 1 to 0 for PVC test-pvc",
+				"Normal Unmounted kubectl-unmount evicted Pod for PVC test-pvc",
+			}, events)
 			return ctx
 		}).
 		Assess("Verify Pods are no longer running", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
-			out, logs, err := runPlugin(func(cfg *ConfigFlags) {
+			_, out, logs, err := runPlugin(func(cfg *ConfigFlags) {
 				*cfg.DryRun = true
 			})
-			require.NoError(t, err)
+			requireExitCode(t, err, ExitCodeNothingToDo)
 			require.Contains(t, logs, "No pods found, nothing to do")
 			require.Empty(t, out)
 			return ctx
@@ -165,6 +381,305 @@ func TestRunPlugin(t *testing.T) {
 	testenv.Test(t, f)
 }
 
+func TestRunPluginSelector(t *testing.T) {
+	f := features.New("Filter pods by label selector").
+		Setup(func(ctx context.Context, t *testing.T, config *envconf.Config) context.Context {
+			client := config.Client()
+
+			// Two pods share the same PVC, but have different labels
+			ns, podSpec := createPVCAndPodSpec(ctx, t, client)
+			for _, app := range []string{"selected", "ignored"} {
+				pod := &corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      fmt.Sprintf("test-pod-%s", app),
+						Namespace: ns,
+						Labels: map[string]string{
+							"app": app,
+						},
+					},
+					Spec: podSpec,
+				}
+				if err := client.Resources().Create(ctx, pod); err != nil {
+					t.Fatal(err)
+				}
+				err := wait.For(conditions.New(client.Resources()).ResourceMatch(pod, func(object k8s.Object) bool {
+					p := object.(*corev1.Pod)
+					return p.Status.Phase == corev1.PodRunning
+				}))
+				if err != nil {
+					t.Error(err)
+				}
+			}
+
+			return context.WithValue(ctx, "selectorNS", ns)
+		}).
+		Assess("Only the selected Pod is affected", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			ns := ctx.Value("selectorNS").(string)
+			_, out, logs, err := runPlugin(func(cfg *ConfigFlags) {
+				*cfg.DryRun = true
+				*cfg.Namespace = ns
+				*cfg.Selector = "app=selected"
+			})
+			require.NoError(t, err)
+			require.Contains(t, logs, "Found 1 pods to scale down")
+			require.ElementsMatch(t, []string{fmt.Sprintf("Pod/%s/test-pod-selected (PVC: test-pvc)", ns)}, out)
+			return ctx
+		}).
+		Assess("Selector narrows the storage class match in all namespaces", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			ns := ctx.Value("selectorNS").(string)
+			_, out, logs, err := runPlugin(func(cfg *ConfigFlags) {
+				*cfg.DryRun = true
+				*cfg.Selector = "app=selected"
+			})
+			require.NoError(t, err)
+			require.Contains(t, logs, "Found 1 pods to scale down")
+			require.ElementsMatch(t, []string{fmt.Sprintf("Pod/%s/test-pod-selected (PVC: test-pvc)", ns)}, out)
+			return ctx
+		}).
+		Assess("Invalid selector is rejected", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			_, _, _, err := runPlugin(func(cfg *ConfigFlags) {
+				*cfg.DryRun = true
+				*cfg.Selector = "app in ("
+			})
+			require.ErrorContains(t, err, "invalid label selector")
+			return ctx
+		}).
+		Teardown(func(ctx context.Context, t *testing.T, config *envconf.Config) context.Context {
+			deleteNamespace(ctx, t, config.Client(), ctx.Value("selectorNS").(string))
+			return ctx
+		}).
+		Feature()
+
+	testenv.Test(t, f)
+}
+
+func TestRunPluginNamespaceSelector(t *testing.T) {
+	f := features.New("Select namespaces by label").
+		Setup(func(ctx context.Context, t *testing.T, config *envconf.Config) context.Context {
+			client := config.Client()
+
+			// Each namespace has a pod using its own PVC, but only two of them are labeled env=prod
+			var namespaces []string
+			for _, env := range []string{"prod", "prod", "staging"} {
+				namespace, podSpec := createPVCAndPodSpec(ctx, t, client)
+				ns := &corev1.Namespace{}
+				if err := client.Resources().Get(ctx, namespace, "", ns); err != nil {
+					t.Fatal(err)
+				}
+				ns.Labels = map[string]string{"env": env}
+				if err := client.Resources().Update(ctx, ns); err != nil {
+					t.Fatal(err)
+				}
+				pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "test-pod", Namespace: namespace}, Spec: podSpec}
+				if err := client.Resources().Create(ctx, pod); err != nil {
+					t.Fatal(err)
+				}
+				err := wait.For(conditions.New(client.Resources()).ResourceMatch(pod, func(object k8s.Object) bool {
+					return object.(*corev1.Pod).Status.Phase == corev1.PodRunning
+				}))
+				if err != nil {
+					t.Error(err)
+				}
+				namespaces = append(namespaces, namespace)
+			}
+
+			return context.WithValue(ctx, "namespaceSelectorNS", namespaces)
+		}).
+		Assess("Only pods in the prod namespaces are affected", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			namespaces := ctx.Value("namespaceSelectorNS").([]string)
+			_, out, logs, err := runPlugin(func(cfg *ConfigFlags) {
+				*cfg.DryRun = true
+				*cfg.NamespaceSelector = "env=prod"
+			})
+			require.NoError(t, err)
+			require.Contains(t, logs, "Found 2 pods to scale down")
+			require.ElementsMatch(t, []string{
+				fmt.Sprintf("Pod/%s/test-pod (PVC: test-pvc)", namespaces[0]),
+				fmt.Sprintf("Pod/%s/test-pod (PVC: test-pvc)", namespaces[1]),
+			}, out)
+			return ctx
+		}).
+		Teardown(func(ctx context.Context, t *testing.T, config *envconf.Config) context.Context {
+			for _, namespace := range ctx.Value("namespaceSelectorNS").([]string) {
+				deleteNamespace(ctx, t, config.Client(), namespace)
+			}
+			return ctx
+		}).
+		Feature()
+
+	testenv.Test(t, f)
+}
+
+func TestRunPluginJobs(t *testing.T) {
+	f := features.New("Suspend Job and CronJob").
+		Setup(func(ctx context.Context, t *testing.T, config *envconf.Config) context.Context {
+			client := config.Client()
+
+			ns, podSpec := createPVCAndPodSpec(ctx, t, client)
+			podSpec.RestartPolicy = corev1.RestartPolicyNever
+			job := &batchv1.Job{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-job", Namespace: ns},
+				Spec:       batchv1.JobSpec{Template: corev1.PodTemplateSpec{Spec: podSpec}},
+			}
+			if err := client.Resources().Create(ctx, job); err != nil {
+				t.Fatal(err)
+			}
+
+			// The CronJob won't run on its own during the test, so create one of its Jobs directly
+			cronJob := &batchv1.CronJob{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-cronjob", Namespace: ns},
+				Spec: batchv1.CronJobSpec{
+					Schedule: "0 0 1 1 *",
+					JobTemplate: batchv1.JobTemplateSpec{
+						Spec: batchv1.JobSpec{Template: corev1.PodTemplateSpec{Spec: podSpec}},
+					},
+				},
+			}
+			if err := client.Resources().Create(ctx, cronJob); err != nil {
+				t.Fatal(err)
+			}
+			cronJobRun := &batchv1.Job{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-cronjob-manual",
+					Namespace: ns,
+					OwnerReferences: []metav1.OwnerReference{{
+						APIVersion: "batch/v1",
+						Kind:       common.KindCronJob,
+						Name:       cronJob.Name,
+						UID:        cronJob.UID,
+						Controller: ptr.To(true),
+					}},
+				},
+				Spec: batchv1.JobSpec{Template: corev1.PodTemplateSpec{Spec: podSpec}},
+			}
+			if err := client.Resources().Create(ctx, cronJobRun); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, j := range []*batchv1.Job{job, cronJobRun} {
+				err := wait.For(conditions.New(client.Resources()).ResourceMatch(j, func(object k8s.Object) bool {
+					return object.(*batchv1.Job).Status.Ready != nil && *object.(*batchv1.Job).Status.Ready == 1
+				}))
+				if err != nil {
+					t.Error(err)
+				}
+			}
+
+			return context.WithValue(ctx, "jobNS", ns)
+		}).
+		Assess("Suspend Job and CronJob", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			ns := ctx.Value("jobNS").(string)
+			result, out, logs, err := runPlugin(func(cfg *ConfigFlags) {
+				*cfg.DryRun = false
+				*cfg.Namespace = ns
+			})
+			require.NoError(t, err)
+			require.Contains(t, logs, "Scale down complete")
+			require.ElementsMatch(t, []string{
+				fmt.Sprintf("Job/%s/test-job (PVC: test-pvc)", ns),
+				fmt.Sprintf("CronJob/%s/test-cronjob (PVC: test-pvc)", ns),
+			}, out)
+			require.ElementsMatch(t, []common.ControllerRef{
+				{Kind: common.KindJob, Namespace: ns, Name: "test-job"},
+				{Kind: common.KindCronJob, Namespace: ns, Name: "test-cronjob"},
+			}, result.Scaled)
+
+			job := &batchv1.Job{}
+			if err := cfg.Client().Resources().Get(ctx, "test-job", ns, job); err != nil {
+				t.Fatal(err)
+			}
+			require.True(t, *job.Spec.Suspend)
+			cronJob := &batchv1.CronJob{}
+			if err := cfg.Client().Resources().Get(ctx, "test-cronjob", ns, cronJob); err != nil {
+				t.Fatal(err)
+			}
+			require.True(t, *cronJob.Spec.Suspend)
+			require.Equal(t, "kubectl-unmount", cronJob.Annotations[common.AnnotationScaledBy])
+
+			// Suspending the Job makes the Job controller terminate its pod
+			err = wait.For(conditions.New(cfg.Client().Resources()).ResourceMatch(job, func(object k8s.Object) bool {
+				return object.(*batchv1.Job).Status.Active == 0
+			}), wait.WithTimeout(2*time.Minute))
+			require.NoError(t, err)
+			return ctx
+		}).
+		Teardown(func(ctx context.Context, t *testing.T, config *envconf.Config) context.Context {
+			deleteNamespace(ctx, t, config.Client(), ctx.Value("jobNS").(string))
+			return ctx
+		}).
+		Feature()
+
+	testenv.Test(t, f)
+}
+
+func TestRunPluginEphemeralVolumes(t *testing.T) {
+	f := features.New("Unmount generic ephemeral volumes").
+		Setup(func(ctx context.Context, t *testing.T, config *envconf.Config) context.Context {
+			client := config.Client()
+
+			ns := envconf.RandomName("test-ns", 16)
+			if err := client.Resources().Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: ns}}); err != nil {
+				t.Fatal(err)
+			}
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-pod", Namespace: ns},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{
+						Name:         "test-container",
+						Image:        "busybox:latest",
+						Command:      []string{"sh", "-c", "sleep 3600"},
+						VolumeMounts: []corev1.VolumeMount{{Name: "scratch", MountPath: "/scratch"}},
+					}},
+					Volumes: []corev1.Volume{{
+						Name: "scratch",
+						VolumeSource: corev1.VolumeSource{
+							Ephemeral: &corev1.EphemeralVolumeSource{
+								VolumeClaimTemplate: &corev1.PersistentVolumeClaimTemplate{
+									Spec: corev1.PersistentVolumeClaimSpec{
+										StorageClassName: &storageClassName,
+										AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+										Resources: corev1.VolumeResourceRequirements{
+											Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("1Mi")},
+										},
+									},
+								},
+							},
+						},
+					}},
+				},
+			}
+			if err := client.Resources().Create(ctx, pod); err != nil {
+				t.Fatal(err)
+			}
+			err := wait.For(conditions.New(client.Resources()).ResourceMatch(pod, func(object k8s.Object) bool {
+				return object.(*corev1.Pod).Status.Phase == corev1.PodRunning
+			}))
+			if err != nil {
+				t.Error(err)
+			}
+
+			return context.WithValue(ctx, "ephemeralNS", ns)
+		}).
+		Assess("Pods using ephemeral volumes of the storage class are affected", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			ns := ctx.Value("ephemeralNS").(string)
+			_, out, logs, err := runPlugin(func(cfg *ConfigFlags) {
+				*cfg.DryRun = true
+				*cfg.Namespace = ns
+			})
+			require.NoError(t, err)
+			require.Contains(t, logs, "Found 1 pods to scale down")
+			require.Equal(t, []string{fmt.Sprintf("Pod/%s/test-pod (PVC: test-pod-scratch)", ns)}, out)
+			return ctx
+		}).
+		Teardown(func(ctx context.Context, t *testing.T, config *envconf.Config) context.Context {
+			deleteNamespace(ctx, t, config.Client(), ctx.Value("ephemeralNS").(string))
+			return ctx
+		}).
+		Feature()
+
+	testenv.Test(t, f)
+}
+
 func createPVCAndPodSpec(ctx context.Context, t *testing.T, client klient.Client) (string, corev1.PodSpec) {
 	// Create a random namespace
 	namespace := envconf.RandomName("test-ns", 16)
@@ -228,27 +743,114 @@ func createPVCAndPodSpec(ctx context.Context, t *testing.T, client klient.Client
 	return namespace, podSpec
 }
 
-func runPlugin(configurers ...func(*ConfigFlags)) ([]string, string, error) {
-	var outBuf, logBuf bytes.Buffer
-	pluginCfg := &ConfigFlags{
-		ConfigFlags: genericclioptions.ConfigFlags{
-			Namespace: common.StringP(""),
-		},
-		PVCName:      common.StringP(""),
-		StorageClass: &storageClassName,
-		DryRun:       common.BoolP(false),
-		Confirmed:    common.BoolP(true),
-		logger:       logger.NewLogger(&logBuf),
-		out:          &outBuf,
+func deleteNamespace(ctx context.Context, t *testing.T, client klient.Client, namespace string) {
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}}
+	if err := client.Resources().Delete(ctx, ns); err != nil {
+		t.Error(err)
 	}
+}
+
+func runPlugin(configurers ...func(*ConfigFlags)) (*Result, []string, string, error) {
+	return runPluginWithContext(context.Background(), configurers...)
+}
 
+func runPluginWithContext(ctx context.Context, configurers ...func(*ConfigFlags)) (*Result, []string, string, error) {
+	var outBuf, logBuf bytes.Buffer
+	pluginCfg := newTestConfig(&outBuf, &logBuf)
 	for _, configurer := range configurers {
 		configurer(pluginCfg)
 	}
 
-	err := RunPlugin(pluginCfg)
+	result, err := RunPlugin(ctx, pluginCfg)
+
+	return result, getLines(outBuf.String()), logBuf.String(), err
+}
 
-	return getLines(outBuf.String()), logBuf.String(), err
+// newTestConfig creates a configuration with the flags' defaults, writing its output and logs to the given buffers.
+func newTestConfig(outBuf, logBuf *bytes.Buffer) *ConfigFlags {
+	return &ConfigFlags{
+		ConfigFlags: genericclioptions.ConfigFlags{
+			Namespace: common.StringP(""),
+		},
+		PVCName:                  &[]string{},
+		PVName:                   common.StringP(""),
+		PVCRegex:                 common.StringP(""),
+		NamespaceSelector:        common.StringP(""),
+		FromStdin:                common.BoolP(false),
+		Selector:                 common.StringP(""),
+		FieldSelector:            common.StringP(""),
+		AnnotationSelector:       common.StringP(""),
+		NodeName:                 common.StringP(""),
+		PodStatusFilter:          &[]string{"Running", "Pending"},
+		ExcludeNamespaces:        &[]string{},
+		ExcludeControllers:       &[]string{},
+		SkipControllers:          &[]string{},
+		OnlyControllers:          common.BoolP(false),
+		OnlyPods:                 common.BoolP(false),
+		ProtectAnnotation:        common.StringP(common.AnnotationSkip),
+		Timings:                  common.BoolP(false),
+		Watch:                    common.BoolP(false),
+		WatchCooldown:            common.DurationP(time.Minute),
+		StorageClass:             &[]string{storageClassName},
+		AccessMode:               common.StringP(""),
+		IncludeUnbound:           common.BoolP(false),
+		CSIDriver:                common.StringP(""),
+		MinSize:                  common.StringP(""),
+		DryRun:                   common.BoolP(false),
+		DryRunDiff:               common.BoolP(false),
+		ByNode:                   common.BoolP(false),
+		DryRunServer:             common.BoolP(false),
+		IgnorePDB:                common.BoolP(false),
+		Force:                    common.BoolP(false),
+		DisableEviction:          common.BoolP(false),
+		CheckPDBViolations:       common.BoolP(false),
+		CheckAdmissionWebhooks:   common.BoolP(false),
+		Confirmed:                common.BoolP(true),
+		Verbose:                  common.BoolP(false),
+		Verbosity:                common.IntP(0),
+		Interactive:              common.BoolP(false),
+		Concurrency:              common.IntP(1),
+		MaxDisruptionBudget:      common.IntP(0),
+		MaxResources:             common.IntP(100),
+		Replicas:                 common.IntP(0),
+		MaxRetries:               common.IntP(3),
+		RateLimit:                common.Float64P(10),
+		Burst:                    common.IntP(20),
+		GracePeriod:              common.Int64P(-1),
+		ScaleAnnotations:         &map[string]string{},
+		PreValidation:            common.BoolP(false),
+		SkipIfScaled:             common.BoolP(false),
+		SkipUnschedulableCheck:   common.BoolP(false),
+		Cordon:                   common.BoolP(false),
+		LeaveHPA:                 common.BoolP(false),
+		Uncordon:                 common.BoolP(false),
+		Wait:                     common.BoolP(false),
+		WaitTimeout:              common.DurationP(5 * time.Minute),
+		MaxWaitForSchedule:       common.DurationP(0),
+		WaitForReplicaSetCleanup: common.BoolP(false),
+		VerifyDetach:             common.BoolP(false),
+		CleanAttachments:         common.BoolP(false),
+		CheckCustomFinalizers:    common.BoolP(false),
+		RemoveCustomFinalizers:   &[]string{},
+		DatadogMetrics:           common.BoolP(false),
+		StatsdAddress:            common.StringP("127.0.0.1:8125"),
+		Output:                   common.StringP(""),
+		PatchIstioVS:             common.BoolP(false),
+		IstioNamespace:           common.StringP("istio-system"),
+		CloudProvider:            common.StringP(""),
+		LogLevel:                 common.StringP("info"),
+		LogJSON:                  common.BoolP(false),
+		Contexts:                 &[]string{},
+		AllContexts:              common.BoolP(false),
+		Timeout:                  common.DurationP(0),
+		OutputFile:               common.StringP(""),
+		RestoreFrom:              common.StringP(""),
+		PlanFile:                 common.StringP(""),
+		ApplyPlan:                common.StringP(""),
+		GenerateRunbook:          common.StringP(""),
+		logger:                   logger.NewLogger(logBuf, logger.LevelInfo),
+		out:                      outBuf,
+	}
 }
 
 func getLines(s string) []string {
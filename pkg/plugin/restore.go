@@ -0,0 +1,147 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/common"
+	"github.com/dancavallaro/kubectl-unmount/pkg/discovery"
+	"github.com/dancavallaro/kubectl-unmount/pkg/keda"
+	"github.com/dancavallaro/kubectl-unmount/pkg/scaling"
+)
+
+// restoreFile lists the controllers that were scaled down, with enough information to restore them (with
+// --restore-from) even if the annotations recording the scale down are lost, e.g. because a controller was
+// recreated.
+type restoreFile struct {
+	ScaledAt    time.Time          `json:"scaledAt"`
+	DryRun      bool               `json:"dryRun,omitempty"`
+	Controllers []scaledController `json:"controllers"`
+}
+
+type scaledController struct {
+	// APIVersion is only set for custom resources, see common.ControllerRef.
+	APIVersion string `json:"apiVersion,omitempty"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace"`
+	Name       string `json:"name"`
+	// Replicas is the controller's number of replicas before it was scaled down, if known. Custom resources are
+	// restored to the number recorded in their annotations otherwise.
+	Replicas *int32 `json:"replicas,omitempty"`
+}
+
+// checkWritable checks that the file given with the given flag can be written, before anything is scaled down. A
+// file created to check this is removed again, so that a run that stops early doesn't leave an empty (and
+// unparseable) file behind.
+func checkWritable(flag, path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if errors.Is(err, os.ErrExist) {
+		f, err = os.OpenFile(path, os.O_WRONLY, 0o644)
+		if err != nil {
+			return fmt.Errorf("cannot write --%s: %w", flag, err)
+		}
+		return f.Close()
+	}
+	if err != nil {
+		return fmt.Errorf("cannot write --%s: %w", flag, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("cannot write --%s: %w", flag, err)
+	}
+	return os.Remove(path)
+}
+
+// writeRestoreFile writes the controllers that were scaled down (or would be, in dry-run mode), which had the
+// given number of replicas before, to the file given with --output-file.
+func writeRestoreFile(cfg *ConfigFlags, result *Result, replicasBefore map[common.ControllerRef]int32) error {
+	file := restoreFile{ScaledAt: time.Now().UTC(), DryRun: *cfg.DryRun, Controllers: []scaledController{}}
+	for _, ctrl := range result.Scaled {
+		scaled := scaledController{APIVersion: ctrl.APIVersion, Kind: ctrl.Kind, Namespace: ctrl.Namespace, Name: ctrl.Name}
+		if replicas, ok := replicasBefore[ctrl]; ok {
+			scaled.Replicas = &replicas
+		}
+		file.Controllers = append(file.Controllers, scaled)
+	}
+
+	f, err := os.Create(*cfg.OutputFile)
+	if err != nil {
+		return fmt.Errorf("failed to write --output-file: %w", err)
+	}
+	if err := printJSON(f, file); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write --output-file: %w", err)
+	}
+	return nil
+}
+
+// restoreFrom scales the controllers listed in the file given with --restore-from back up to their recorded
+// number of replicas. It continues with other controllers if one fails.
+func restoreFrom(ctx context.Context, cfg *ConfigFlags, finder discovery.Finder, kedaClient keda.Client, scaler scaling.Scaler,
+	result *Result) error {
+	data, err := os.ReadFile(*cfg.RestoreFrom)
+	if err != nil {
+		return fmt.Errorf("failed to read --restore-from file: %w", err)
+	}
+	var file restoreFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to parse --restore-from file %s: %w", *cfg.RestoreFrom, err)
+	}
+	if file.DryRun {
+		cfg.logger.Warn("%s was written by a dry run, so these controllers weren't actually scaled down", *cfg.RestoreFrom)
+	}
+
+	kedaInstalled, err := kedaClient.Installed()
+	if err != nil {
+		return err
+	}
+
+	cfg.logger.Info("Restoring %d controller(s) scaled down at %s...", len(file.Controllers), file.ScaledAt.Format(time.RFC3339))
+	var errs []error
+	for _, scaled := range file.Controllers {
+		ctrl := common.ControllerRef{Kind: scaled.Kind, Namespace: scaled.Namespace, Name: scaled.Name, APIVersion: scaled.APIVersion}
+		if kedaInstalled {
+			if err := resumeScaledObject(ctx, cfg, kedaClient, ctrl); err != nil {
+				cfg.logger.Error(err)
+				errs = append(errs, err)
+				result.Failed = append(result.Failed, ctrl)
+				continue
+			}
+		}
+		// Re-enable the controller's HPA first, so that it resumes autoscaling from the restored replicas
+		if err := restoreHPA(ctx, finder, scaler, ctrl); err != nil {
+			cfg.logger.Error(err)
+			errs = append(errs, err)
+			result.Failed = append(result.Failed, ctrl)
+			continue
+		}
+		if err := scaler.Restore(ctx, ctrl, scaled.Replicas); err != nil {
+			cfg.logger.Error(err)
+			errs = append(errs, err)
+			result.Failed = append(result.Failed, ctrl)
+			continue
+		}
+		result.Restored = append(result.Restored, ctrl)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("encountered %d errors restoring: %w", len(errs), errors.Join(errs...))
+	}
+	return nil
+}
+
+// restoreHPA restores the HorizontalPodAutoscaler targeting the given controller, if it was disabled when the
+// controller was scaled down.
+func restoreHPA(ctx context.Context, finder discovery.Finder, scaler scaling.Scaler, ctrl common.ControllerRef) error {
+	hpa, err := finder.FindHPA(ctx, ctrl)
+	if err != nil || hpa == nil {
+		return err
+	}
+	_, err = scaler.RestoreHPA(ctx, hpa)
+	return err
+}
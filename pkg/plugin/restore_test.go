@@ -0,0 +1,163 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/common"
+	"github.com/dancavallaro/kubectl-unmount/pkg/discovery"
+	"github.com/dancavallaro/kubectl-unmount/pkg/keda"
+	"github.com/dancavallaro/kubectl-unmount/pkg/logger"
+	"github.com/dancavallaro/kubectl-unmount/pkg/scaling"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
+)
+
+func TestRestoreFile(t *testing.T) {
+	deployment := common.ControllerRef{Kind: common.KindDeployment, Namespace: "test-ns", Name: "web"}
+	cronJob := common.ControllerRef{Kind: common.KindCronJob, Namespace: "test-ns", Name: "backup"}
+	clientset := fake.NewClientset(
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "test-ns", Name: "web"}, Spec: appsv1.DeploymentSpec{Replicas: ptr.To(int32(0))}},
+		&batchv1.CronJob{ObjectMeta: metav1.ObjectMeta{Namespace: "test-ns", Name: "backup"}, Spec: batchv1.CronJobSpec{Suspend: ptr.To(true)}},
+		&autoscalingv2.HorizontalPodAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   "test-ns",
+				Name:        "web",
+				Annotations: map[string]string{common.AnnotationOriginalMinReplicas: "0"},
+			},
+			Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+				ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{Kind: common.KindDeployment, Name: "web"},
+				MinReplicas:    ptr.To(int32(1)),
+			},
+		},
+	)
+
+	var logs bytes.Buffer
+	path := filepath.Join(t.TempDir(), "scaled.json")
+	cfg := &ConfigFlags{
+		DryRun:      common.BoolP(false),
+		OutputFile:  common.StringP(path),
+		RestoreFrom: common.StringP(path),
+		logger:      logger.NewLogger(&logs, logger.LevelInfo),
+	}
+	require.NoError(t, checkWritable("output-file", path))
+	scaled := &Result{Scaled: []common.ControllerRef{deployment, cronJob}}
+	require.NoError(t, writeRestoreFile(cfg, scaled, map[common.ControllerRef]int32{deployment: 3}))
+
+	ctx := context.Background()
+	result := &Result{}
+	scaler := scaling.New(clientset, cfg.logger, scaling.Options{})
+	kedaClient := keda.New(dynamicfake.NewSimpleDynamicClient(runtime.NewScheme()), clientset.Discovery(), cfg.logger)
+	require.NoError(t, restoreFrom(ctx, cfg, discovery.New(clientset, cfg.logger), kedaClient, scaler, result))
+	require.Equal(t, []common.ControllerRef{deployment, cronJob}, result.Restored)
+
+	d, err := clientset.AppsV1().Deployments("test-ns").Get(ctx, "web", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Equal(t, int32(3), *d.Spec.Replicas)
+	c, err := clientset.BatchV1().CronJobs("test-ns").Get(ctx, "backup", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.False(t, *c.Spec.Suspend)
+	require.Contains(t, logs.String(), "Restored Deployment/test-ns/web to 3 replicas")
+
+	hpa, err := clientset.AutoscalingV2().HorizontalPodAutoscalers("test-ns").Get(ctx, "web", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Equal(t, int32(0), *hpa.Spec.MinReplicas)
+	require.NotContains(t, hpa.Annotations, common.AnnotationOriginalMinReplicas)
+}
+
+func TestRestoreFileUnsupported(t *testing.T) {
+	var logs bytes.Buffer
+	path := filepath.Join(t.TempDir(), "scaled.json")
+	cfg := &ConfigFlags{
+		DryRun:      common.BoolP(false),
+		OutputFile:  common.StringP(path),
+		RestoreFrom: common.StringP(path),
+		logger:      logger.NewLogger(&logs, logger.LevelInfo),
+	}
+	daemonSet := common.ControllerRef{Kind: common.KindDaemonSet, Namespace: "test-ns", Name: "agent"}
+	require.NoError(t, writeRestoreFile(cfg, &Result{Scaled: []common.ControllerRef{daemonSet}}, nil))
+
+	clientset := fake.NewClientset()
+	result := &Result{}
+	kedaClient := keda.New(dynamicfake.NewSimpleDynamicClient(runtime.NewScheme()), clientset.Discovery(), cfg.logger)
+	err := restoreFrom(context.Background(), cfg, discovery.New(clientset, cfg.logger), kedaClient,
+		scaling.New(clientset, cfg.logger, scaling.Options{}), result)
+	require.ErrorContains(t, err, "restoring is not supported for DaemonSets")
+	require.Empty(t, result.Restored)
+	require.Equal(t, []common.ControllerRef{daemonSet}, result.Failed)
+}
+
+func TestCheckWritable(t *testing.T) {
+	// A run that stops early must not leave an empty file behind
+	path := filepath.Join(t.TempDir(), "scaled.json")
+	require.NoError(t, checkWritable("output-file", path))
+	require.NoFileExists(t, path)
+	// An existing file is left untouched until it's written
+	require.NoError(t, os.WriteFile(path, []byte("{}"), 0o644))
+	require.NoError(t, checkWritable("output-file", path))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "{}", string(data))
+
+	require.ErrorContains(t, checkWritable("output-file", filepath.Join(t.TempDir(), "missing", "scaled.json")), "cannot write --output-file")
+}
+
+func TestRestoreFileRollout(t *testing.T) {
+	rollouts := schema.GroupVersionResource{Group: "argoproj.io", Version: "v1alpha1", Resource: "rollouts"}
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{rollouts: "RolloutList"},
+		&unstructured.Unstructured{Object: map[string]any{
+			"apiVersion": "argoproj.io/v1alpha1",
+			"kind":       "Rollout",
+			"metadata": map[string]any{
+				"name":        "web",
+				"namespace":   "test-ns",
+				"annotations": map[string]any{common.AnnotationOriginalReplicas: "3"},
+			},
+			"spec": map[string]any{"replicas": int64(0)},
+		}})
+	clientset := fake.NewClientset()
+	clientset.Discovery().(*fakediscovery.FakeDiscovery).Resources = []*metav1.APIResourceList{{
+		GroupVersion: "argoproj.io/v1alpha1",
+		APIResources: []metav1.APIResource{
+			{Name: "rollouts", Kind: "Rollout", Namespaced: true},
+			{Name: "rollouts/scale", Kind: "Scale", Namespaced: true},
+		},
+	}}
+
+	var logs bytes.Buffer
+	path := filepath.Join(t.TempDir(), "scaled.json")
+	cfg := &ConfigFlags{
+		DryRun:      common.BoolP(false),
+		OutputFile:  common.StringP(path),
+		RestoreFrom: common.StringP(path),
+		logger:      logger.NewLogger(&logs, logger.LevelInfo),
+	}
+	rollout := common.ControllerRef{Kind: "Rollout", Namespace: "test-ns", Name: "web", APIVersion: "argoproj.io/v1alpha1"}
+	require.NoError(t, writeRestoreFile(cfg, &Result{Scaled: []common.ControllerRef{rollout}}, nil))
+
+	ctx := context.Background()
+	result := &Result{}
+	kedaClient := keda.New(dynamicfake.NewSimpleDynamicClient(runtime.NewScheme()), clientset.Discovery(), cfg.logger)
+	scaler := scaling.New(clientset, cfg.logger, scaling.Options{Dynamic: dynamicClient})
+	require.NoError(t, restoreFrom(ctx, cfg, discovery.New(clientset, cfg.logger), kedaClient, scaler, result))
+	require.Equal(t, []common.ControllerRef{rollout}, result.Restored)
+
+	obj, err := dynamicClient.Resource(rollouts).Namespace("test-ns").Get(ctx, "web", metav1.GetOptions{})
+	require.NoError(t, err)
+	replicas, _, _ := unstructured.NestedInt64(obj.Object, "spec", "replicas")
+	require.Equal(t, int64(3), replicas)
+}
@@ -0,0 +1,177 @@
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/common"
+	"github.com/dancavallaro/kubectl-unmount/pkg/scaling"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Result describes the outcome of running the plugin. If the run fails partway through, it describes
+// everything that was done up to that point.
+type Result struct {
+	DryRun bool
+
+	// PVCs are the targeted PVCs, formatted as "namespace/name".
+	PVCs []string
+	// Pods are the pods found using the targeted PVCs, formatted as "namespace/name".
+	Pods []string
+	// Controllers are the top-level controllers that own the pods.
+	Controllers []common.ControllerRef
+
+	// Scaled are the controllers that were scaled down (or would have been, in dry-run mode).
+	Scaled []common.ControllerRef
+	// DeletedPods are the standalone pods that were deleted (or would have been, in dry-run mode).
+	DeletedPods []common.ControllerRef
+	// Skipped are the controllers that were left untouched, e.g. because they were declined
+	// interactively, can't be scaled down, or the run was interrupted before reaching them.
+	Skipped []common.ControllerRef
+	// Failed are the controllers that couldn't be scaled down because of an error.
+	Failed []common.ControllerRef
+	// Cancelled is whether the user declined the confirmation prompt, so nothing was scaled down.
+	Cancelled bool
+
+	// CordonedNodes are the nodes that were cordoned (with --cordon).
+	CordonedNodes []string
+	// UncordonedNodes are the nodes that were uncordoned (with --uncordon).
+	UncordonedNodes []string
+	// Restored are the controllers that were scaled back up (with --restore-from).
+	Restored []common.ControllerRef
+	// DisabledHPAs are the HorizontalPodAutoscalers that were disabled so that they don't scale their targets
+	// back up, formatted as "namespace/name".
+	DisabledHPAs []string
+	// PausedScaledObjects are the KEDA ScaledObjects that were paused so that they don't scale their targets
+	// back up, formatted as "namespace/name".
+	PausedScaledObjects []string
+
+	// Contexts breaks the outcome down by kubeconfig context, when running in several (with --contexts or
+	// --all-contexts).
+	Contexts map[string]*Result
+}
+
+// NothingToDo returns whether nothing was (or would have been, in dry-run mode) modified, e.g. because no
+// pods were using the targeted PVCs.
+func (r *Result) NothingToDo() bool {
+	return len(r.Scaled) == 0 && len(r.DeletedPods) == 0 && len(r.Failed) == 0 && len(r.Restored) == 0 &&
+		len(r.UncordonedNodes) == 0
+}
+
+// summary tallies the controllers that were (or would have been, in dry-run mode) scaled down by kind, e.g.
+// "Scaled down: 3 Deployments, 1 StatefulSet, 2 Pods deleted (6 total)".
+func (r *Result) summary() string {
+	prefix := "Scaled down"
+	if r.DryRun {
+		prefix = "Would scale down"
+	}
+	return fmt.Sprintf("%s: %s", prefix, tally(r.Scaled, r.DeletedPods))
+}
+
+// namespaceSummaries breaks down the summary by namespace, e.g. "my-namespace: 1 Deployment (1 total)".
+func (r *Result) namespaceSummaries() []string {
+	var namespaces []string
+	for _, ctrl := range slices.Concat(r.Scaled, r.DeletedPods) {
+		namespaces = append(namespaces, ctrl.Namespace)
+	}
+	slices.Sort(namespaces)
+
+	var lines []string
+	for _, ns := range slices.Compact(namespaces) {
+		otherNamespace := func(ctrl common.ControllerRef) bool { return ctrl.Namespace != ns }
+		scaled := slices.DeleteFunc(slices.Clone(r.Scaled), otherNamespace)
+		deletedPods := slices.DeleteFunc(slices.Clone(r.DeletedPods), otherNamespace)
+		lines = append(lines, fmt.Sprintf("%s: %s", ns, tally(scaled, deletedPods)))
+	}
+	return lines
+}
+
+// tally counts the given scaled down controllers by kind, e.g. "3 Deployments, 2 Pods deleted (5 total)".
+func tally(scaled, deletedPods []common.ControllerRef) string {
+	var kinds []string
+	counts := make(map[string]int)
+	for _, ctrl := range scaled {
+		if counts[ctrl.Kind] == 0 {
+			kinds = append(kinds, ctrl.Kind)
+		}
+		counts[ctrl.Kind]++
+	}
+	var parts []string
+	for _, kind := range kinds {
+		parts = append(parts, pluralize(counts[kind], kind))
+	}
+	if len(deletedPods) > 0 {
+		parts = append(parts, pluralize(len(deletedPods), common.KindPod)+" deleted")
+	}
+	if len(parts) == 0 {
+		parts = append(parts, "nothing")
+	}
+	return fmt.Sprintf("%s (%d total)", strings.Join(parts, ", "), len(scaled)+len(deletedPods))
+}
+
+func pluralize(n int, kind string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, kind)
+	}
+	return fmt.Sprintf("%d %ss", n, kind)
+}
+
+// add adds the outcome of another run (in another context) to this one.
+func (r *Result) add(other *Result) {
+	r.PVCs = append(r.PVCs, other.PVCs...)
+	r.Pods = append(r.Pods, other.Pods...)
+	r.Controllers = append(r.Controllers, other.Controllers...)
+	r.Scaled = append(r.Scaled, other.Scaled...)
+	r.DeletedPods = append(r.DeletedPods, other.DeletedPods...)
+	r.Skipped = append(r.Skipped, other.Skipped...)
+	r.Failed = append(r.Failed, other.Failed...)
+	r.Cancelled = r.Cancelled || other.Cancelled
+	r.CordonedNodes = append(r.CordonedNodes, other.CordonedNodes...)
+	r.UncordonedNodes = append(r.UncordonedNodes, other.UncordonedNodes...)
+	r.Restored = append(r.Restored, other.Restored...)
+	r.DisabledHPAs = append(r.DisabledHPAs, other.DisabledHPAs...)
+	r.PausedScaledObjects = append(r.PausedScaledObjects, other.PausedScaledObjects...)
+}
+
+func (r *Result) setPVCs(pvcsPerNs map[string][]string) {
+	r.PVCs = nil
+	for ns, pvcs := range pvcsPerNs {
+		for _, pvc := range pvcs {
+			r.PVCs = append(r.PVCs, fmt.Sprintf("%s/%s", ns, pvc))
+		}
+	}
+}
+
+func (r *Result) setPods(pods []corev1.Pod) {
+	r.Pods = nil
+	for _, pod := range pods {
+		r.Pods = append(r.Pods, fmt.Sprintf("%s/%s", pod.Namespace, pod.Name))
+	}
+}
+
+// recordScaleDown records the outcome of scaling down each of the given controllers, where errs[i]
+// is the error (if any) from scaling down controllers[i].
+func (r *Result) recordScaleDown(controllers []common.ControllerRef, errs []error) {
+	for i, ctrl := range controllers {
+		switch {
+		case errors.Is(errs[i], errInterrupted), errors.Is(errs[i], scaling.ErrNotScalable):
+			r.Skipped = append(r.Skipped, ctrl)
+		case errs[i] != nil:
+			r.Failed = append(r.Failed, ctrl)
+		case !scaling.CanScaleDown(ctrl):
+			r.Skipped = append(r.Skipped, ctrl)
+		case ctrl.Kind == common.KindPod:
+			r.DeletedPods = append(r.DeletedPods, ctrl)
+		default:
+			r.Scaled = append(r.Scaled, ctrl)
+		}
+	}
+}
+
+// scaledDown returns the controllers that were scaled down or deleted, which are the only ones whose
+// pods are expected to terminate.
+func (r *Result) scaledDown() []common.ControllerRef {
+	return slices.Concat(r.Scaled, r.DeletedPods)
+}
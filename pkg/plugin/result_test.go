@@ -0,0 +1,51 @@
+package plugin
+
+import (
+	"testing"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/common"
+	"github.com/dancavallaro/kubectl-unmount/pkg/scaling"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResultSummary(t *testing.T) {
+	result := &Result{
+		Scaled: []common.ControllerRef{
+			{Kind: common.KindDeployment, Namespace: "test-ns", Name: "app-1"},
+			{Kind: common.KindStatefulSet, Namespace: "test-ns", Name: "db"},
+			{Kind: common.KindDeployment, Namespace: "test-ns", Name: "app-2"},
+		},
+		DeletedPods: []common.ControllerRef{
+			{Kind: common.KindPod, Namespace: "test-ns", Name: "pod-1"},
+			{Kind: common.KindPod, Namespace: "test-ns", Name: "pod-2"},
+		},
+	}
+	require.Equal(t, "Scaled down: 2 Deployments, 1 StatefulSet, 2 Pods deleted (5 total)", result.summary())
+
+	result.Scaled[1].Namespace = "other-ns"
+	require.Equal(t, []string{
+		"other-ns: 1 StatefulSet (1 total)",
+		"test-ns: 2 Deployments, 2 Pods deleted (4 total)",
+	}, result.namespaceSummaries())
+
+	result.DryRun = true
+	result.DeletedPods = nil
+	require.Equal(t, "Would scale down: 2 Deployments, 1 StatefulSet (3 total)", result.summary())
+
+	require.Equal(t, "Scaled down: nothing (0 total)", (&Result{}).summary())
+}
+
+func TestResultScaledDown(t *testing.T) {
+	result := &Result{}
+	result.recordScaleDown([]common.ControllerRef{
+		{Kind: common.KindStatefulSet, Namespace: "test-ns", Name: "db"},
+		{Kind: common.KindPod, Namespace: "test-ns", Name: "pod-1"},
+		{Kind: "Cluster", Namespace: "test-ns", Name: "no-scale-subresource"},
+		{Kind: common.KindDaemonSet, Namespace: "test-ns", Name: "agent"},
+	}, []error{nil, nil, scaling.ErrNotScalable, nil})
+
+	require.Equal(t, []common.ControllerRef{
+		{Kind: common.KindStatefulSet, Namespace: "test-ns", Name: "db"},
+		{Kind: common.KindPod, Namespace: "test-ns", Name: "pod-1"},
+	}, result.scaledDown())
+}
@@ -0,0 +1,178 @@
+package plugin
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/common"
+)
+
+// runbook documents an operation (with --generate-runbook) for an on-call engineer who wasn't present for it:
+// what was scaled down, how, and how to undo it.
+type runbook struct {
+	cluster        string
+	version        string
+	replicas       int32
+	generatedAt    time.Time
+	dryRun         bool
+	pvcs           []string
+	controllers    []common.ControllerRef
+	replicasBefore map[common.ControllerRef]int32
+	result         *Result
+}
+
+// newRunbook creates a runbook for the given run, whose controllers had the given number of replicas before
+// being scaled down.
+func newRunbook(cfg *ConfigFlags, result *Result, replicasBefore map[common.ControllerRef]int32) runbook {
+	return runbook{
+		cluster:        clusterName(cfg),
+		version:        cfg.Version,
+		replicas:       int32(*cfg.Replicas),
+		generatedAt:    time.Now().UTC(),
+		dryRun:         *cfg.DryRun,
+		pvcs:           result.PVCs,
+		controllers:    result.Controllers,
+		replicasBefore: replicasBefore,
+		result:         result,
+	}
+}
+
+// clusterName returns the name of the cluster in the kubeconfig context being used, if it can be determined.
+func clusterName(cfg *ConfigFlags) string {
+	rawConfig, err := cfg.ToRawKubeConfigLoader().RawConfig()
+	if err != nil {
+		return "unknown"
+	}
+	contextName := rawConfig.CurrentContext
+	if cfg.Context != nil && *cfg.Context != "" {
+		contextName = *cfg.Context
+	}
+	if kubeContext, ok := rawConfig.Contexts[contextName]; ok {
+		return kubeContext.Cluster
+	}
+	return "unknown"
+}
+
+// write writes the runbook as Markdown to the given file.
+func (r runbook) write(path string) error {
+	var b strings.Builder
+	b.WriteString("# kubectl-unmount runbook\n\n")
+	fmt.Fprintf(&b, "- **Cluster:** %s\n", r.cluster)
+	fmt.Fprintf(&b, "- **kubectl-unmount version:** %s\n", r.version)
+	fmt.Fprintf(&b, "- **Generated at:** %s\n", r.generatedAt.Format(time.RFC3339))
+	if r.dryRun {
+		b.WriteString("- **Mode:** dry run (nothing was modified)\n")
+	}
+	b.WriteString("\n## Targeted PVCs\n\n")
+	for _, pvc := range r.pvcs {
+		fmt.Fprintf(&b, "- `%s`\n", pvc)
+	}
+
+	b.WriteString("\n## Controllers\n\n")
+	b.WriteString("| Controller | Replicas before | Status |\n")
+	b.WriteString("|---|---|---|\n")
+	for _, ctrl := range r.controllers {
+		replicas := "-"
+		if n, ok := r.replicasBefore[ctrl]; ok {
+			replicas = fmt.Sprint(n)
+		}
+		fmt.Fprintf(&b, "| `%v` | %s | %s |\n", ctrl, replicas, r.status(ctrl))
+	}
+
+	verb := "were"
+	if r.dryRun {
+		verb = "would be"
+	}
+	fmt.Fprintf(&b, "\n## Scale down commands\n\nThese are the equivalent commands that %s issued:\n\n```shell\n", verb)
+	for _, ctrl := range r.modified() {
+		b.WriteString(scaleDownCommand(ctrl, r.replicas) + "\n")
+	}
+	b.WriteString("```\n")
+
+	b.WriteString("\n## Restore commands\n\n")
+	b.WriteString("Run these to scale the controllers back up once the volumes are no longer needed elsewhere:\n\n```shell\n")
+	var deletedPods []common.ControllerRef
+	for _, ctrl := range r.modified() {
+		if ctrl.Kind == common.KindPod {
+			deletedPods = append(deletedPods, ctrl)
+			continue
+		}
+		b.WriteString(restoreCommand(ctrl, r.replicasBefore[ctrl]) + "\n")
+	}
+	b.WriteString("```\n")
+	if len(deletedPods) > 0 {
+		b.WriteString("\nThese standalone pods were deleted, and must be recreated from their original manifests:\n\n")
+		for _, pod := range deletedPods {
+			fmt.Fprintf(&b, "- `%v`\n", pod)
+		}
+	}
+
+	b.WriteString("\n## Rollback procedure\n\n")
+	b.WriteString("1. Run the restore commands above.\n")
+	b.WriteString("2. Check that the pods are running again, e.g. `kubectl get pods --namespace=<namespace> --watch`.\n")
+	b.WriteString("3. If nodes were cordoned with `--cordon`, uncordon them with `kubectl unmount --uncordon`.\n")
+	fmt.Fprintf(&b, "4. If this runbook is lost, the original number of replicas of each scaled down controller is "+
+		"recorded in its `%s` annotation.\n", common.AnnotationOriginalReplicas)
+
+	if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+		return fmt.Errorf("failed to write runbook: %w", err)
+	}
+	return nil
+}
+
+// modified returns the controllers that were (or would be, in dry-run mode) scaled down or deleted.
+func (r runbook) modified() []common.ControllerRef {
+	var modified []common.ControllerRef
+	for _, ctrl := range r.controllers {
+		if r.status(ctrl) == "scaled down" || r.status(ctrl) == "would be scaled down" {
+			modified = append(modified, ctrl)
+		}
+	}
+	return modified
+}
+
+func (r runbook) status(ctrl common.ControllerRef) string {
+	switch {
+	case slices.Contains(r.result.Failed, ctrl):
+		return "failed"
+	case slices.Contains(r.result.Skipped, ctrl):
+		return "skipped"
+	case slices.Contains(r.result.Scaled, ctrl), slices.Contains(r.result.DeletedPods, ctrl):
+		if r.dryRun {
+			return "would be scaled down"
+		}
+		return "scaled down"
+	default:
+		return "not scaled down"
+	}
+}
+
+// scaleDownCommand returns the kubectl command equivalent to scaling down the given controller to the given number
+// of replicas.
+func scaleDownCommand(ctrl common.ControllerRef, replicas int32) string {
+	switch ctrl.Kind {
+	case common.KindPod:
+		return fmt.Sprintf("kubectl delete pod %s --namespace=%s", ctrl.Name, ctrl.Namespace)
+	case common.KindJob, common.KindCronJob:
+		return fmt.Sprintf(`kubectl patch %s %s --namespace=%s --type=merge -p '{"spec":{"suspend":true}}'`,
+			strings.ToLower(ctrl.Kind), ctrl.Name, ctrl.Namespace)
+	default:
+		return fmt.Sprintf("kubectl scale %s/%s --namespace=%s --replicas=%d", strings.ToLower(ctrl.Kind), ctrl.Name,
+			ctrl.Namespace, replicas)
+	}
+}
+
+// restoreCommand returns the kubectl command that reverses scaling down the given controller.
+func restoreCommand(ctrl common.ControllerRef, replicas int32) string {
+	switch ctrl.Kind {
+	case common.KindJob, common.KindCronJob:
+		return fmt.Sprintf(`kubectl patch %s %s --namespace=%s --type=merge -p '{"spec":{"suspend":false}}'`,
+			strings.ToLower(ctrl.Kind), ctrl.Name, ctrl.Namespace)
+	default:
+		return fmt.Sprintf("kubectl scale %s/%s --namespace=%s --replicas=%d", strings.ToLower(ctrl.Kind), ctrl.Name,
+			ctrl.Namespace, replicas)
+	}
+}
@@ -0,0 +1,49 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunbook(t *testing.T) {
+	deployment := common.ControllerRef{Kind: common.KindDeployment, Namespace: "test-ns", Name: "web"}
+	cronJob := common.ControllerRef{Kind: common.KindCronJob, Namespace: "test-ns", Name: "backup"}
+	pod := common.ControllerRef{Kind: common.KindPod, Namespace: "test-ns", Name: "debug"}
+	daemonSet := common.ControllerRef{Kind: common.KindDaemonSet, Namespace: "test-ns", Name: "agent"}
+	r := runbook{
+		cluster:        "prod-cluster",
+		version:        "1.2.3",
+		generatedAt:    time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC),
+		pvcs:           []string{"test-ns/data"},
+		controllers:    []common.ControllerRef{deployment, cronJob, pod, daemonSet},
+		replicasBefore: map[common.ControllerRef]int32{deployment: 3},
+		result: &Result{
+			Scaled:      []common.ControllerRef{deployment, cronJob},
+			DeletedPods: []common.ControllerRef{pod},
+			Skipped:     []common.ControllerRef{daemonSet},
+		},
+	}
+
+	path := filepath.Join(t.TempDir(), "runbook.md")
+	require.NoError(t, r.write(path))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	runbook := string(data)
+
+	require.Contains(t, runbook, "- **Cluster:** prod-cluster\n")
+	require.Contains(t, runbook, "- **kubectl-unmount version:** 1.2.3\n")
+	require.Contains(t, runbook, "- **Generated at:** 2024-01-15T10:00:00Z\n")
+	require.Contains(t, runbook, "| `Deployment/test-ns/web` | 3 | scaled down |\n")
+	require.Contains(t, runbook, "| `DaemonSet/test-ns/agent` | - | skipped |\n")
+	require.Contains(t, runbook, "kubectl scale deployment/web --namespace=test-ns --replicas=0\n")
+	require.Contains(t, runbook, "kubectl delete pod debug --namespace=test-ns\n")
+	require.Contains(t, runbook, "kubectl scale deployment/web --namespace=test-ns --replicas=3\n")
+	require.Contains(t, runbook, `kubectl patch cronjob backup --namespace=test-ns --type=merge -p '{"spec":{"suspend":false}}'`)
+	require.Contains(t, runbook, "must be recreated from their original manifests:\n\n- `Pod/test-ns/debug`\n")
+	require.NotContains(t, runbook, "daemonset/agent")
+}
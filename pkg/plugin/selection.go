@@ -0,0 +1,238 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/common"
+	"github.com/dancavallaro/kubectl-unmount/pkg/discovery"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Selection describes the resources a run would affect: the targeted PVCs, the pods mounting them, and the
+// controllers of those pods.
+type Selection struct {
+	// PVCs maps each namespace to the names of the targeted PVCs in it.
+	PVCs map[string][]string
+	// Pods are the pods mounting the targeted PVCs.
+	Pods []corev1.Pod
+	// Controllers are the controllers of the pods, sorted by name. These include the ones that won't be scaled
+	// down because of --exclude or --protect-annotation, which are only applied when acting on them.
+	Controllers []common.ControllerRef
+	// PodsByController maps each of the controllers to its pods.
+	PodsByController map[common.ControllerRef][]corev1.Pod
+	// AlreadyScaled are the controllers found already scaled down with --skip-if-scaled, when no pods are
+	// mounting the targeted PVCs.
+	AlreadyScaled []common.ControllerRef
+}
+
+// FindAffectedResources finds the resources that RunPlugin would act on with the given configuration, using the
+// given clientset, without modifying anything. Only the selection flags are used: flags that only apply when
+// acting on the resources (and the modes that don't select any, like --restore-from) are ignored, as are
+// --contexts and --all-contexts.
+func FindAffectedResources(ctx context.Context, clientset kubernetes.Interface, cfg *ConfigFlags) (*Selection, error) {
+	if err := setDefaults(cfg); err != nil {
+		return nil, err
+	}
+	if err := validate(cfg); err != nil {
+		return nil, err
+	}
+	filter, podFilter := newFilters(cfg)
+	return selectResources(ctx, cfg, discovery.New(clientset, cfg.logger), filter, podFilter, newTimings())
+}
+
+// newFilters creates the PVC and pod filters for the selection flags.
+func newFilters(cfg *ConfigFlags) (discovery.PVCFilter, discovery.PodFilter) {
+	filter := discovery.PVCFilter{BoundOnly: !*cfg.IncludeUnbound}
+	if cfg.Namespace != nil {
+		filter.Namespace = *cfg.Namespace
+	}
+	if cfg.StorageClass != nil {
+		filter.StorageClasses = *cfg.StorageClass
+	}
+	if isSet(cfg.AccessMode) {
+		filter.AccessMode = discovery.AccessModes[*cfg.AccessMode]
+	}
+	if isSet(cfg.CSIDriver) {
+		filter.CSIDriver = *cfg.CSIDriver
+	}
+	if isSet(cfg.MinSize) {
+		minSize := resource.MustParse(*cfg.MinSize)
+		filter.MinSize = &minSize
+	}
+	if isSet(cfg.PVCRegex) {
+		filter.NameRegex = regexp.MustCompile(*cfg.PVCRegex)
+	}
+
+	podFilter := discovery.PodFilter{}
+	if cfg.Selector != nil {
+		podFilter.LabelSelector = *cfg.Selector
+	}
+	if cfg.FieldSelector != nil {
+		podFilter.FieldSelector = *cfg.FieldSelector
+	}
+	if isSet(cfg.AnnotationSelector) {
+		podFilter.Annotations, _ = parseAnnotationSelector(*cfg.AnnotationSelector)
+	}
+	if cfg.PodStatusFilter != nil {
+		for _, phase := range *cfg.PodStatusFilter {
+			podFilter.Phases = append(podFilter.Phases, corev1.PodPhase(phase))
+		}
+	}
+	if cfg.NodeName != nil && *cfg.NodeName != "" {
+		nodeSelector := fields.OneTermEqualSelector("spec.nodeName", *cfg.NodeName)
+		if podFilter.FieldSelector != "" {
+			nodeSelector = fields.AndSelectors(fields.ParseSelectorOrDie(podFilter.FieldSelector), nodeSelector)
+		}
+		podFilter.FieldSelector = nodeSelector.String()
+	}
+
+	return filter, podFilter
+}
+
+// selectResources finds the targeted PVCs, the pods mounting them, and their controllers. If there's nothing to
+// do at any step, the Selection only has what was found so far.
+func selectResources(ctx context.Context, cfg *ConfigFlags, finder discovery.Finder, filter discovery.PVCFilter,
+	podFilter discovery.PodFilter, timer *timings) (*Selection, error) {
+	timer.phase("finding PVCs")
+	cfg.logger.Info("Finding volumes...")
+	sel := &Selection{}
+	var pvcsPerNs map[string][]string
+	var targets stdinTargets
+	switch {
+	case *cfg.FromStdin:
+		var err error
+		targets, pvcsPerNs, err = readTargets(ctx, cfg, finder, filter)
+		if err != nil {
+			return nil, err
+		}
+		if len(pvcsPerNs) == 0 {
+			cfg.logger.Info("No targets read from stdin, nothing to do")
+			return sel, nil
+		}
+	case *cfg.PVName != "":
+		claim, err := finder.FindPVCForPV(ctx, *cfg.PVName)
+		if err != nil {
+			return nil, err
+		}
+		if claim == nil {
+			cfg.logger.Info("Nothing is mounting PV %s, nothing to do", *cfg.PVName)
+			return sel, nil
+		}
+		pvcsPerNs = map[string][]string{
+			claim.Namespace: {claim.Name},
+		}
+	case len(*cfg.PVCName) > 0:
+		pvcsPerNs = map[string][]string{
+			*cfg.Namespace: slices.Compact(slices.Sorted(slices.Values(*cfg.PVCName))),
+		}
+	default:
+		var err error
+		if isSet(cfg.NamespaceSelector) {
+			pvcsPerNs, err = findPVCsInSelectedNamespaces(ctx, cfg, finder, filter)
+		} else {
+			pvcsPerNs, err = finder.FindPVCs(ctx, filter)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(pvcsPerNs) == 0 {
+			cfg.logger.Info("No matching PVCs found, nothing to do")
+			return sel, nil
+		}
+		if filter.NameRegex != nil {
+			// Let the operator sanity-check the pattern before confirming
+			var matched []string
+			for ns, pvcs := range pvcsPerNs {
+				for _, pvc := range pvcs {
+					matched = append(matched, fmt.Sprintf("%s/%s", ns, pvc))
+				}
+			}
+			slices.Sort(matched)
+			cfg.logger.Info("PVCs matching --pvc-regex=%s: %s", *cfg.PVCRegex, strings.Join(matched, ", "))
+		}
+	}
+
+	if *cfg.PreValidation {
+		cfg.logger.Info("Validating PVC mounts...")
+		var err error
+		pvcsPerNs, err = finder.FindMountedPVCs(ctx, pvcsPerNs, podFilter)
+		if err != nil {
+			return nil, err
+		}
+		if len(pvcsPerNs) == 0 {
+			cfg.logger.Info("No mounted PVCs found, nothing to do")
+			return sel, nil
+		}
+	}
+
+	if *cfg.CloudProvider != "" {
+		if err := logCloudVolumes(ctx, cfg, finder, pvcsPerNs); err != nil {
+			return nil, err
+		}
+	}
+
+	timer.phase("finding pods")
+	cfg.logger.Info("Finding pods...")
+	sel.PVCs = pvcsPerNs
+	pods, err := finder.FindPodsUsingPVCs(ctx, pvcsPerNs, podFilter)
+	if err != nil {
+		return nil, err
+	}
+	sel.Pods = pods
+	if len(pods) == 0 {
+		if *cfg.SkipIfScaled {
+			scaled, err := finder.FindScaledDownControllers(ctx, pvcsPerNs)
+			if err != nil {
+				return nil, err
+			}
+			for _, ctrl := range scaled {
+				cfg.logger.Info("%v is already scaled down, skipping", ctrl)
+			}
+			sel.AlreadyScaled = scaled
+			if len(scaled) > 0 {
+				return sel, nil
+			}
+		}
+		cfg.logger.Info("No pods found, nothing to do")
+		return sel, nil
+	}
+	cfg.logger.Info("Found %d pods to scale down", len(pods))
+	if *cfg.Verbose {
+		logVolumeMounts(cfg.logger, pods, pvcsPerNs)
+	}
+	for _, pod := range pods {
+		for _, pvc := range discovery.EphemeralPVCs(pod) {
+			if slices.Contains(pvcsPerNs[pod.Namespace], pvc) {
+				cfg.logger.Info("  PVC %s/%s is an ephemeral volume owned by Pod %s, it will be garbage-collected when the pod is deleted",
+					pod.Namespace, pvc, pod.Name)
+			}
+		}
+	}
+
+	timer.phase("finding controllers")
+	podsByController, err := finder.GroupPodsByController(ctx, pods)
+	if err != nil {
+		return nil, err
+	}
+	if *cfg.FromStdin {
+		targets.narrow(cfg, podsByController)
+		pods = podsOf(slices.Collect(maps.Keys(podsByController)), podsByController)
+		sel.Pods = pods
+	}
+	controllers := slices.SortedFunc(maps.Keys(podsByController), func(a, b common.ControllerRef) int {
+		return strings.Compare(a.String(), b.String())
+	})
+	sel.PodsByController, sel.Controllers = podsByController, controllers
+	if len(controllers) == 0 {
+		cfg.logger.Info("No controllers found to scale down")
+	}
+	return sel, nil
+}
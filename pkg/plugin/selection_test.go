@@ -0,0 +1,74 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/common"
+	"github.com/stretchr/testify/require"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
+)
+
+func TestFindAffectedResources(t *testing.T) {
+	newPVC := func(name, storageClass string) *corev1.PersistentVolumeClaim {
+		return &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test-ns"},
+			Spec:       corev1.PersistentVolumeClaimSpec{StorageClassName: ptr.To(storageClass)},
+			Status:     corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimBound},
+		}
+	}
+	owner := metav1.OwnerReference{APIVersion: "batch/v1", Kind: common.KindJob, Name: "batch"}
+	clientset := fake.NewClientset(
+		newPVC("data", storageClassName),
+		newPVC("scratch", "other"),
+		&batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "batch", Namespace: "test-ns"}},
+		newWatchedPod("batch-1", "data", owner),
+		newWatchedPod("standalone", "data"),
+		newWatchedPod("unrelated", "scratch"),
+	)
+
+	var out, logs bytes.Buffer
+	cfg := newTestConfig(&out, &logs)
+	sel, err := FindAffectedResources(context.Background(), clientset, cfg)
+	require.NoError(t, err)
+
+	require.Equal(t, map[string][]string{"test-ns": {"data"}}, sel.PVCs)
+	require.Len(t, sel.Pods, 2)
+	job := common.ControllerRef{Kind: common.KindJob, Namespace: "test-ns", Name: "batch"}
+	pod := common.ControllerRef{Kind: common.KindPod, Namespace: "test-ns", Name: "standalone"}
+	require.Equal(t, []common.ControllerRef{job, pod}, sel.Controllers)
+	require.Equal(t, "batch-1", sel.PodsByController[job][0].Name)
+
+	// Nothing is modified
+	for _, action := range clientset.Actions() {
+		require.Contains(t, []string{"get", "list"}, action.GetVerb(), "unexpected %s of %s", action.GetVerb(), action.GetResource())
+	}
+	j, err := clientset.BatchV1().Jobs("test-ns").Get(context.Background(), "batch", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Nil(t, j.Spec.Suspend)
+	require.Empty(t, out.String())
+}
+
+func TestFindAffectedResourcesWithMinimalConfig(t *testing.T) {
+	clientset := fake.NewClientset(
+		&corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: "data", Namespace: "test-ns"},
+			Spec:       corev1.PersistentVolumeClaimSpec{StorageClassName: ptr.To(storageClassName)},
+			Status:     corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimBound},
+		},
+		newWatchedPod("standalone", "data"),
+	)
+
+	// The flags that aren't set get their defaults
+	sel, err := FindAffectedResources(context.Background(), clientset, &ConfigFlags{
+		StorageClass: &[]string{storageClassName},
+		LogLevel:     common.StringP("error"),
+	})
+	require.NoError(t, err)
+	require.Equal(t, []common.ControllerRef{{Kind: common.KindPod, Namespace: "test-ns", Name: "standalone"}}, sel.Controllers)
+}
@@ -0,0 +1,97 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/discovery"
+	"k8s.io/client-go/kubernetes"
+)
+
+// statusLine is a line of JSON Lines output describing a controller scaled down by kubectl-unmount.
+type statusLine struct {
+	Kind             string `json:"kind"`
+	Namespace        string `json:"namespace"`
+	Name             string `json:"name"`
+	OriginalReplicas string `json:"originalReplicas"`
+	ScaledAt         string `json:"scaledAt"`
+	TriggeringPVC    string `json:"triggeringPVC"`
+}
+
+// RunStatus prints the controllers in the target namespace (or all namespaces) that are currently scaled down
+// by kubectl-unmount, according to their annotations. It doesn't modify anything.
+func RunStatus(ctx context.Context, cfg *ConfigFlags) error {
+	if err := setDefaults(cfg); err != nil {
+		return err
+	}
+	if *cfg.Output != "" && *cfg.Output != OutputNDJSON && *cfg.Output != OutputJSONLines {
+		return fmt.Errorf("invalid output format %q for status, must be one of %v", *cfg.Output,
+			[]string{OutputNDJSON, OutputJSONLines})
+	}
+
+	config, err := restConfig(cfg)
+	if err != nil {
+		return err
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to create clientset: %w", err)
+	}
+
+	finder := discovery.New(clientset, cfg.logger)
+	unmounted, err := finder.FindUnmountedControllers(ctx, *cfg.Namespace)
+	if err != nil {
+		return err
+	}
+	if *cfg.Output != "" {
+		return printStatusLines(cfg.out, unmounted)
+	}
+	if len(unmounted) == 0 {
+		cfg.logger.Info("No controllers scaled down by kubectl-unmount found")
+		return nil
+	}
+	return printStatusTable(cfg.out, unmounted)
+}
+
+// printStatusTable prints the given controllers as a table, like kubectl get.
+func printStatusTable(w io.Writer, unmounted []discovery.UnmountedController) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 3, ' ', 0)
+	fmt.Fprintln(tw, "KIND\tNAMESPACE\tNAME\tORIGINAL REPLICAS\tSCALED AT\tTRIGGERING PVC")
+	for _, ctrl := range unmounted {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", ctrl.Kind, ctrl.Namespace, ctrl.Name, ctrl.OriginalReplicas,
+			orNone(ctrl.ScaledAt), orNone(ctrl.TriggeringPVC))
+	}
+	if err := tw.Flush(); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	return nil
+}
+
+// printStatusLines prints the given controllers as JSON Lines, with one JSON object per controller.
+func printStatusLines(w io.Writer, unmounted []discovery.UnmountedController) error {
+	enc := json.NewEncoder(w)
+	for _, ctrl := range unmounted {
+		line := statusLine{
+			Kind:             ctrl.Kind,
+			Namespace:        ctrl.Namespace,
+			Name:             ctrl.Name,
+			OriginalReplicas: ctrl.OriginalReplicas,
+			ScaledAt:         ctrl.ScaledAt,
+			TriggeringPVC:    ctrl.TriggeringPVC,
+		}
+		if err := enc.Encode(line); err != nil {
+			return fmt.Errorf("failed to write output: %w", err)
+		}
+	}
+	return nil
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "<none>"
+	}
+	return s
+}
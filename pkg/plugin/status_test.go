@@ -0,0 +1,28 @@
+package plugin
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/common"
+	"github.com/dancavallaro/kubectl-unmount/pkg/discovery"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrintStatus(t *testing.T) {
+	unmounted := []discovery.UnmountedController{{
+		ControllerRef:    common.ControllerRef{Kind: common.KindDeployment, Namespace: "test-ns", Name: "my-app"},
+		OriginalReplicas: "3",
+		ScaledAt:         "2024-01-15T10:00:00Z",
+	}}
+
+	var table bytes.Buffer
+	require.NoError(t, printStatusTable(&table, unmounted))
+	require.Equal(t, "KIND         NAMESPACE   NAME     ORIGINAL REPLICAS   SCALED AT              TRIGGERING PVC\n"+
+		"Deployment   test-ns     my-app   3                   2024-01-15T10:00:00Z   <none>\n", table.String())
+
+	var lines bytes.Buffer
+	require.NoError(t, printStatusLines(&lines, unmounted))
+	require.JSONEq(t, `{"kind":"Deployment","namespace":"test-ns","name":"my-app","originalReplicas":"3",
+		"scaledAt":"2024-01-15T10:00:00Z","triggeringPVC":""}`, lines.String())
+}
@@ -0,0 +1,110 @@
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/common"
+	"github.com/dancavallaro/kubectl-unmount/pkg/discovery"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// stdinTargets are the targets read with --from-stdin: PVCs, whose pods are all scaled down, and controllers,
+// which are scaled down (only) if they use any PVC matching the other filters.
+type stdinTargets struct {
+	pvcsPerNs   map[string][]string
+	controllers []common.ControllerRef
+}
+
+// readTargets reads the targets given with --from-stdin, one per line, as either a PVC name (in --namespace) or
+// a kind/namespace/name controller reference. Lines that don't resolve to a PVC or controller are skipped with a
+// warning. Returns the targets, and the PVCs to look for pods using.
+func readTargets(ctx context.Context, cfg *ConfigFlags, finder discovery.Finder, filter discovery.PVCFilter) (stdinTargets, map[string][]string, error) {
+	targets := stdinTargets{pvcsPerNs: make(map[string][]string)}
+	scanner := bufio.NewScanner(cfg.in)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.Split(line, "/")
+		switch {
+		case len(parts) == 1 && *cfg.Namespace == "":
+			cfg.logger.Warn("Skipping PVC %s from stdin, PVCs can only be given with --namespace", line)
+		case len(parts) == 1:
+			exists, err := finder.PVCExists(ctx, *cfg.Namespace, line)
+			if err != nil {
+				return stdinTargets{}, nil, err
+			}
+			if !exists {
+				cfg.logger.Warn("Skipping PVC %s/%s from stdin, it doesn't exist", *cfg.Namespace, line)
+				continue
+			}
+			targets.pvcsPerNs[*cfg.Namespace] = append(targets.pvcsPerNs[*cfg.Namespace], line)
+		case len(parts) == 3 && !slices.Contains(parts, ""):
+			targets.controllers = append(targets.controllers, common.ControllerRef{
+				Kind: canonicalKind(parts[0]), Namespace: parts[1], Name: parts[2],
+			})
+		default:
+			cfg.logger.Warn("Skipping %q from stdin, it isn't a PVC name or a kind/namespace/name controller", line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return stdinTargets{}, nil, fmt.Errorf("failed to read targets from stdin: %w", err)
+	}
+
+	// Controllers are found through the PVCs they use, so look for pods using any matching PVC in their namespaces
+	pvcsPerNs := maps.Clone(targets.pvcsPerNs)
+	var namespaces []string
+	for _, ctrl := range targets.controllers {
+		namespaces = append(namespaces, ctrl.Namespace)
+	}
+	for _, ns := range slices.Compact(slices.Sorted(slices.Values(namespaces))) {
+		nsFilter := filter
+		nsFilter.Namespace = ns
+		found, err := finder.FindPVCs(ctx, nsFilter)
+		if err != nil {
+			return stdinTargets{}, nil, err
+		}
+		pvcsPerNs[ns] = slices.Concat(pvcsPerNs[ns], found[ns])
+	}
+	for ns, pvcs := range pvcsPerNs {
+		pvcsPerNs[ns] = slices.Compact(slices.Sorted(slices.Values(pvcs)))
+	}
+	return targets, pvcsPerNs, nil
+}
+
+// canonicalKind returns the known kind matching the given kind case-insensitively (e.g. "Deployment" for
+// "deployment"), or the given kind if it isn't known.
+func canonicalKind(kind string) string {
+	for _, known := range []string{common.KindPod, common.KindDeployment, common.KindStatefulSet, common.KindReplicaSet,
+		common.KindReplicationController, common.KindDaemonSet, common.KindJob, common.KindCronJob} {
+		if strings.EqualFold(kind, known) {
+			return known
+		}
+	}
+	return kind
+}
+
+// narrow filters the given controllers (and their pods) down to the targets: the listed controllers, and
+// controllers using any of the listed PVCs. Listed controllers that weren't found are skipped with a warning.
+func (t stdinTargets) narrow(cfg *ConfigFlags, podsByController map[common.ControllerRef][]corev1.Pod) {
+	found := make(map[common.ControllerRef]bool)
+	for ctrl, pods := range podsByController {
+		target := common.ControllerRef{Kind: ctrl.Kind, Namespace: ctrl.Namespace, Name: ctrl.Name}
+		if slices.Contains(t.controllers, target) {
+			found[target] = true
+		} else if len(discovery.PVCsUsedBy(pods, t.pvcsPerNs)) == 0 {
+			delete(podsByController, ctrl)
+		}
+	}
+	for _, target := range t.controllers {
+		if !found[target] {
+			cfg.logger.Warn("Skipping %v from stdin, it doesn't exist or doesn't use any matching PVC", target)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/common"
+	"github.com/dancavallaro/kubectl-unmount/pkg/discovery"
+	"github.com/dancavallaro/kubectl-unmount/pkg/logger"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestReadTargets(t *testing.T) {
+	newPVC := func(namespace, name string) *corev1.PersistentVolumeClaim {
+		return &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+	}
+	clientset := fake.NewClientset(newPVC("test-ns", "data-0"), newPVC("test-ns", "data-1"), newPVC("other-ns", "cache"))
+	var logs bytes.Buffer
+	log := logger.NewLogger(&logs, logger.LevelInfo)
+	cfg := &ConfigFlags{
+		ConfigFlags: genericclioptions.ConfigFlags{Namespace: common.StringP("test-ns")},
+		logger:      log,
+		in:          strings.NewReader("data-0\n\n# comment\nmissing-pvc\ndeployment/other-ns/cache-app\ndeployment/other-ns/gone\nnot/a-target\n"),
+	}
+
+	targets, pvcsPerNs, err := readTargets(context.Background(), cfg, discovery.New(clientset, log), discovery.PVCFilter{})
+	require.NoError(t, err)
+	require.Equal(t, map[string][]string{"test-ns": {"data-0"}, "other-ns": {"cache"}}, pvcsPerNs)
+	require.Contains(t, logs.String(), "Skipping PVC test-ns/missing-pvc from stdin, it doesn't exist")
+	require.Contains(t, logs.String(), `Skipping "not/a-target" from stdin`)
+
+	listed := common.ControllerRef{Kind: common.KindDeployment, Namespace: "other-ns", Name: "cache-app"}
+	usingPVC := common.ControllerRef{Kind: common.KindStatefulSet, Namespace: "test-ns", Name: "db"}
+	unlisted := common.ControllerRef{Kind: common.KindDeployment, Namespace: "other-ns", Name: "cache-reader"}
+	newPod := func(namespace, pvc string) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+			Spec: corev1.PodSpec{Volumes: []corev1.Volume{{
+				Name:         "data",
+				VolumeSource: corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: pvc}},
+			}}},
+		}
+	}
+	podsByController := map[common.ControllerRef][]corev1.Pod{
+		listed:   {newPod("other-ns", "cache")},
+		usingPVC: {newPod("test-ns", "data-0")},
+		unlisted: {newPod("other-ns", "cache")},
+	}
+	targets.narrow(cfg, podsByController)
+	require.ElementsMatch(t, []common.ControllerRef{listed, usingPVC}, slices.Collect(maps.Keys(podsByController)))
+	require.Contains(t, logs.String(), "Skipping Deployment/other-ns/gone from stdin, it doesn't exist or doesn't use any matching PVC")
+}
@@ -0,0 +1,43 @@
+package plugin
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+func TestRunPluginCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	start := time.Now()
+	_, _, _, err := runPluginWithContext(ctx)
+	require.ErrorIs(t, err, context.Canceled)
+	require.Less(t, time.Since(start), time.Second)
+}
+
+func TestRunPluginTimeout(t *testing.T) {
+	// An API server that never responds
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	kubeconfig := filepath.Join(t.TempDir(), "kubeconfig")
+	require.NoError(t, os.WriteFile(kubeconfig, []byte(testKubeconfig), 0o600))
+
+	_, _, _, err := runPlugin(func(cfg *ConfigFlags) {
+		cfg.ConfigFlags = *genericclioptions.NewConfigFlags(false)
+		*cfg.KubeConfig = kubeconfig
+		*cfg.APIServer = server.URL
+		*cfg.Timeout = 100 * time.Millisecond
+	})
+	require.EqualError(t, err, "operation timed out after 100ms")
+}
@@ -0,0 +1,45 @@
+package plugin
+
+import (
+	"time"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/logger"
+)
+
+// timings records how long each phase of a run takes, for --timings. Starting a phase ends the previous one, so
+// that returning early from any phase doesn't need to be accounted for.
+type timings struct {
+	start   time.Time
+	current string
+	since   time.Time
+	phases  []phaseTiming
+}
+
+type phaseTiming struct {
+	name     string
+	duration time.Duration
+}
+
+func newTimings() *timings {
+	now := time.Now()
+	return &timings{start: now, since: now}
+}
+
+// phase ends the current phase (if any), and starts the named one.
+func (t *timings) phase(name string) {
+	now := time.Now()
+	if t.current != "" {
+		t.phases = append(t.phases, phaseTiming{name: t.current, duration: now.Sub(t.since)})
+	}
+	t.current, t.since = name, now
+}
+
+// log ends the current phase, and logs how long each phase (and the whole run) took.
+func (t *timings) log(log *logger.Logger) {
+	t.phase("")
+	log.Info("Timings:")
+	for _, p := range t.phases {
+		log.Info("  %s: %v", p.name, p.duration.Round(time.Millisecond))
+	}
+	log.Info("  total: %v", time.Since(t.start).Round(time.Millisecond))
+}
@@ -0,0 +1,26 @@
+package plugin
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/logger"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimings(t *testing.T) {
+	timer := newTimings()
+	timer.phase("finding pods")
+	time.Sleep(10 * time.Millisecond)
+	timer.phase("scaling down")
+
+	var logs bytes.Buffer
+	timer.log(logger.NewLogger(&logs, logger.LevelInfo))
+	require.Len(t, timer.phases, 2)
+	require.Equal(t, "finding pods", timer.phases[0].name)
+	require.GreaterOrEqual(t, timer.phases[0].duration, 10*time.Millisecond)
+	require.Contains(t, logs.String(), "Timings:\n  finding pods: ")
+	require.Contains(t, logs.String(), "\n  scaling down: ")
+	require.Contains(t, logs.String(), "\n  total: ")
+}
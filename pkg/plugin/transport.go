@@ -0,0 +1,62 @@
+package plugin
+
+import (
+	"net/http"
+	"net/http/httputil"
+	"regexp"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/logger"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// verbosityRequests logs each API request made by the plugin.
+	verbosityRequests = 1
+	// verbosityBodies also logs the full request and response of each API request.
+	verbosityBodies = 2
+)
+
+var authorizationHeader = regexp.MustCompile(`(?mi)^(Authorization:).*$`)
+
+// loggingTransport logs the API requests made through it, to help debug permission errors. Credentials
+// are redacted.
+type loggingTransport struct {
+	next http.RoundTripper
+	log  *logger.Logger
+}
+
+func (t *loggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.log.Verbose(verbosityRequests, "%s %s", req.Method, req.URL.RequestURI())
+	if t.log.IsVerbose(verbosityBodies) {
+		if dump, err := httputil.DumpRequestOut(req, true); err == nil {
+			t.log.Verbose(verbosityBodies, "%s", authorizationHeader.ReplaceAll(dump, []byte("$1 <redacted>")))
+		}
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		t.log.Verbose(verbosityRequests, "%s %s failed: %v", req.Method, req.URL.RequestURI(), err)
+		return nil, err
+	}
+	t.log.Verbose(verbosityRequests, "%s %s: %s", req.Method, req.URL.RequestURI(), resp.Status)
+	if t.log.IsVerbose(verbosityBodies) {
+		if dump, err := httputil.DumpResponse(resp, true); err == nil {
+			t.log.Verbose(verbosityBodies, "%s", dump)
+		}
+	}
+	return resp, nil
+}
+
+// rateLimitedTransport waits for the limiter before each API request, so that the plugin doesn't overwhelm
+// (or get throttled by) the API server on large clusters.
+type rateLimitedTransport struct {
+	next    http.RoundTripper
+	limiter *rate.Limiter
+}
+
+func (t *rateLimitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.limiter.Wait(req.Context()); err != nil {
+		return nil, err
+	}
+	return t.next.RoundTrip(req)
+}
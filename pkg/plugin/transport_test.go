@@ -0,0 +1,59 @@
+package plugin
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/logger"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestLoggingTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"kind":"Scale"}`))
+	}))
+	defer server.Close()
+
+	for _, verbosity := range []int{verbosityRequests, verbosityBodies} {
+		var logs bytes.Buffer
+		log := logger.NewLogger(&logs, logger.LevelInfo)
+		log.SetVerbosity(verbosity)
+		client := &http.Client{Transport: &loggingTransport{next: http.DefaultTransport, log: log}}
+
+		req, err := http.NewRequest(http.MethodGet, server.URL+"/apis/apps/v1/namespaces/test-ns/deployments/test/scale", nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer secret-token")
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+
+		require.Contains(t, logs.String(), "GET /apis/apps/v1/namespaces/test-ns/deployments/test/scale: 200 OK")
+		require.NotContains(t, logs.String(), "secret-token")
+		if verbosity == verbosityBodies {
+			require.Contains(t, logs.String(), "Authorization: <redacted>")
+			require.Contains(t, logs.String(), `{"kind":"Scale"}`)
+		} else {
+			require.NotContains(t, logs.String(), `{"kind":"Scale"}`)
+		}
+	}
+}
+
+func TestRateLimitedTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	// After the burst of 10, the other 10 requests are spread out at 5 per second
+	limiter := rate.NewLimiter(5, 10)
+	client := &http.Client{Transport: &rateLimitedTransport{next: http.DefaultTransport, limiter: limiter}}
+	start := time.Now()
+	for range 20 {
+		resp, err := client.Get(server.URL)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+	}
+	require.GreaterOrEqual(t, time.Since(start), 1900*time.Millisecond)
+}
@@ -0,0 +1,68 @@
+package plugin
+
+import (
+	"testing"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/common"
+	"github.com/stretchr/testify/require"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+func TestValidateSelection(t *testing.T) {
+	tests := []struct {
+		name         string
+		namespace    string
+		pvc          []string
+		pv           string
+		pvcRegex     string
+		nsSelector   string
+		storageClass []string
+		node         string
+		wantErr      string
+	}{
+		{name: "no selection", wantErr: "must specify exactly one of --pvc, --pv, --storage-class"},
+		{name: "storage class", storageClass: []string{"standard"}},
+		{name: "PVCs", namespace: "test-ns", pvc: []string{"test-pvc-0", "test-pvc-1"}},
+		{name: "PV", pv: "test-pv"},
+		{name: "all PVCs in namespace", namespace: "test-ns"},
+		{name: "all PVCs on node", node: "node-1"},
+		{
+			name:         "PVC and storage class",
+			pvc:          []string{"test-pvc"},
+			storageClass: []string{"standard"},
+			wantErr:      "got --pvc and --storage-class",
+		},
+		{name: "PV and PVC", pv: "test-pv", pvc: []string{"test-pvc"}, wantErr: "got --pvc and --pv"},
+		{name: "PVC regex", namespace: "test-ns", pvcRegex: "^data-mysql-[0-9]+$"},
+		{name: "PVC regex and PV", pv: "test-pv", pvcRegex: "^data-", wantErr: "got --pv and --pvc-regex"},
+		{name: "invalid PVC regex", pvcRegex: "data-(", wantErr: "invalid --pvc-regex"},
+		{name: "all PVCs in labeled namespaces", nsSelector: "purpose=batch"},
+		{name: "namespace selector and storage class", nsSelector: "purpose=batch", storageClass: []string{"standard"}},
+		{
+			name:       "namespace selector and namespace",
+			namespace:  "test-ns",
+			nsSelector: "purpose=batch",
+			wantErr:    "--namespace-selector and --namespace can't be used together",
+		},
+		{name: "namespace selector and PV", pv: "test-pv", nsSelector: "purpose=batch", wantErr: "can't be used together with --pv"},
+		{name: "invalid namespace selector", nsSelector: "purpose in (", wantErr: "invalid --namespace-selector"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateSelection(&ConfigFlags{
+				ConfigFlags:       genericclioptions.ConfigFlags{Namespace: common.StringP(tt.namespace)},
+				PVCName:           &tt.pvc,
+				PVName:            common.StringP(tt.pv),
+				PVCRegex:          common.StringP(tt.pvcRegex),
+				NamespaceSelector: common.StringP(tt.nsSelector),
+				StorageClass:      &tt.storageClass,
+				NodeName:          common.StringP(tt.node),
+			})
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.ErrorContains(t, err, tt.wantErr)
+			}
+		})
+	}
+}
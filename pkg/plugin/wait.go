@@ -0,0 +1,230 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/common"
+	"github.com/dancavallaro/kubectl-unmount/pkg/discovery"
+	"github.com/dancavallaro/kubectl-unmount/pkg/scaling"
+	"github.com/dancavallaro/kubectl-unmount/pkg/spinner"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const pollInterval = 2 * time.Second
+
+// waitForScaleDown waits for the pods of the scaled down controllers to terminate (all but --replicas of them, for
+// controllers with replicas). With --wait, this first waits for each controller to report no more than --replicas
+// ready replicas, and fails if --wait-timeout expires.
+func waitForScaleDown(ctx context.Context, cfg *ConfigFlags, finder discovery.Finder, scaler scaling.Scaler,
+	controllers []common.ControllerRef, podsByController map[common.ControllerRef][]corev1.Pod,
+	pvcsPerNs map[string][]string, podFilter discovery.PodFilter) error {
+	waitingFor := make(map[types.UID]common.ControllerRef)
+	for _, ctrl := range controllers {
+		for _, pod := range podsByController[ctrl] {
+			waitingFor[pod.UID] = ctrl
+		}
+	}
+
+	warned := make(map[types.UID]bool)
+
+	if *cfg.Wait {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *cfg.WaitTimeout)
+		defer cancel()
+	}
+	onErr := func(err error) {
+		// Errors caused by the timeout expiring are reported once below
+		if ctx.Err() == nil {
+			cfg.logger.Error(err)
+		}
+	}
+
+	if *cfg.Wait {
+		if err := waitForControllers(ctx, cfg, finder, controllers, onErr); err != nil {
+			return err
+		}
+	}
+
+	err := <-spinner.Wait(ctx, "Waiting for pods to scale down... ", func() (bool, error) {
+		pods, err := finder.FindPodsUsingPVCs(ctx, pvcsPerNs, podFilter)
+		if err != nil {
+			return false, err
+		}
+		// Ignore pods of other controllers that weren't scaled down (e.g. because they were excluded)
+		pods = slices.DeleteFunc(pods, func(pod corev1.Pod) bool {
+			_, ok := waitingFor[pod.UID]
+			return !ok
+		})
+		if *cfg.MaxWaitForSchedule > 0 {
+			pods = slices.DeleteFunc(pods, func(pod corev1.Pod) bool {
+				return stuckUnschedulable(cfg, pod, warned)
+			})
+		}
+		if len(*cfg.RemoveCustomFinalizers) > 0 {
+			if err := scaler.RemoveFinalizers(ctx, pods, *cfg.RemoveCustomFinalizers); err != nil {
+				return false, err
+			}
+		}
+		return fewEnoughLeft(cfg, pods, waitingFor), nil
+	}, onErr, pollInterval)
+	if err != nil {
+		return waitError(cfg, err, "pods to terminate")
+	}
+
+	if *cfg.WaitForReplicaSetCleanup {
+		err := <-spinner.Wait(ctx, "Waiting for old ReplicaSets to be cleaned up... ", func() (bool, error) {
+			for _, ctrl := range controllers {
+				if ctrl.Kind != common.KindDeployment {
+					continue
+				}
+				old, err := finder.FindOldReplicaSets(ctx, ctrl)
+				if err != nil {
+					return false, err
+				}
+				if len(old) > 0 {
+					return false, nil
+				}
+			}
+			return true, nil
+		}, onErr, pollInterval)
+		if err != nil {
+			return waitError(cfg, err, "old ReplicaSets to be cleaned up")
+		}
+	}
+
+	if *cfg.CleanAttachments {
+		pvs, err := finder.FindBoundPVs(ctx, pvcsPerNs)
+		if err != nil {
+			return err
+		}
+		attachments, err := finder.FindVolumeAttachments(ctx, pvs)
+		if err != nil {
+			return err
+		}
+		if err := scaler.DeleteVolumeAttachments(ctx, attachments); err != nil {
+			return err
+		}
+	}
+
+	if *cfg.VerifyDetach {
+		if err := waitForDetach(ctx, cfg, finder, pvcsPerNs, onErr); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// fewEnoughLeft checks whether each scaled down controller has at most --replicas of its original pods left, given
+// the ones still running. Standalone pods, Jobs and CronJobs can't keep any.
+func fewEnoughLeft(cfg *ConfigFlags, pods []corev1.Pod, controllerOf map[types.UID]common.ControllerRef) bool {
+	left := make(map[common.ControllerRef]int)
+	for _, pod := range pods {
+		left[controllerOf[pod.UID]]++
+	}
+	for ctrl, n := range left {
+		switch ctrl.Kind {
+		case common.KindPod, common.KindJob, common.KindCronJob:
+			return false
+		}
+		if n > *cfg.Replicas {
+			return false
+		}
+	}
+	return true
+}
+
+// waitForControllers waits for each of the controllers to report no more than --replicas ready replicas, one after
+// the other, reporting which one is being waited for.
+func waitForControllers(ctx context.Context, cfg *ConfigFlags, finder discovery.Finder, controllers []common.ControllerRef,
+	onErr func(error)) error {
+	defer cfg.progress.Done()
+	start := time.Now()
+	for i, ctrl := range controllers {
+		err := <-spinner.Poll(ctx, func() (bool, error) {
+			cfg.progress.Report(i+1, len(controllers), fmt.Sprintf("Waiting for %v to reach %d replicas", ctrl, *cfg.Replicas),
+				time.Since(start))
+			ready, ok, err := finder.ReadyReplicas(ctx, ctrl)
+			if err != nil {
+				return false, err
+			}
+			return !ok || ready <= int32(*cfg.Replicas), nil
+		}, onErr, pollInterval)
+		if err != nil {
+			return waitError(cfg, err, fmt.Sprintf("%v to scale down", ctrl))
+		}
+	}
+	return nil
+}
+
+// waitForDetach waits for the PVs bound to the targeted PVCs to have no VolumeAttachments left, since pods
+// terminating doesn't guarantee that the CSI driver has detached their volumes from the nodes yet. Logs the
+// node of each attachment the first time it's found.
+func waitForDetach(ctx context.Context, cfg *ConfigFlags, finder discovery.Finder, pvcsPerNs map[string][]string,
+	onErr func(error)) error {
+	pvs, err := finder.FindBoundPVs(ctx, pvcsPerNs)
+	if err != nil {
+		return err
+	}
+	var attachments []storagev1.VolumeAttachment
+	logged := make(map[string]bool)
+	err = <-spinner.Wait(ctx, "Waiting for volumes to detach... ", func() (bool, error) {
+		found, err := finder.FindVolumeAttachments(ctx, pvs)
+		if err != nil {
+			return false, err
+		}
+		attachments = found
+		for _, attachment := range attachments {
+			if !logged[attachment.Name] {
+				logged[attachment.Name] = true
+				cfg.logger.Info("PV %s is still attached to node %s", *attachment.Spec.Source.PersistentVolumeName,
+					attachment.Spec.NodeName)
+			}
+		}
+		return len(attachments) == 0, nil
+	}, onErr, pollInterval)
+	if err != nil {
+		if errors.Is(err, context.Canceled) || len(attachments) == 0 {
+			return waitError(cfg, err, "volumes to detach")
+		}
+		var still []string
+		for _, attachment := range attachments {
+			still = append(still, fmt.Sprintf("PV %s on node %s", *attachment.Spec.Source.PersistentVolumeName,
+				attachment.Spec.NodeName))
+		}
+		return fmt.Errorf("%w, still attached: %s", waitError(cfg, err, "volumes to detach"), strings.Join(still, ", "))
+	}
+	cfg.logger.Info("Verified that %d PVs are detached from all nodes", len(pvs))
+	return nil
+}
+
+// stuckUnschedulable checks whether the pod has been unschedulable for longer than --max-wait-for-schedule.
+// Such pods will never terminate on their own, but also aren't using the PVC, so they're considered effectively
+// terminated. Logs a warning the first time each such pod is found.
+func stuckUnschedulable(cfg *ConfigFlags, pod corev1.Pod, warned map[types.UID]bool) bool {
+	since, ok := discovery.UnschedulableSince(pod)
+	if !ok || time.Since(since) < *cfg.MaxWaitForSchedule {
+		return false
+	}
+	if !warned[pod.UID] {
+		warned[pod.UID] = true
+		cfg.logger.Warn("Pod %s/%s has been unschedulable for over %v, considering it terminated",
+			pod.Namespace, pod.Name, *cfg.MaxWaitForSchedule)
+	}
+	return true
+}
+
+// waitError describes why waiting failed: either --wait-timeout expired, or the run was interrupted.
+func waitError(cfg *ConfigFlags, err error, what string) error {
+	if errors.Is(err, context.Canceled) {
+		return fmt.Errorf("interrupted while waiting for %s: %w", what, err)
+	}
+	return fmt.Errorf("timed out after %v waiting for %s", *cfg.WaitTimeout, what)
+}
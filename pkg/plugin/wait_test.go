@@ -0,0 +1,30 @@
+package plugin
+
+import (
+	"testing"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/common"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestFewEnoughLeft(t *testing.T) {
+	web := common.ControllerRef{Kind: common.KindDeployment, Namespace: "test-ns", Name: "web"}
+	debug := common.ControllerRef{Kind: common.KindPod, Namespace: "test-ns", Name: "debug"}
+	newPod := func(uid string) corev1.Pod {
+		return corev1.Pod{ObjectMeta: metav1.ObjectMeta{UID: types.UID(uid)}}
+	}
+	controllerOf := map[types.UID]common.ControllerRef{"web-1": web, "web-2": web, "debug": debug}
+	cfg := &ConfigFlags{Replicas: common.IntP(1)}
+
+	require.False(t, fewEnoughLeft(cfg, []corev1.Pod{newPod("web-1"), newPod("web-2")}, controllerOf))
+	require.True(t, fewEnoughLeft(cfg, []corev1.Pod{newPod("web-1")}, controllerOf))
+	require.False(t, fewEnoughLeft(cfg, []corev1.Pod{newPod("web-1"), newPod("debug")}, controllerOf),
+		"standalone pods should always be waited for")
+
+	*cfg.Replicas = 0
+	require.False(t, fewEnoughLeft(cfg, []corev1.Pod{newPod("web-1")}, controllerOf))
+	require.True(t, fewEnoughLeft(cfg, nil, controllerOf))
+}
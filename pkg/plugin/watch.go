@@ -0,0 +1,146 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/common"
+	"github.com/dancavallaro/kubectl-unmount/pkg/discovery"
+	"github.com/dancavallaro/kubectl-unmount/pkg/scaling"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// podWatcher scales down the controllers of pods mounting the targeted PVCs as they're created, with --watch.
+type podWatcher struct {
+	cfg       *ConfigFlags
+	finder    discovery.Finder
+	scaler    scaling.Scaler
+	filter    discovery.PVCFilter
+	podFilter discovery.PodFilter
+	result    *Result
+
+	// scaledAt is when each controller was last scaled down, for --watch-cooldown
+	scaledAt map[common.ControllerRef]time.Time
+}
+
+// watchPods scales down the controllers of the pods mounting the targeted PVCs, both the existing ones and any
+// that are created later, until the context is cancelled.
+func watchPods(ctx context.Context, cfg *ConfigFlags, clientset kubernetes.Interface, finder discovery.Finder,
+	scaler scaling.Scaler, filter discovery.PVCFilter, podFilter discovery.PodFilter, result *Result) error {
+	w := &podWatcher{
+		cfg:       cfg,
+		finder:    finder,
+		scaler:    scaler,
+		filter:    filter,
+		podFilter: podFilter,
+		result:    result,
+		scaledAt:  make(map[common.ControllerRef]time.Time),
+	}
+
+	factory := informers.NewSharedInformerFactoryWithOptions(clientset, 0, informers.WithNamespace(filter.Namespace),
+		informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.LabelSelector = podFilter.LabelSelector
+			opts.FieldSelector = podFilter.FieldSelector
+		}))
+	// Pods are handled one at a time below, rather than in the informer's goroutine. The pods that already exist
+	// are passed to AddFunc too, once the informer has listed them.
+	created := make(chan *corev1.Pod)
+	_, err := factory.Core().V1().Pods().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj any) {
+			if pod, ok := obj.(*corev1.Pod); ok {
+				select {
+				case created <- pod:
+				case <-ctx.Done():
+				}
+			}
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to watch pods: %w", err)
+	}
+	factory.Start(ctx.Done())
+	defer factory.Shutdown()
+
+	cfg.logger.Info("Watching for pods mounting the targeted PVCs, press Ctrl-C to stop...")
+	for {
+		select {
+		case <-ctx.Done():
+			cfg.logger.Info("Stopped watching: %s", tally(result.Scaled, result.DeletedPods))
+			return nil
+		case pod := <-created:
+			if err := w.handle(ctx, *pod); err != nil {
+				cfg.logger.Error(err)
+			}
+		}
+	}
+}
+
+// handle scales down the controller of the given pod, if it mounts any of the targeted PVCs.
+func (w *podWatcher) handle(ctx context.Context, pod corev1.Pod) error {
+	if pod.DeletionTimestamp != nil || !w.podFilter.Matches(pod) {
+		return nil
+	}
+	if !slices.ContainsFunc(pod.Spec.Volumes, func(vol corev1.Volume) bool {
+		return vol.PersistentVolumeClaim != nil || vol.Ephemeral != nil
+	}) {
+		return nil
+	}
+
+	// PVCs matching the selection may have been created since the last pod was handled
+	pvcsPerNs, err := w.targets(ctx)
+	if err != nil {
+		return err
+	}
+	pvcs := triggerPVCs([]corev1.Pod{pod}, pvcsPerNs)
+	if len(pvcs) == 0 {
+		return nil
+	}
+
+	ctrl, err := w.finder.FindController(ctx, pod)
+	if err != nil {
+		return fmt.Errorf("failed to find controller for pod %s/%s: %w", pod.Namespace, pod.Name, err)
+	}
+	if isExcluded(w.cfg, ctrl) {
+		w.cfg.logger.Info("Pod %s/%s mounts %s, but %v is excluded", pod.Namespace, pod.Name, strings.Join(pvcs, ", "), ctrl)
+		return nil
+	}
+	if *w.cfg.ProtectAnnotation != "" {
+		isProtected, err := w.finder.IsProtected(ctx, ctrl, []corev1.Pod{pod}, *w.cfg.ProtectAnnotation)
+		if err != nil {
+			return err
+		}
+		if isProtected {
+			w.cfg.logger.Info("Pod %s/%s mounts %s, but %v is protected by its %s annotation",
+				pod.Namespace, pod.Name, strings.Join(pvcs, ", "), ctrl, *w.cfg.ProtectAnnotation)
+			return nil
+		}
+	}
+	if scaledAt, ok := w.scaledAt[ctrl]; ok && time.Since(scaledAt) < *w.cfg.WatchCooldown {
+		w.cfg.logger.Warn("Pod %s/%s mounts %s, but %v was already scaled down %v ago, leaving it alone until "+
+			"--watch-cooldown=%v expires", pod.Namespace, pod.Name, strings.Join(pvcs, ", "), ctrl,
+			time.Since(scaledAt).Round(time.Second), *w.cfg.WatchCooldown)
+		return nil
+	}
+
+	w.cfg.logger.Info("Pod %s/%s mounts %s, scaling down %v", pod.Namespace, pod.Name, strings.Join(pvcs, ", "), ctrl)
+	// Failures count towards the cooldown too, so that they aren't retried in a hot loop
+	w.scaledAt[ctrl] = time.Now()
+	_, err = w.scaler.ScaleDown(ctx, ctrl, pvcs)
+	w.result.recordScaleDown([]common.ControllerRef{ctrl}, []error{err})
+	return err
+}
+
+// targets finds the PVCs currently matching the selection.
+func (w *podWatcher) targets(ctx context.Context) (map[string][]string, error) {
+	if len(*w.cfg.PVCName) > 0 {
+		return map[string][]string{*w.cfg.Namespace: *w.cfg.PVCName}, nil
+	}
+	return w.finder.FindPVCs(ctx, w.filter)
+}
@@ -0,0 +1,123 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/common"
+	"github.com/dancavallaro/kubectl-unmount/pkg/discovery"
+	"github.com/dancavallaro/kubectl-unmount/pkg/logger"
+	"github.com/dancavallaro/kubectl-unmount/pkg/scaling"
+	"github.com/stretchr/testify/require"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
+)
+
+func newWatchedPod(name, pvc string, owners ...metav1.OwnerReference) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test-ns", UID: types.UID(name), OwnerReferences: owners},
+		Spec: corev1.PodSpec{
+			Volumes: []corev1.Volume{{
+				Name: "data",
+				VolumeSource: corev1.VolumeSource{
+					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: pvc},
+				},
+			}},
+		},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+}
+
+func newWatchConfig(logs *bytes.Buffer) *ConfigFlags {
+	cfg := &ConfigFlags{
+		PVCName:           &[]string{},
+		OnlyControllers:   common.BoolP(false),
+		OnlyPods:          common.BoolP(false),
+		ProtectAnnotation: common.StringP(common.AnnotationSkip),
+		WatchCooldown:     common.DurationP(time.Hour),
+		logger:            logger.NewLogger(logs, logger.LevelInfo),
+	}
+	cfg.Namespace = common.StringP("test-ns")
+	return cfg
+}
+
+func TestPodWatcherCooldown(t *testing.T) {
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "data", Namespace: "test-ns"},
+		Spec:       corev1.PersistentVolumeClaimSpec{StorageClassName: ptr.To("standard")},
+	}
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "batch", Namespace: "test-ns"}}
+	owner := metav1.OwnerReference{APIVersion: "batch/v1", Kind: common.KindJob, Name: "batch"}
+	clientset := fake.NewClientset(pvc, job)
+
+	var logs bytes.Buffer
+	cfg := newWatchConfig(&logs)
+	w := &podWatcher{
+		cfg:      cfg,
+		finder:   discovery.New(clientset, cfg.logger),
+		scaler:   scaling.New(clientset, cfg.logger, scaling.Options{}),
+		filter:   discovery.PVCFilter{Namespace: "test-ns", StorageClasses: []string{"standard"}},
+		result:   &Result{},
+		scaledAt: make(map[common.ControllerRef]time.Time),
+	}
+	ctx := context.Background()
+
+	require.NoError(t, w.handle(ctx, *newWatchedPod("other", "scratch")))
+	require.NotContains(t, logs.String(), "scaling down")
+
+	require.NoError(t, w.handle(ctx, *newWatchedPod("batch-1", "data", owner)))
+	require.Contains(t, logs.String(), "Pod test-ns/batch-1 mounts data, scaling down Job/test-ns/batch")
+	job, err := clientset.BatchV1().Jobs("test-ns").Get(ctx, "batch", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.True(t, *job.Spec.Suspend)
+
+	// Another pod of the same Job doesn't get it scaled down again before the cooldown expires
+	require.NoError(t, w.handle(ctx, *newWatchedPod("batch-2", "data", owner)))
+	require.Contains(t, logs.String(), "Pod test-ns/batch-2 mounts data, but Job/test-ns/batch was already scaled down")
+	require.Equal(t, []common.ControllerRef{{Kind: common.KindJob, Namespace: "test-ns", Name: "batch"}}, w.result.Scaled)
+
+	*cfg.WatchCooldown = 0
+	require.NoError(t, w.handle(ctx, *newWatchedPod("batch-3", "data", owner)))
+	require.Len(t, w.result.Scaled, 2)
+}
+
+func TestWatchPods(t *testing.T) {
+	clientset := fake.NewClientset(newWatchedPod("existing", "data"))
+	var logs bytes.Buffer
+	cfg := newWatchConfig(&logs)
+	*cfg.PVCName = []string{"data"}
+	scaler := scaling.New(clientset, cfg.logger, scaling.Options{})
+	result := &Result{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- watchPods(ctx, cfg, clientset, discovery.New(clientset, cfg.logger), scaler,
+			discovery.PVCFilter{Namespace: "test-ns"}, discovery.PodFilter{}, result)
+	}()
+
+	podGone := func(name string) func() bool {
+		return func() bool {
+			_, err := clientset.CoreV1().Pods("test-ns").Get(ctx, name, metav1.GetOptions{})
+			return err != nil
+		}
+	}
+	require.Eventually(t, podGone("existing"), 5*time.Second, 10*time.Millisecond)
+	_, err := clientset.CoreV1().Pods("test-ns").Create(ctx, newWatchedPod("created", "data"), metav1.CreateOptions{})
+	require.NoError(t, err)
+	require.Eventually(t, podGone("created"), 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+	require.ElementsMatch(t, []common.ControllerRef{
+		{Kind: common.KindPod, Namespace: "test-ns", Name: "existing"},
+		{Kind: common.KindPod, Namespace: "test-ns", Name: "created"},
+	}, result.DeletedPods)
+	require.Contains(t, logs.String(), "Stopped watching: 2 Pods deleted (2 total)")
+}
@@ -0,0 +1,44 @@
+package scaling
+
+import (
+	"context"
+	"fmt"
+
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DeleteVolumeAttachments deletes the given VolumeAttachments, which can linger after the pods using their volumes
+// are gone and keep the PVs from being attached to other nodes. Deleting one asks the CSI driver to detach it, so
+// one still held by finalizers (e.g. while the driver is detaching it) is only removed once that's done, and a
+// warning is logged.
+func (s Scaler) DeleteVolumeAttachments(ctx context.Context, attachments []storagev1.VolumeAttachment) error {
+	for _, attachment := range attachments {
+		pv := *attachment.Spec.Source.PersistentVolumeName
+		if s.dryRun && !s.serverDryRun {
+			s.log.Info("  (dry-run, skipping deleting VolumeAttachment %s of PV %s)", attachment.Name, pv)
+			continue
+		}
+
+		err := s.clientset.StorageV1().VolumeAttachments().Delete(ctx, attachment.Name, metav1.DeleteOptions{DryRun: s.dryRunOptions()})
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to delete VolumeAttachment %s of PV %s: %w", attachment.Name, pv, err)
+		}
+
+		remaining, err := s.clientset.StorageV1().VolumeAttachments().Get(ctx, attachment.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) || err == nil && len(remaining.Finalizers) == 0 {
+			s.log.Info("  Deleted VolumeAttachment %s of PV %s (node %s)", attachment.Name, pv, attachment.Spec.NodeName)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get VolumeAttachment %s of PV %s: %w", attachment.Name, pv, err)
+		}
+		s.log.Warn("VolumeAttachment %s of PV %s (node %s) can't be deleted yet, it's held by finalizers %v",
+			attachment.Name, pv, attachment.Spec.NodeName, remaining.Finalizers)
+	}
+	return nil
+}
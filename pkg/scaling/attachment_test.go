@@ -0,0 +1,47 @@
+package scaling
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/logger"
+	"github.com/stretchr/testify/require"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/utils/ptr"
+)
+
+func TestDeleteVolumeAttachments(t *testing.T) {
+	newAttachment := func(name, pv string, finalizers ...string) storagev1.VolumeAttachment {
+		return storagev1.VolumeAttachment{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Finalizers: finalizers},
+			Spec: storagev1.VolumeAttachmentSpec{
+				NodeName: "node-1",
+				Source:   storagev1.VolumeAttachmentSource{PersistentVolumeName: ptr.To(pv)},
+			},
+		}
+	}
+	detached := newAttachment("csi-detached", "pv-1")
+	stuck := newAttachment("csi-stuck", "pv-2", "external-attacher/ebs-csi-aws-com")
+	clientset := fake.NewClientset(&detached, &stuck)
+	// Like the API server, only mark attachments with finalizers for deletion instead of deleting them
+	clientset.PrependReactor("delete", "volumeattachments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return action.(k8stesting.DeleteAction).GetName() == stuck.Name, nil, nil
+	})
+
+	var logs bytes.Buffer
+	s := New(clientset, logger.NewLogger(&logs, logger.LevelInfo), Options{})
+	ctx := context.Background()
+	require.NoError(t, s.DeleteVolumeAttachments(ctx, []storagev1.VolumeAttachment{detached, stuck}))
+
+	_, err := clientset.StorageV1().VolumeAttachments().Get(ctx, detached.Name, metav1.GetOptions{})
+	require.True(t, apierrors.IsNotFound(err))
+	require.Contains(t, logs.String(), "Deleted VolumeAttachment csi-detached of PV pv-1 (node node-1)")
+	require.Contains(t, logs.String(),
+		"VolumeAttachment csi-stuck of PV pv-2 (node node-1) can't be deleted yet, it's held by finalizers [external-attacher/ebs-csi-aws-com]")
+}
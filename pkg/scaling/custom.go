@@ -0,0 +1,128 @@
+package scaling
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/common"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+)
+
+// ErrNotScalable is returned when scaling down a custom resource that doesn't have a scale subresource.
+var ErrNotScalable = errors.New("no scale subresource")
+
+// scaleCustomResource scales a custom resource (e.g. an Argo Rollout) down through its scale subresource, after
+// annotating it to record the operation, and returns its original number of replicas.
+func (s Scaler) scaleCustomResource(ctx context.Context, ctrl common.ControllerRef, annotations map[string]string,
+	dryRun []string) (int32, error) {
+	resource, err := s.scaleResource(ctrl)
+	if err != nil {
+		return 0, err
+	}
+	client := s.dynamic.Resource(resource).Namespace(ctrl.Namespace)
+
+	scale, err := client.Get(ctx, ctrl.Name, metav1.GetOptions{}, "scale")
+	if err != nil {
+		return 0, fmt.Errorf("failed to get scale for %s %s/%s: %w", ctrl.Kind, ctrl.Namespace, ctrl.Name, err)
+	}
+	originalReplicas, _, err := unstructured.NestedInt64(scale.Object, "spec", "replicas")
+	if err != nil {
+		return 0, fmt.Errorf("failed to get scale for %s %s/%s: %w", ctrl.Kind, ctrl.Namespace, ctrl.Name, err)
+	}
+	if originalReplicas <= int64(s.replicas) {
+		s.log.Info("%s %s/%s is already scaled to %d", ctrl.Kind, ctrl.Namespace, ctrl.Name, originalReplicas)
+		return int32(originalReplicas), nil
+	}
+
+	// The scale subresource can't be patched together with the annotations, so record the operation first
+	annotations[common.AnnotationOriginalReplicas] = strconv.FormatInt(originalReplicas, 10)
+	data, err := json.Marshal(map[string]any{"metadata": map[string]any{"annotations": annotations}})
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode patch for %s %s/%s: %w", ctrl.Kind, ctrl.Namespace, ctrl.Name, err)
+	}
+	if _, err := client.Patch(ctx, ctrl.Name, types.MergePatchType, data, metav1.PatchOptions{DryRun: dryRun}); err != nil {
+		return 0, fmt.Errorf("failed to annotate %s %s/%s: %w", ctrl.Kind, ctrl.Namespace, ctrl.Name, err)
+	}
+	data = fmt.Appendf(nil, `{"spec":{"replicas":%d}}`, s.replicas)
+	if _, err := client.Patch(ctx, ctrl.Name, types.MergePatchType, data, metav1.PatchOptions{DryRun: dryRun}, "scale"); err != nil {
+		return 0, fmt.Errorf("failed to scale down %s %s/%s: %w", ctrl.Kind, ctrl.Namespace, ctrl.Name, err)
+	}
+
+	s.log.Info("  Scaled down %s %s/%s from %d to %d replicas", ctrl.Kind, ctrl.Namespace, ctrl.Name, originalReplicas, s.replicas)
+	return int32(originalReplicas), nil
+}
+
+// restoreCustomResource scales a custom resource back up through its scale subresource, to the given number of
+// replicas or (if unknown) the one recorded in its annotations, and then removes the annotations.
+func (s Scaler) restoreCustomResource(ctx context.Context, ctrl common.ControllerRef, replicas *int32) error {
+	resource, err := s.scaleResource(ctrl)
+	if err != nil {
+		return err
+	}
+	client := s.dynamic.Resource(resource).Namespace(ctrl.Namespace)
+	dryRun := s.dryRunOptions()
+
+	obj, err := client.Get(ctx, ctrl.Name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get %v: %w", ctrl, err)
+	}
+	if replicas == nil {
+		original, err := strconv.ParseInt(obj.GetAnnotations()[common.AnnotationOriginalReplicas], 10, 32)
+		if err != nil {
+			return fmt.Errorf("cannot restore %v, its original number of replicas is unknown", ctrl)
+		}
+		replicas = ptr.To(int32(original))
+	}
+
+	err = s.withRetries(ctx, ctrl.String(), func() error {
+		data := fmt.Appendf(nil, `{"spec":{"replicas":%d}}`, *replicas)
+		if _, err := client.Patch(ctx, ctrl.Name, types.MergePatchType, data, metav1.PatchOptions{DryRun: dryRun}, "scale"); err != nil {
+			return err
+		}
+		data, err := json.Marshal(map[string]any{"metadata": map[string]any{"annotations": restoreAnnotations(obj.GetAnnotations())}})
+		if err != nil {
+			return err
+		}
+		_, err = client.Patch(ctx, ctrl.Name, types.MergePatchType, data, metav1.PatchOptions{DryRun: dryRun})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to restore %v: %w", ctrl, err)
+	}
+	s.log.Info("  Restored %v to %d replicas", ctrl, *replicas)
+	return nil
+}
+
+// scaleResource discovers the resource of the given custom resource's kind, and checks that it has a scale
+// subresource.
+func (s Scaler) scaleResource(ctrl common.ControllerRef) (schema.GroupVersionResource, error) {
+	gv, err := schema.ParseGroupVersion(ctrl.APIVersion)
+	if err != nil {
+		return schema.GroupVersionResource{}, fmt.Errorf("invalid API version of %v: %w", ctrl, err)
+	}
+	resources, err := s.clientset.Discovery().ServerResourcesForGroupVersion(ctrl.APIVersion)
+	if err != nil {
+		return schema.GroupVersionResource{}, fmt.Errorf("failed to discover the resources of %s: %w", ctrl.APIVersion, err)
+	}
+
+	i := slices.IndexFunc(resources.APIResources, func(r metav1.APIResource) bool {
+		return r.Kind == ctrl.Kind && !strings.Contains(r.Name, "/")
+	})
+	if i < 0 {
+		return schema.GroupVersionResource{}, fmt.Errorf("%s doesn't serve the %s kind", ctrl.APIVersion, ctrl.Kind)
+	}
+	name := resources.APIResources[i].Name
+	if !slices.ContainsFunc(resources.APIResources, func(r metav1.APIResource) bool { return r.Name == name+"/scale" }) {
+		return schema.GroupVersionResource{}, fmt.Errorf("cannot scale down %v, %s has %w", ctrl, name, ErrNotScalable)
+	}
+	return gv.WithResource(name), nil
+}
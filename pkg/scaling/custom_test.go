@@ -0,0 +1,108 @@
+package scaling
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/common"
+	"github.com/dancavallaro/kubectl-unmount/pkg/logger"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestScaleDownCustomResource(t *testing.T) {
+	newResource := func(kind, name string) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]any{
+			"apiVersion": "argoproj.io/v1alpha1",
+			"kind":       kind,
+			"metadata":   map[string]any{"name": name, "namespace": "test-ns"},
+			"spec":       map[string]any{"replicas": int64(3)},
+		}}
+	}
+	rollouts := schema.GroupVersionResource{Group: "argoproj.io", Version: "v1alpha1", Resource: "rollouts"}
+	workflows := schema.GroupVersionResource{Group: "argoproj.io", Version: "v1alpha1", Resource: "workflows"}
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		rollouts:  "RolloutList",
+		workflows: "WorkflowList",
+	}, newResource("Rollout", "web"), newResource("Workflow", "etl"))
+	clientset := fake.NewClientset()
+	clientset.Discovery().(*fakediscovery.FakeDiscovery).Resources = []*metav1.APIResourceList{{
+		GroupVersion: "argoproj.io/v1alpha1",
+		APIResources: []metav1.APIResource{
+			{Name: "rollouts", Kind: "Rollout", Namespaced: true},
+			{Name: "rollouts/scale", Kind: "Scale", Namespaced: true},
+			{Name: "workflows", Kind: "Workflow", Namespaced: true},
+		},
+	}}
+
+	var logs bytes.Buffer
+	s := New(clientset, logger.NewLogger(&logs, logger.LevelInfo), Options{Dynamic: dynamicClient})
+	ctx := context.Background()
+
+	rollout := common.ControllerRef{Kind: "Rollout", Namespace: "test-ns", Name: "web", APIVersion: "argoproj.io/v1alpha1"}
+	require.True(t, CanScaleDown(rollout))
+	_, err := s.ScaleDown(ctx, rollout, []string{"data"})
+	require.NoError(t, err)
+	obj, err := dynamicClient.Resource(rollouts).Namespace("test-ns").Get(ctx, "web", metav1.GetOptions{})
+	require.NoError(t, err)
+	replicas, _, _ := unstructured.NestedInt64(obj.Object, "spec", "replicas")
+	require.Zero(t, replicas)
+	require.Equal(t, "3", obj.GetAnnotations()[common.AnnotationOriginalReplicas])
+	require.Equal(t, "data", obj.GetAnnotations()[common.AnnotationPVCTrigger])
+	require.Contains(t, logs.String(), "Scaled down Rollout test-ns/web from 3 to 0 replicas")
+
+	workflow := common.ControllerRef{Kind: "Workflow", Namespace: "test-ns", Name: "etl", APIVersion: "argoproj.io/v1alpha1"}
+	_, err = s.ScaleDown(ctx, workflow, []string{"data"})
+	require.ErrorIs(t, err, ErrNotScalable)
+	obj, err = dynamicClient.Resource(workflows).Namespace("test-ns").Get(ctx, "etl", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Empty(t, obj.GetAnnotations())
+
+	require.False(t, CanScaleDown(common.ControllerRef{Kind: common.KindDaemonSet, Namespace: "test-ns", Name: "agent"}))
+}
+
+func TestRestoreRollout(t *testing.T) {
+	rollouts := schema.GroupVersionResource{Group: "argoproj.io", Version: "v1alpha1", Resource: "rollouts"}
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{rollouts: "RolloutList"},
+		&unstructured.Unstructured{Object: map[string]any{
+			"apiVersion": "argoproj.io/v1alpha1",
+			"kind":       "Rollout",
+			"metadata":   map[string]any{"name": "web", "namespace": "test-ns"},
+			"spec":       map[string]any{"replicas": int64(3)},
+		}})
+	clientset := fake.NewClientset()
+	clientset.Discovery().(*fakediscovery.FakeDiscovery).Resources = []*metav1.APIResourceList{{
+		GroupVersion: "argoproj.io/v1alpha1",
+		APIResources: []metav1.APIResource{
+			{Name: "rollouts", Kind: "Rollout", Namespaced: true},
+			{Name: "rollouts/scale", Kind: "Scale", Namespaced: true},
+		},
+	}}
+
+	var logs bytes.Buffer
+	s := New(clientset, logger.NewLogger(&logs, logger.LevelInfo), Options{
+		Dynamic:     dynamicClient,
+		Annotations: map[string]string{"ticket": "OPS-123"},
+	})
+	ctx := context.Background()
+	rollout := common.ControllerRef{Kind: "Rollout", Namespace: "test-ns", Name: "web", APIVersion: "argoproj.io/v1alpha1"}
+	_, err := s.ScaleDown(ctx, rollout, []string{"data"})
+	require.NoError(t, err)
+
+	// The number of replicas isn't in the restore file, so the recorded one is used
+	require.NoError(t, s.Restore(ctx, rollout, nil))
+	obj, err := dynamicClient.Resource(rollouts).Namespace("test-ns").Get(ctx, "web", metav1.GetOptions{})
+	require.NoError(t, err)
+	replicas, _, _ := unstructured.NestedInt64(obj.Object, "spec", "replicas")
+	require.Equal(t, int64(3), replicas)
+	require.Empty(t, obj.GetAnnotations())
+	require.Contains(t, logs.String(), "Restored Rollout/test-ns/web to 3 replicas")
+}
@@ -0,0 +1,36 @@
+package scaling
+
+import (
+	"github.com/dancavallaro/kubectl-unmount/pkg/common"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// EventReasonUnmounted is the reason of the events recorded on scaled down controllers.
+const EventReasonUnmounted = "Unmounted"
+
+// recordEvent records an event on the given controller, if the Scaler has an event recorder.
+func (s Scaler) recordEvent(ctrl common.ControllerRef, eventType, message string) {
+	if s.recorder == nil {
+		return
+	}
+	s.recorder.Event(objectReference(ctrl), eventType, EventReasonUnmounted, message)
+}
+
+func objectReference(ctrl common.ControllerRef) *corev1.ObjectReference {
+	apiVersion := "apps/v1"
+	switch ctrl.Kind {
+	case common.KindPod:
+		apiVersion = "v1"
+	case common.KindJob, common.KindCronJob:
+		apiVersion = "batch/v1"
+	}
+	if ctrl.APIVersion != "" {
+		apiVersion = ctrl.APIVersion
+	}
+	return &corev1.ObjectReference{
+		APIVersion: apiVersion,
+		Kind:       ctrl.Kind,
+		Namespace:  ctrl.Namespace,
+		Name:       ctrl.Name,
+	}
+}
@@ -0,0 +1,40 @@
+package scaling
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RemoveFinalizers removes the given finalizers from any of the provided pods that are
+// already terminating, so that they aren't left stuck after being scaled down.
+func (s Scaler) RemoveFinalizers(ctx context.Context, pods []corev1.Pod, finalizers []string) error {
+	for _, pod := range pods {
+		if pod.DeletionTimestamp == nil {
+			continue
+		}
+
+		remaining := slices.DeleteFunc(slices.Clone(pod.Finalizers), func(finalizer string) bool {
+			return slices.Contains(finalizers, finalizer)
+		})
+		if len(remaining) == len(pod.Finalizers) {
+			continue
+		}
+
+		if s.dryRun {
+			s.log.Info("  (dry-run, skipping finalizer removal for Pod %s/%s)", pod.Namespace, pod.Name)
+			continue
+		}
+
+		pod.Finalizers = remaining
+		_, err := s.clientset.CoreV1().Pods(pod.Namespace).Update(ctx, &pod, metav1.UpdateOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to remove finalizers from pod %s/%s: %w", pod.Namespace, pod.Name, err)
+		}
+		s.log.Info("  Removed finalizers from terminating Pod %s/%s", pod.Namespace, pod.Name)
+	}
+	return nil
+}
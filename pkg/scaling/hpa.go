@@ -0,0 +1,89 @@
+package scaling
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/common"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// DisableHPA keeps the given HorizontalPodAutoscaler from scaling its target back up once it's scaled down.
+// The HPA controller doesn't autoscale targets with 0 replicas unless the HPA's minReplicas is 0 (with the
+// HPAScaleToZero feature gate), so only those HPAs need to be changed: their minReplicas is raised to 1, and the
+// original value is recorded in an annotation so it can be restored. Returns false if the HPA was left untouched.
+func (s Scaler) DisableHPA(ctx context.Context, hpa *autoscalingv2.HorizontalPodAutoscaler) (bool, error) {
+	if hpa.Spec.MinReplicas == nil || *hpa.Spec.MinReplicas > 0 {
+		s.log.Info("  HorizontalPodAutoscaler %s/%s is inactive while its target has 0 replicas, leaving it as is",
+			hpa.Namespace, hpa.Name)
+		return false, nil
+	}
+	if s.dryRun && !s.serverDryRun {
+		s.log.Info("  (dry-run, skipping disabling HorizontalPodAutoscaler %s/%s)", hpa.Namespace, hpa.Name)
+		return true, nil
+	}
+
+	data, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{
+			"annotations": map[string]string{
+				common.AnnotationScaledBy:            "kubectl-unmount",
+				common.AnnotationOriginalMinReplicas: strconv.Itoa(int(*hpa.Spec.MinReplicas)),
+			},
+		},
+		"spec": map[string]any{
+			"minReplicas": 1,
+		},
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to encode patch for HorizontalPodAutoscaler %s/%s: %w", hpa.Namespace, hpa.Name, err)
+	}
+	_, err = s.clientset.AutoscalingV2().HorizontalPodAutoscalers(hpa.Namespace).Patch(ctx, hpa.Name, types.MergePatchType, data,
+		metav1.PatchOptions{DryRun: s.dryRunOptions()})
+	if err != nil {
+		return false, fmt.Errorf("failed to disable HorizontalPodAutoscaler %s/%s: %w", hpa.Namespace, hpa.Name, err)
+	}
+	s.log.Info("  Disabled HorizontalPodAutoscaler %s/%s (raised minReplicas from 0 to 1)", hpa.Namespace, hpa.Name)
+	return true, nil
+}
+
+// RestoreHPA reverses DisableHPA: if the given HorizontalPodAutoscaler was disabled by kubectl-unmount, its
+// minReplicas is set back to the original value, and the annotations added by DisableHPA are removed (so that a
+// later change to minReplicas isn't reverted by another restore). Returns false if the HPA wasn't disabled.
+func (s Scaler) RestoreHPA(ctx context.Context, hpa *autoscalingv2.HorizontalPodAutoscaler) (bool, error) {
+	original, ok := hpa.Annotations[common.AnnotationOriginalMinReplicas]
+	if !ok {
+		return false, nil
+	}
+	minReplicas, err := strconv.Atoi(original)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s annotation on HorizontalPodAutoscaler %s/%s: %w",
+			common.AnnotationOriginalMinReplicas, hpa.Namespace, hpa.Name, err)
+	}
+	if s.dryRun && !s.serverDryRun {
+		s.log.Info("  (dry-run, skipping restoring HorizontalPodAutoscaler %s/%s)", hpa.Namespace, hpa.Name)
+		return true, nil
+	}
+
+	data, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{
+			"annotations": map[string]any{common.AnnotationScaledBy: nil, common.AnnotationOriginalMinReplicas: nil},
+		},
+		"spec": map[string]any{
+			"minReplicas": minReplicas,
+		},
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to encode patch for HorizontalPodAutoscaler %s/%s: %w", hpa.Namespace, hpa.Name, err)
+	}
+	_, err = s.clientset.AutoscalingV2().HorizontalPodAutoscalers(hpa.Namespace).Patch(ctx, hpa.Name, types.MergePatchType, data,
+		metav1.PatchOptions{DryRun: s.dryRunOptions()})
+	if err != nil {
+		return false, fmt.Errorf("failed to restore HorizontalPodAutoscaler %s/%s: %w", hpa.Namespace, hpa.Name, err)
+	}
+	s.log.Info("  Restored HorizontalPodAutoscaler %s/%s (minReplicas back to %d)", hpa.Namespace, hpa.Name, minReplicas)
+	return true, nil
+}
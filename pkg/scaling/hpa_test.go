@@ -0,0 +1,51 @@
+package scaling
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/common"
+	"github.com/dancavallaro/kubectl-unmount/pkg/logger"
+	"github.com/stretchr/testify/require"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
+)
+
+func TestDisableAndRestoreHPA(t *testing.T) {
+	ctx := context.Background()
+	hpa := &autoscalingv2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "test-ns"},
+		Spec:       autoscalingv2.HorizontalPodAutoscalerSpec{MinReplicas: ptr.To(int32(0))},
+	}
+	clientset := fake.NewClientset(hpa)
+	s := New(clientset, logger.NewLogger(&bytes.Buffer{}, logger.LevelInfo), Options{})
+	get := func() *autoscalingv2.HorizontalPodAutoscaler {
+		hpa, err := clientset.AutoscalingV2().HorizontalPodAutoscalers("test-ns").Get(ctx, "web", metav1.GetOptions{})
+		require.NoError(t, err)
+		return hpa
+	}
+
+	disabled, err := s.DisableHPA(ctx, hpa)
+	require.NoError(t, err)
+	require.True(t, disabled)
+	hpa = get()
+	require.Equal(t, int32(1), *hpa.Spec.MinReplicas)
+	require.Equal(t, "0", hpa.Annotations[common.AnnotationOriginalMinReplicas])
+	require.Equal(t, "kubectl-unmount", hpa.Annotations[common.AnnotationScaledBy])
+
+	restored, err := s.RestoreHPA(ctx, hpa)
+	require.NoError(t, err)
+	require.True(t, restored)
+	hpa = get()
+	require.Equal(t, int32(0), *hpa.Spec.MinReplicas)
+	require.NotContains(t, hpa.Annotations, common.AnnotationOriginalMinReplicas)
+	require.NotContains(t, hpa.Annotations, common.AnnotationScaledBy)
+
+	// HPAs that weren't disabled are left alone
+	restored, err = s.RestoreHPA(ctx, hpa)
+	require.NoError(t, err)
+	require.False(t, restored)
+}
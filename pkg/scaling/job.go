@@ -0,0 +1,62 @@
+package scaling
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/common"
+	"github.com/dancavallaro/kubectl-unmount/pkg/logger"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// suspendPatch returns a merge patch that suspends a Job or CronJob and adds the given annotations.
+func suspendPatch(annotations map[string]string) ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"metadata": map[string]any{
+			"annotations": annotations,
+		},
+		"spec": map[string]any{
+			"suspend": true,
+		},
+	})
+}
+
+// suspendJob suspends a Job, which makes the Job controller terminate its active pods.
+func suspendJob(ctx context.Context, log *logger.Logger, clientset kubernetes.Interface, ctrl common.ControllerRef,
+	annotations map[string]string, dryRun []string) error {
+	data, err := suspendPatch(annotations)
+	if err != nil {
+		return fmt.Errorf("failed to encode patch for %s %s/%s: %w", ctrl.Kind, ctrl.Namespace, ctrl.Name, err)
+	}
+	_, err = clientset.BatchV1().Jobs(ctrl.Namespace).Patch(ctx, ctrl.Name, types.MergePatchType, data, metav1.PatchOptions{DryRun: dryRun})
+	if err != nil {
+		return fmt.Errorf("failed to suspend %s %s/%s: %w", ctrl.Kind, ctrl.Namespace, ctrl.Name, err)
+	}
+	log.Info("  Suspended Job %s/%s", ctrl.Namespace, ctrl.Name)
+	return nil
+}
+
+// suspendCronJob suspends a CronJob so that it doesn't create new Jobs, and then suspends its active Jobs.
+func suspendCronJob(ctx context.Context, log *logger.Logger, clientset kubernetes.Interface, ctrl common.ControllerRef,
+	annotations map[string]string, dryRun []string) error {
+	data, err := suspendPatch(annotations)
+	if err != nil {
+		return fmt.Errorf("failed to encode patch for %s %s/%s: %w", ctrl.Kind, ctrl.Namespace, ctrl.Name, err)
+	}
+	cronJob, err := clientset.BatchV1().CronJobs(ctrl.Namespace).Patch(ctx, ctrl.Name, types.MergePatchType, data, metav1.PatchOptions{DryRun: dryRun})
+	if err != nil {
+		return fmt.Errorf("failed to suspend %s %s/%s: %w", ctrl.Kind, ctrl.Namespace, ctrl.Name, err)
+	}
+	log.Info("  Suspended CronJob %s/%s", ctrl.Namespace, ctrl.Name)
+
+	for _, active := range cronJob.Status.Active {
+		job := common.ControllerRef{Kind: common.KindJob, Namespace: ctrl.Namespace, Name: active.Name}
+		if err := suspendJob(ctx, log, clientset, job, annotations, dryRun); err != nil {
+			return err
+		}
+	}
+	return nil
+}
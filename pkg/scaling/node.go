@@ -0,0 +1,69 @@
+package scaling
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/common"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Cordon marks the given node unschedulable, so that replacement pods don't land on it and re-mount the PVCs,
+// and annotates it to record that kubectl-unmount cordoned it. Returns false if the node was already
+// unschedulable, in which case it's left untouched (so that Uncordon won't undo someone else's cordon).
+func (s Scaler) Cordon(ctx context.Context, name string) (bool, error) {
+	nodes := s.clientset.CoreV1().Nodes()
+	node, err := nodes.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return false, fmt.Errorf("failed to get node %s: %w", name, err)
+	}
+	if node.Spec.Unschedulable {
+		s.log.Info("  Node %s is already cordoned", name)
+		return false, nil
+	}
+	if s.dryRun && !s.serverDryRun {
+		s.log.Info("  (dry-run, skipping cordon of Node %s)", name)
+		return true, nil
+	}
+
+	if err := s.patchNode(ctx, node, true, map[string]any{common.AnnotationCordonedBy: "kubectl-unmount"}); err != nil {
+		return false, fmt.Errorf("failed to cordon node %s: %w", name, err)
+	}
+	s.log.Info("  Node/%s cordoned", name)
+	return true, nil
+}
+
+// Uncordon marks the given node (previously cordoned by Cordon) schedulable again, and removes the annotation
+// recording that kubectl-unmount cordoned it.
+func (s Scaler) Uncordon(ctx context.Context, node corev1.Node) error {
+	if s.dryRun && !s.serverDryRun {
+		s.log.Info("  (dry-run, skipping uncordon of Node %s)", node.Name)
+		return nil
+	}
+
+	if err := s.patchNode(ctx, &node, false, map[string]any{common.AnnotationCordonedBy: nil}); err != nil {
+		return fmt.Errorf("failed to uncordon node %s: %w", node.Name, err)
+	}
+	s.log.Info("  Node/%s uncordoned", node.Name)
+	return nil
+}
+
+func (s Scaler) patchNode(ctx context.Context, node *corev1.Node, unschedulable bool, annotations map[string]any) error {
+	data, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{
+			"annotations": annotations,
+		},
+		"spec": map[string]any{
+			"unschedulable": unschedulable,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = s.clientset.CoreV1().Nodes().Patch(ctx, node.Name, types.MergePatchType, data,
+		metav1.PatchOptions{DryRun: s.dryRunOptions()})
+	return err
+}
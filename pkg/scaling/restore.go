@@ -0,0 +1,124 @@
+package scaling
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/common"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Restore reverses scaling down the given controller: it scales it back up to the given number of replicas
+// (or resumes it, for Jobs and CronJobs), and removes the annotations recording the scale down, including the
+// custom ones added with --scale-annotations.
+func (s Scaler) Restore(ctx context.Context, ctrl common.ControllerRef, replicas *int32) error {
+	if s.dryRun && !s.serverDryRun {
+		s.log.Info("  (dry-run, skipping restoring controller: %v)", ctrl)
+		return nil
+	}
+
+	var spec map[string]any
+	switch ctrl.Kind {
+	case common.KindDeployment, common.KindStatefulSet, common.KindReplicaSet, common.KindReplicationController:
+		if replicas == nil {
+			return fmt.Errorf("cannot restore %v, its original number of replicas is unknown", ctrl)
+		}
+		spec = map[string]any{"replicas": *replicas}
+	case common.KindJob, common.KindCronJob:
+		spec = map[string]any{"suspend": false}
+	default:
+		if ctrl.APIVersion == "" || s.dynamic == nil {
+			return fmt.Errorf("cannot restore %v, restoring is not supported for %ss", ctrl, ctrl.Kind)
+		}
+		return s.restoreCustomResource(ctx, ctrl, replicas)
+	}
+	current, err := s.annotationsOf(ctx, ctrl)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{"annotations": restoreAnnotations(current)},
+		"spec":     spec,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode patch for %v: %w", ctrl, err)
+	}
+
+	apps := s.clientset.AppsV1()
+	batch := s.clientset.BatchV1()
+	dryRun := s.dryRunOptions()
+	var patch patchFunc
+	switch ctrl.Kind {
+	case common.KindDeployment:
+		patch = patcher(apps.Deployments(ctrl.Namespace).Patch, dryRun)
+	case common.KindStatefulSet:
+		patch = patcher(apps.StatefulSets(ctrl.Namespace).Patch, dryRun)
+	case common.KindReplicaSet:
+		patch = patcher(apps.ReplicaSets(ctrl.Namespace).Patch, dryRun)
+	case common.KindReplicationController:
+		patch = patcher(s.clientset.CoreV1().ReplicationControllers(ctrl.Namespace).Patch, dryRun)
+	case common.KindJob:
+		patch = patcher(batch.Jobs(ctrl.Namespace).Patch, dryRun)
+	case common.KindCronJob:
+		patch = patcher(batch.CronJobs(ctrl.Namespace).Patch, dryRun)
+	}
+	err = s.withRetries(ctx, ctrl.String(), func() error {
+		return patch(ctx, ctrl.Name, data)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to restore %v: %w", ctrl, err)
+	}
+
+	if replicas != nil {
+		s.log.Info("  Restored %v to %d replicas", ctrl, *replicas)
+	} else {
+		s.log.Info("  Resumed %v", ctrl)
+	}
+	return nil
+}
+
+// restoreAnnotations returns the annotations patch that removes the annotations added when scaling down a
+// controller, which currently has the given annotations.
+func restoreAnnotations(current map[string]string) map[string]any {
+	annotations := map[string]any{
+		common.AnnotationScaledBy:          nil,
+		common.AnnotationScaledAt:          nil,
+		common.AnnotationOriginalReplicas:  nil,
+		common.AnnotationPVCTrigger:        nil,
+		common.AnnotationCustomAnnotations: nil,
+	}
+	if keys := current[common.AnnotationCustomAnnotations]; keys != "" {
+		for _, key := range strings.Split(keys, ",") {
+			annotations[key] = nil
+		}
+	}
+	return annotations
+}
+
+// annotationsOf gets the current annotations of the given controller.
+func (s Scaler) annotationsOf(ctx context.Context, ctrl common.ControllerRef) (map[string]string, error) {
+	apps := s.clientset.AppsV1()
+	batch := s.clientset.BatchV1()
+	var meta metav1.Object
+	var err error
+	switch ctrl.Kind {
+	case common.KindDeployment:
+		meta, err = apps.Deployments(ctrl.Namespace).Get(ctx, ctrl.Name, metav1.GetOptions{})
+	case common.KindStatefulSet:
+		meta, err = apps.StatefulSets(ctrl.Namespace).Get(ctx, ctrl.Name, metav1.GetOptions{})
+	case common.KindReplicaSet:
+		meta, err = apps.ReplicaSets(ctrl.Namespace).Get(ctx, ctrl.Name, metav1.GetOptions{})
+	case common.KindReplicationController:
+		meta, err = s.clientset.CoreV1().ReplicationControllers(ctrl.Namespace).Get(ctx, ctrl.Name, metav1.GetOptions{})
+	case common.KindJob:
+		meta, err = batch.Jobs(ctrl.Namespace).Get(ctx, ctrl.Name, metav1.GetOptions{})
+	case common.KindCronJob:
+		meta, err = batch.CronJobs(ctrl.Namespace).Get(ctx, ctrl.Name, metav1.GetOptions{})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %v: %w", ctrl, err)
+	}
+	return meta.GetAnnotations(), nil
+}
@@ -0,0 +1,52 @@
+package scaling
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/common"
+	"github.com/dancavallaro/kubectl-unmount/pkg/logger"
+	"github.com/stretchr/testify/require"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestRestoreRemovesAnnotations(t *testing.T) {
+	ctx := context.Background()
+	cronJob := &batchv1.CronJob{ObjectMeta: metav1.ObjectMeta{
+		Name:        "backup",
+		Namespace:   "test-ns",
+		Annotations: map[string]string{"owner": "storage-team"},
+	}}
+	clientset := fake.NewClientset(cronJob)
+	s := New(clientset, logger.NewLogger(&bytes.Buffer{}, logger.LevelInfo), Options{
+		Annotations: map[string]string{"ticket": "OPS-123"},
+	})
+	ctrl := common.ControllerRef{Kind: common.KindCronJob, Namespace: "test-ns", Name: "backup"}
+	get := func() *batchv1.CronJob {
+		cronJob, err := clientset.BatchV1().CronJobs("test-ns").Get(ctx, "backup", metav1.GetOptions{})
+		require.NoError(t, err)
+		return cronJob
+	}
+
+	_, err := s.ScaleDown(ctx, ctrl, []string{"data"})
+	require.NoError(t, err)
+	cronJob = get()
+	require.True(t, *cronJob.Spec.Suspend)
+	require.Equal(t, "OPS-123", cronJob.Annotations["ticket"])
+	require.Equal(t, "ticket", cronJob.Annotations[common.AnnotationCustomAnnotations])
+
+	require.NoError(t, s.Restore(ctx, ctrl, nil))
+	cronJob = get()
+	require.False(t, *cronJob.Spec.Suspend)
+	require.Equal(t, map[string]string{"owner": "storage-team"}, cronJob.Annotations)
+}
+
+func TestRestoreUnsupported(t *testing.T) {
+	s := New(fake.NewClientset(), logger.NewLogger(&bytes.Buffer{}, logger.LevelInfo), Options{})
+	// Custom resources can only be restored with their API version, through their scale subresource
+	rollout := common.ControllerRef{Kind: "Rollout", Namespace: "test-ns", Name: "web"}
+	require.ErrorContains(t, s.Restore(context.Background(), rollout, nil), "restoring is not supported for Rollouts")
+}
@@ -0,0 +1,47 @@
+package scaling
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+)
+
+// retryBackoff is the backoff between attempts of retried operations, which is doubled after each retry.
+var retryBackoff = wait.Backoff{
+	Duration: 200 * time.Millisecond,
+	Factor:   2,
+	Jitter:   0.1,
+}
+
+// withRetries runs the given operation, retrying it (with exponential backoff) up to the configured number of
+// times if it fails with a transient API error.
+func (s Scaler) withRetries(ctx context.Context, what string, operation func() error) error {
+	backoff := retryBackoff
+	backoff.Steps = s.maxRetries + 1
+	attempt := 0
+	return retry.OnError(backoff, func(err error) bool {
+		attempt++
+		if ctx.Err() != nil || !isRetryable(err) {
+			return false
+		}
+		if attempt <= s.maxRetries {
+			s.log.Debug("Retrying %s after error (retry %d/%d): %v", what, attempt, s.maxRetries, err)
+		}
+		return true
+	}, operation)
+}
+
+// isRetryable checks whether the error is a transient API error, such as a conflict, being throttled, or a
+// server error, that may succeed if retried.
+func isRetryable(err error) bool {
+	if apierrors.IsConflict(err) || apierrors.IsTooManyRequests(err) || apierrors.IsServerTimeout(err) ||
+		apierrors.IsTimeout(err) || apierrors.IsInternalError(err) || apierrors.IsServiceUnavailable(err) {
+		return true
+	}
+	var status apierrors.APIStatus
+	return errors.As(err, &status) && status.Status().Code >= 500
+}
@@ -0,0 +1,54 @@
+package scaling
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/logger"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestWithRetries(t *testing.T) {
+	retryBackoff.Duration = time.Millisecond
+	resource := schema.GroupResource{Group: "apps", Resource: "deployments"}
+	tests := []struct {
+		name     string
+		err      error
+		fails    int
+		wantErr  bool
+		attempts int
+	}{
+		{name: "succeeds after conflicts", err: apierrors.NewConflict(resource, "web", errors.New("modified")), fails: 2, attempts: 3},
+		{name: "succeeds after throttling", err: apierrors.NewTooManyRequests("slow down", 1), fails: 3, attempts: 4},
+		{name: "gives up after max retries", err: apierrors.NewInternalError(errors.New("etcd")), fails: 10, wantErr: true, attempts: 4},
+		{name: "doesn't retry forbidden", err: apierrors.NewForbidden(resource, "web", errors.New("no")), fails: 10, wantErr: true, attempts: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			s := Scaler{log: logger.NewLogger(&logs, logger.LevelDebug), maxRetries: 3}
+			attempts := 0
+			err := s.withRetries(context.Background(), "Deployment/test-ns/web", func() error {
+				attempts++
+				if attempts <= tt.fails {
+					return tt.err
+				}
+				return nil
+			})
+			if tt.wantErr {
+				require.ErrorIs(t, err, tt.err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tt.attempts, attempts)
+			if tt.attempts > 1 {
+				require.Contains(t, logs.String(), "Retrying Deployment/test-ns/web after error (retry 1/3)")
+			}
+		})
+	}
+}
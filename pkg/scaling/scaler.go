@@ -2,86 +2,322 @@ package scaling
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/dancavallaro/kubectl-unmount/pkg/common"
 	"github.com/dancavallaro/kubectl-unmount/pkg/logger"
 	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
 )
 
 type Scaler struct {
-	clientset *kubernetes.Clientset
-	log       *logger.Logger
-	dryRun    bool
+	clientset    kubernetes.Interface
+	dynamic      dynamic.Interface
+	log          *logger.Logger
+	dryRun       bool
+	serverDryRun bool
+	gracePeriod  *int64
+	useEviction  bool
+	recorder     record.EventRecorder
+	maxRetries   int
+	replicas     int32
+
+	customAnnotations map[string]string
+}
+
+// Options configures how a Scaler scales down controllers.
+type Options struct {
+	DryRun bool
+	// ServerDryRun sends the scale down requests as server-side dry runs, so that they're validated (including
+	// by admission webhooks) without being persisted.
+	ServerDryRun bool
+	// GracePeriod overrides the termination grace period (in seconds) of the pods being removed, if set.
+	GracePeriod *int64
+	// UseEviction removes standalone pods with the Eviction API (which respects PodDisruptionBudgets)
+	// instead of deleting them directly.
+	UseEviction bool
+	// Recorder records events on the controllers that are scaled down, if set.
+	Recorder record.EventRecorder
+	// Annotations are custom annotations to add to the controllers that are scaled down, e.g. to document why.
+	Annotations map[string]string
+	// MaxRetries is how many times to retry scaling down a controller (or deleting a pod) after a transient
+	// API error, such as a conflict or a server error.
+	MaxRetries int
+	// Dynamic is used to scale down custom resources through their scale subresource, if set.
+	Dynamic dynamic.Interface
+	// Replicas is the number of replicas to scale controllers down to, rather than 0. Controllers that already
+	// have this many replicas or fewer are left as is.
+	Replicas int32
 }
 
 // New creates a new Scaler instance.
-func New(clientset *kubernetes.Clientset, log *logger.Logger, dryRun bool) Scaler {
+func New(clientset kubernetes.Interface, log *logger.Logger, opts Options) Scaler {
 	return Scaler{
-		clientset: clientset,
-		log:       log,
-		dryRun:    dryRun,
+		clientset:    clientset,
+		dynamic:      opts.Dynamic,
+		log:          log,
+		dryRun:       opts.DryRun || opts.ServerDryRun,
+		serverDryRun: opts.ServerDryRun,
+		gracePeriod:  opts.GracePeriod,
+		useEviction:  opts.UseEviction,
+		recorder:     opts.Recorder,
+		maxRetries:   opts.MaxRetries,
+		replicas:     opts.Replicas,
+
+		customAnnotations: opts.Annotations,
 	}
 }
 
-func (s Scaler) ScaleDown(ctx context.Context, ctrl common.ControllerRef) error {
+// ScaleDown scales down the given controller (or suspends it, for Jobs and CronJobs, or deletes it, for standalone
+// pods). Scaled down controllers are annotated to record the operation, including the PVCs that triggered it,
+// and an event describing the outcome is recorded on them. Returns whether anything was (or, in dry-run mode, would
+// be) changed: nothing is if the controller is already scaled down, or can't be scaled.
+func (s Scaler) ScaleDown(ctx context.Context, ctrl common.ControllerRef, pvcs []string) (bool, error) {
+	var message string
+	scaleDown := func() error {
+		var err error
+		message, err = s.scaleDown(ctx, ctrl, pvcs)
+		return err
+	}
+	if s.serverDryRun {
+		s.log.Info("  (server dry-run, changes to %v won't be persisted)", ctrl)
+		err := s.withRetries(ctx, ctrl.String(), scaleDown)
+		return err == nil && message != "", err
+	}
 	if s.dryRun {
 		s.log.Info("  (dry-run, skipping controller: %v)", ctrl)
-		return nil
+		return true, nil
 	}
 
+	err := s.withRetries(ctx, ctrl.String(), scaleDown)
+	if errors.Is(err, ErrNotScalable) {
+		return false, err
+	}
+	if err != nil {
+		s.recordEvent(ctrl, corev1.EventTypeWarning,
+			fmt.Sprintf("kubectl-unmount failed to scale down for PVC %s: %v", strings.Join(pvcs, ","), err))
+		return false, err
+	}
+	if message == "" {
+		return false, nil
+	}
+	s.recordEvent(ctrl, corev1.EventTypeNormal, fmt.Sprintf("kubectl-unmount %s for PVC %s", message, strings.Join(pvcs, ",")))
+	return true, nil
+}
+
+// scaleDown scales down the given controller, and returns a description of what was done (if anything).
+func (s Scaler) scaleDown(ctx context.Context, ctrl common.ControllerRef, pvcs []string) (string, error) {
+	apps := s.clientset.AppsV1()
+	annotations := s.annotations(pvcs)
+	dryRun := s.dryRunOptions()
+	var replicas int32
+	var err error
 	switch ctrl.Kind {
 	case common.KindDeployment:
-		return scaleControllerToZero(ctx, s.log, s.clientset.AppsV1().Deployments(ctrl.Namespace), ctrl)
+		deployments := apps.Deployments(ctrl.Namespace)
+		replicas, err = scaleController(ctx, s.log, deployments, patcher(deployments.Patch, dryRun), ctrl, annotations, s.replicas)
 	case common.KindStatefulSet:
-		return scaleControllerToZero(ctx, s.log, s.clientset.AppsV1().StatefulSets(ctrl.Namespace), ctrl)
+		statefulSets := apps.StatefulSets(ctrl.Namespace)
+		replicas, err = scaleController(ctx, s.log, statefulSets, patcher(statefulSets.Patch, dryRun), ctrl, annotations, s.replicas)
 	case common.KindReplicaSet:
-		return scaleControllerToZero(ctx, s.log, s.clientset.AppsV1().ReplicaSets(ctrl.Namespace), ctrl)
+		replicaSets := apps.ReplicaSets(ctrl.Namespace)
+		replicas, err = scaleController(ctx, s.log, replicaSets, patcher(replicaSets.Patch, dryRun), ctrl, annotations, s.replicas)
+	case common.KindReplicationController:
+		rcs := s.clientset.CoreV1().ReplicationControllers(ctrl.Namespace)
+		replicas, err = scaleController(ctx, s.log, rcs, patcher(rcs.Patch, dryRun), ctrl, annotations, s.replicas)
+	case common.KindJob:
+		return "suspended Job", suspendJob(ctx, s.log, s.clientset, ctrl, annotations, dryRun)
+	case common.KindCronJob:
+		return "suspended CronJob", suspendCronJob(ctx, s.log, s.clientset, ctrl, annotations, dryRun)
 	case common.KindPod:
-		return deletePod(ctx, s.log, s.clientset, ctrl)
+		if s.useEviction {
+			return "evicted Pod", evictPod(ctx, s.log, s.clientset, ctrl, s.gracePeriod, dryRun)
+		}
+		return "deleted Pod", deletePod(ctx, s.log, s.clientset, ctrl, s.gracePeriod, dryRun)
 	case common.KindDaemonSet:
 		s.log.Warn("Cannot scale down DaemonSet %s/%s (DaemonSets cannot be scaled)", ctrl.Namespace, ctrl.Name)
-		return nil
+		return "", nil
 	default:
-		s.log.Warn("Unsupported controller type %s for %s/%s, skipping", ctrl.Kind, ctrl.Namespace, ctrl.Name)
-		return nil
+		if ctrl.APIVersion == "" || s.dynamic == nil {
+			s.log.Warn("Unsupported controller type %s for %s/%s, skipping", ctrl.Kind, ctrl.Namespace, ctrl.Name)
+			return "", nil
+		}
+		replicas, err = s.scaleCustomResource(ctx, ctrl, annotations, dryRun)
+	}
+	if err != nil || replicas <= s.replicas {
+		return "", err
+	}
+	return fmt.Sprintf("scaled replicas from %d to %d", replicas, s.replicas), nil
+}
+
+// CanScaleDown checks whether the given controller can be scaled down (or deleted, for standalone pods). Custom
+// resources are assumed to be scalable, scaling them down fails with ErrNotScalable if they aren't.
+func CanScaleDown(ctrl common.ControllerRef) bool {
+	switch ctrl.Kind {
+	case common.KindDeployment, common.KindStatefulSet, common.KindReplicaSet, common.KindReplicationController,
+		common.KindJob, common.KindCronJob, common.KindPod:
+		return true
+	default:
+		return ctrl.APIVersion != ""
 	}
 }
 
 type scalable interface {
 	GetScale(ctx context.Context, deploymentName string, options metav1.GetOptions) (*autoscalingv1.Scale, error)
-	UpdateScale(ctx context.Context, deploymentName string, scale *autoscalingv1.Scale, opts metav1.UpdateOptions) (*autoscalingv1.Scale, error)
 }
 
-func scaleControllerToZero(ctx context.Context, log *logger.Logger, scaler scalable, ctrl common.ControllerRef) error {
+// patchFunc applies a merge patch to the named resource.
+type patchFunc func(ctx context.Context, name string, data []byte) error
+
+// patcher adapts the Patch method of a typed client to a patchFunc.
+func patcher[T any](patch func(ctx context.Context, name string, pt types.PatchType, data []byte,
+	opts metav1.PatchOptions, subresources ...string) (T, error), dryRun []string) patchFunc {
+	return func(ctx context.Context, name string, data []byte) error {
+		_, err := patch(ctx, name, types.MergePatchType, data, metav1.PatchOptions{DryRun: dryRun})
+		return err
+	}
+}
+
+// scaleController scales the controller down to the given number of replicas, adding the given annotations in the
+// same patch, and returns its original number of replicas.
+func scaleController(ctx context.Context, log *logger.Logger, scaler scalable, patch patchFunc,
+	ctrl common.ControllerRef, annotations map[string]string, replicas int32) (int32, error) {
 	scale, err := scaler.GetScale(ctx, ctrl.Name, metav1.GetOptions{})
 	if err != nil {
-		return fmt.Errorf("failed to get scale for %s %s/%s: %w", ctrl.Kind, ctrl.Namespace, ctrl.Name, err)
+		return 0, fmt.Errorf("failed to get scale for %s %s/%s: %w", ctrl.Kind, ctrl.Namespace, ctrl.Name, err)
 	}
 
 	originalReplicas := scale.Spec.Replicas
-	if originalReplicas == 0 {
-		log.Info("%s %s/%s is already scaled to 0", ctrl.Kind, ctrl.Namespace, ctrl.Name)
-		return nil
+	if originalReplicas <= replicas {
+		log.Info("%s %s/%s is already scaled to %d", ctrl.Kind, ctrl.Namespace, ctrl.Name, originalReplicas)
+		return originalReplicas, nil
 	}
 
-	scale.Spec.Replicas = 0
-	_, err = scaler.UpdateScale(ctx, ctrl.Name, scale, metav1.UpdateOptions{})
+	// Scale down and record the operation in a single patch
+	annotations[common.AnnotationOriginalReplicas] = strconv.Itoa(int(originalReplicas))
+	data, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{
+			"annotations": annotations,
+		},
+		"spec": map[string]any{
+			"replicas": replicas,
+		},
+	})
 	if err != nil {
-		return fmt.Errorf("failed to scale down %s %s/%s: %w", ctrl.Kind, ctrl.Namespace, ctrl.Name, err)
+		return 0, fmt.Errorf("failed to encode patch for %s %s/%s: %w", ctrl.Kind, ctrl.Namespace, ctrl.Name, err)
+	}
+	if err := patch(ctx, ctrl.Name, data); err != nil {
+		return 0, fmt.Errorf("failed to scale down %s %s/%s: %w", ctrl.Kind, ctrl.Namespace, ctrl.Name, err)
 	}
 
-	log.Info("  Scaled down %s %s/%s from %d to 0 replicas", ctrl.Kind, ctrl.Namespace, ctrl.Name, originalReplicas)
+	log.Info("  Scaled down %s %s/%s from %d to %d replicas", ctrl.Kind, ctrl.Namespace, ctrl.Name, originalReplicas, replicas)
+	return originalReplicas, nil
+}
+
+// dryRunOptions returns the DryRun option of the requests that scale down controllers.
+func (s Scaler) dryRunOptions() []string {
+	if s.serverDryRun {
+		return []string{metav1.DryRunAll}
+	}
 	return nil
 }
 
-func deletePod(ctx context.Context, log *logger.Logger, clientset *kubernetes.Clientset, ctrl common.ControllerRef) error {
-	err := clientset.CoreV1().Pods(ctrl.Namespace).Delete(ctx, ctrl.Name, metav1.DeleteOptions{})
+// annotations returns the annotations to add to a controller scaled down because of the given PVCs: the ones
+// recording the operation, plus any custom annotations (whose keys are recorded so they can be removed later).
+func (s Scaler) annotations(pvcs []string) map[string]string {
+	annotations := map[string]string{
+		common.AnnotationScaledBy:   "kubectl-unmount",
+		common.AnnotationScaledAt:   time.Now().UTC().Format(time.RFC3339),
+		common.AnnotationPVCTrigger: strings.Join(pvcs, ","),
+	}
+	if len(s.customAnnotations) > 0 {
+		maps.Copy(annotations, s.customAnnotations)
+		annotations[common.AnnotationCustomAnnotations] = strings.Join(slices.Sorted(maps.Keys(s.customAnnotations)), ",")
+	}
+	return annotations
+}
+
+// OverrideGracePeriod re-deletes the given pods (which are already being removed by scaling down their controller)
+// with the configured grace period, shortening how long they take to terminate. This does nothing if no grace
+// period override is configured.
+func (s Scaler) OverrideGracePeriod(ctx context.Context, pods []corev1.Pod) error {
+	if s.gracePeriod == nil || s.dryRun {
+		return nil
+	}
+	for _, pod := range pods {
+		warnForceDelete(s.log, s.gracePeriod, pod.Namespace, pod.Name)
+		err := s.withRetries(ctx, fmt.Sprintf("Pod/%s/%s", pod.Namespace, pod.Name), func() error {
+			return s.clientset.CoreV1().Pods(pod.Namespace).Delete(ctx, pod.Name, metav1.DeleteOptions{
+				GracePeriodSeconds: s.gracePeriod,
+			})
+		})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete pod %s/%s: %w", pod.Namespace, pod.Name, err)
+		}
+	}
+	return nil
+}
+
+func deletePod(ctx context.Context, log *logger.Logger, clientset kubernetes.Interface, ctrl common.ControllerRef,
+	gracePeriod *int64, dryRun []string) error {
+	warnForceDelete(log, gracePeriod, ctrl.Namespace, ctrl.Name)
+	err := clientset.CoreV1().Pods(ctrl.Namespace).Delete(ctx, ctrl.Name, metav1.DeleteOptions{
+		GracePeriodSeconds: gracePeriod,
+		DryRun:             dryRun,
+	})
 	if err != nil {
 		return fmt.Errorf("failed to delete pod %s/%s: %w", ctrl.Namespace, ctrl.Name, err)
 	}
 	log.Info("  Deleted standalone Pod %s/%s", ctrl.Namespace, ctrl.Name)
 	return nil
 }
+
+// evictPod evicts a standalone pod, falling back to deleting it if the Eviction API isn't available.
+func evictPod(ctx context.Context, log *logger.Logger, clientset kubernetes.Interface, ctrl common.ControllerRef,
+	gracePeriod *int64, dryRun []string) error {
+	warnForceDelete(log, gracePeriod, ctrl.Namespace, ctrl.Name)
+	err := clientset.PolicyV1().Evictions(ctrl.Namespace).Evict(ctx, &policyv1.Eviction{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ctrl.Name,
+			Namespace: ctrl.Namespace,
+		},
+		DeleteOptions: &metav1.DeleteOptions{
+			GracePeriodSeconds: gracePeriod,
+			DryRun:             dryRun,
+		},
+	})
+	if apierrors.IsMethodNotSupported(err) {
+		return deletePod(ctx, log, clientset, ctrl, gracePeriod, dryRun)
+	}
+	if apierrors.IsTooManyRequests(err) {
+		return fmt.Errorf("cannot evict pod %s/%s, it would violate a PodDisruptionBudget (use --disable-eviction to delete it anyway): %w",
+			ctrl.Namespace, ctrl.Name, err)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to evict pod %s/%s: %w", ctrl.Namespace, ctrl.Name, err)
+	}
+	log.Info("  Evicted standalone Pod %s/%s", ctrl.Namespace, ctrl.Name)
+	return nil
+}
+
+// warnForceDelete warns that the pod is being force-deleted if the grace period is 0: the API server removes it right
+// away, without waiting for the kubelet to confirm that its containers stopped (and released the volume).
+func warnForceDelete(log *logger.Logger, gracePeriod *int64, namespace, name string) {
+	if gracePeriod != nil && *gracePeriod == 0 {
+		log.Warn("Force-deleting Pod %s/%s without waiting for the kubelet to confirm it terminated", namespace, name)
+	}
+}
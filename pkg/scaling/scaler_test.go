@@ -0,0 +1,74 @@
+package scaling
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/dancavallaro/kubectl-unmount/pkg/common"
+	"github.com/dancavallaro/kubectl-unmount/pkg/logger"
+	"github.com/stretchr/testify/require"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/utils/ptr"
+)
+
+func TestDeletePodWithGracePeriod(t *testing.T) {
+	for _, gracePeriod := range []int64{0, 30} {
+		clientset := fake.NewClientset(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "test-pod", Namespace: "test-ns"}})
+		var deleteOpts metav1.DeleteOptions
+		clientset.PrependReactor("delete", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			deleteOpts = action.(k8stesting.DeleteAction).GetDeleteOptions()
+			return false, nil, nil
+		})
+
+		var logs bytes.Buffer
+		s := New(clientset, logger.NewLogger(&logs, logger.LevelInfo), Options{GracePeriod: ptr.To(gracePeriod)})
+		pod := common.ControllerRef{Kind: common.KindPod, Namespace: "test-ns", Name: "test-pod"}
+		_, err := s.ScaleDown(context.Background(), pod, []string{"test-pvc"})
+		require.NoError(t, err)
+
+		require.Equal(t, gracePeriod, *deleteOpts.GracePeriodSeconds)
+		if gracePeriod == 0 {
+			require.Contains(t, logs.String(), "Force-deleting Pod test-ns/test-pod without waiting for the kubelet")
+		} else {
+			require.NotContains(t, logs.String(), "Force-deleting")
+		}
+	}
+}
+
+type fixedScale int32
+
+func (f fixedScale) GetScale(context.Context, string, metav1.GetOptions) (*autoscalingv1.Scale, error) {
+	return &autoscalingv1.Scale{Spec: autoscalingv1.ScaleSpec{Replicas: int32(f)}}, nil
+}
+
+func TestScaleControllerToReplicas(t *testing.T) {
+	ctrl := common.ControllerRef{Kind: common.KindStatefulSet, Namespace: "test-ns", Name: "db"}
+	var patches []string
+	patch := func(_ context.Context, _ string, data []byte) error {
+		patches = append(patches, string(data))
+		return nil
+	}
+
+	var logs bytes.Buffer
+	log := logger.NewLogger(&logs, logger.LevelInfo)
+	original, err := scaleController(context.Background(), log, fixedScale(3), patch, ctrl, map[string]string{}, 1)
+	require.NoError(t, err)
+	require.Equal(t, int32(3), original)
+	require.Len(t, patches, 1)
+	require.Contains(t, patches[0], `"spec":{"replicas":1}`)
+	require.Contains(t, patches[0], `"kubectl-unmount/original-replicas":"3"`)
+	require.Contains(t, logs.String(), "Scaled down StatefulSet test-ns/db from 3 to 1 replicas")
+
+	// Controllers are never scaled up
+	original, err = scaleController(context.Background(), log, fixedScale(1), patch, ctrl, map[string]string{}, 2)
+	require.NoError(t, err)
+	require.Equal(t, int32(1), original)
+	require.Len(t, patches, 1)
+	require.Contains(t, logs.String(), "StatefulSet test-ns/db is already scaled to 1")
+}
@@ -0,0 +1,52 @@
+package spinner
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/mattn/go-isatty"
+)
+
+// Progress shows how many of a number of operations are done (e.g. "Scaling down 4/12 controllers..."),
+// updated in place. It shows nothing unless it's writing to a terminal, since each operation is also logged.
+type Progress struct {
+	mu    sync.Mutex
+	w     io.Writer
+	label string
+	total int
+	done  int
+	tty   bool
+}
+
+// NewProgress creates a Progress for the given number of operations, labeled like "Scaling down %d/%d controllers...".
+func NewProgress(w io.Writer, label string, total int) *Progress {
+	f, ok := w.(*os.File)
+	p := &Progress{w: w, label: label, total: total, tty: ok && isatty.IsTerminal(f.Fd())}
+	p.print()
+	return p
+}
+
+// Increment records that one more operation is done.
+func (p *Progress) Increment() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done++
+	p.print()
+}
+
+// Stop clears the progress line.
+func (p *Progress) Stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.tty {
+		_, _ = fmt.Fprint(p.w, "\r\033[K")
+	}
+}
+
+func (p *Progress) print() {
+	if p.tty {
+		_, _ = fmt.Fprintf(p.w, "\r\033[K"+p.label, p.done, p.total)
+	}
+}
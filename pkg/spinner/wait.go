@@ -1,18 +1,35 @@
 package spinner
 
 import (
+	"context"
 	"time"
 
 	"github.com/briandowns/spinner"
 )
 
-func Wait(label string, until func() (bool, error), onErr func(error), interval time.Duration) <-chan struct{} {
-	ch := make(chan struct{})
+// Wait shows a spinner while polling until the condition is met, or the context is done. The returned
+// channel receives nil once the condition is met, or the context's error if it finished first.
+func Wait(ctx context.Context, label string, until func() (bool, error), onErr func(error), interval time.Duration) <-chan error {
+	return poll(ctx, label, until, onErr, interval)
+}
+
+// Poll is like Wait, but without showing a spinner (e.g. because the condition reports its own progress).
+func Poll(ctx context.Context, until func() (bool, error), onErr func(error), interval time.Duration) <-chan error {
+	return poll(ctx, "", until, onErr, interval)
+}
+
+// poll polls until the condition is met, or the context is done, showing a spinner if it has a label.
+func poll(ctx context.Context, label string, until func() (bool, error), onErr func(error), interval time.Duration) <-chan error {
+	ch := make(chan error, 1)
 
 	go func() {
-		s := spinner.New(spinner.CharSets[70], 100*time.Millisecond)
-		s.Prefix = label
-		s.Start()
+		if label != "" {
+			s := spinner.New(spinner.CharSets[70], 100*time.Millisecond)
+			s.Prefix = label
+			s.Start()
+			defer s.Stop()
+		}
+		defer close(ch)
 
 		for {
 			done, err := until()
@@ -20,13 +37,16 @@ func Wait(label string, until func() (bool, error), onErr func(error), interval
 				onErr(err)
 			}
 			if done {
-				break
+				ch <- nil
+				return
+			}
+			select {
+			case <-ctx.Done():
+				ch <- ctx.Err()
+				return
+			case <-time.After(interval):
 			}
-			<-time.NewTimer(interval).C
 		}
-
-		s.Stop()
-		close(ch)
 	}()
 
 	return ch